@@ -0,0 +1,13 @@
+package schema
+
+// Album groups tracks released together, identified by its album artist and title
+type Album struct {
+	ID       uint64 `gorm:"primary_key;column:id" json:"id"`
+	ArtistID uint64 `gorm:"column:artist_id;not null;uniqueIndex:idx_album_artist_title" json:"artist_id"`
+	Title    string `gorm:"column:title;size:255;not null;uniqueIndex:idx_album_artist_title" json:"title"`
+	Year     *int   `gorm:"column:year" json:"year"`
+	Base
+
+	Artist Artist  `gorm:"foreignKey:ArtistID;constraint:OnDelete:CASCADE" json:"artist,omitempty"`
+	Tracks []Track `gorm:"foreignKey:AlbumID;constraint:OnDelete:SET NULL" json:"tracks,omitempty"`
+}
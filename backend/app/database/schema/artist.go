@@ -0,0 +1,11 @@
+package schema
+
+// Artist is a performer shared by every track credited to it. Names are unique, the
+// database collation matches them regardless of case.
+type Artist struct {
+	ID   uint64 `gorm:"primary_key;column:id" json:"id"`
+	Name string `gorm:"column:name;size:255;not null;uniqueIndex:idx_artist_name" json:"name"`
+	Base
+
+	Tracks []Track `gorm:"foreignKey:ArtistID;constraint:OnDelete:SET NULL" json:"tracks,omitempty"`
+}
@@ -0,0 +1,10 @@
+package schema
+
+type TrackArtwork struct {
+	ID              uint64 `gorm:"primary_key;column:id" json:"id"`
+	TrackID         uint64 `gorm:"column:track_id;not null;index:idx_track_artwork" json:"track_id"`
+	Size            int    `gorm:"column:size;not null" json:"size"`
+	Source          string `gorm:"column:source;default:'embedded'" json:"source"`
+	StorageFilename string `gorm:"column:storage_filename;not null" json:"storage_filename"`
+	Base
+}
@@ -0,0 +1,26 @@
+package schema
+
+// credit roles, in the order credits are listed
+const (
+	RolePrimary  = "primary"
+	RoleFeatured = "featured"
+	RoleRemixer  = "remixer"
+	RoleProducer = "producer"
+	RoleComposer = "composer"
+)
+
+// CreditRoles lists the valid roles of a TrackCredit
+var CreditRoles = []string{RolePrimary, RoleFeatured, RoleRemixer, RoleProducer, RoleComposer}
+
+// TrackCredit credits an artist on a track in a role. Position orders the credits of a track,
+// the first primary artist is the one the track is linked to through ArtistID.
+type TrackCredit struct {
+	ID       uint64 `gorm:"primary_key;column:id" json:"id"`
+	TrackID  uint64 `gorm:"column:track_id;not null;uniqueIndex:idx_credit_track_artist_role" json:"track_id"`
+	ArtistID uint64 `gorm:"column:artist_id;not null;uniqueIndex:idx_credit_track_artist_role;index:idx_credit_artist_role" json:"artist_id"`
+	Role     string `gorm:"column:role;size:16;not null;uniqueIndex:idx_credit_track_artist_role;index:idx_credit_artist_role" json:"role"`
+	Position int    `gorm:"column:position;not null;default:0" json:"position"`
+	Base
+
+	Artist Artist `gorm:"foreignKey:ArtistID;constraint:OnDelete:CASCADE" json:"artist,omitempty"`
+}
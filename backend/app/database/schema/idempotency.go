@@ -0,0 +1,18 @@
+package schema
+
+import "time"
+
+// IdempotencyKey stores the first response of a request sent with an Idempotency-Key header,
+// repeats of the request by the same user are answered with it until ExpiresAt
+type IdempotencyKey struct {
+	ID          uint64     `gorm:"primary_key;column:id" json:"id"`
+	UserID      uint64     `gorm:"column:user_id;not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Key         string     `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	Fingerprint string     `gorm:"column:fingerprint;size:64;not null" json:"fingerprint"`
+	StatusCode  int        `gorm:"column:status_code" json:"status_code"`
+	ContentType string     `gorm:"column:content_type;size:255" json:"content_type"`
+	Response    []byte     `gorm:"column:response;type:mediumblob" json:"-"`
+	CompletedAt *time.Time `gorm:"column:completed_at" json:"completed_at"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at;not null;index:idx_idempotency_expires" json:"expires_at"`
+	Base
+}
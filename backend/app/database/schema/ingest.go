@@ -0,0 +1,14 @@
+package schema
+
+import "time"
+
+// IngestFailure records an audio file that could not be imported automatically
+type IngestFailure struct {
+	ID             uint64     `gorm:"primary_key;column:id" json:"id"`
+	Source         string     `gorm:"column:source;size:32;not null" json:"source"`
+	Path           string     `gorm:"column:path;size:1024;not null;index:idx_ingest_failure_path,length:255" json:"path"`
+	FileSize       int64      `gorm:"column:file_size;default:0" json:"file_size"`
+	FileModifiedAt *time.Time `gorm:"column:file_modified_at" json:"file_modified_at"`
+	Error          string     `gorm:"column:error;type:text" json:"error"`
+	Base
+}
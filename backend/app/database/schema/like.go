@@ -0,0 +1,23 @@
+package schema
+
+// kinds of items a user can like
+const (
+	LikeTrack    = "track"
+	LikeAlbum    = "album"
+	LikePlaylist = "playlist"
+)
+
+// LikeTypes lists the kinds of liked items
+var LikeTypes = []string{LikeTrack, LikeAlbum, LikePlaylist}
+
+// Like marks a track, album or playlist as a favorite of a user. TargetType is one of LikeTypes
+// and TargetID the ID of the item, likes are removed with the item.
+type Like struct {
+	ID         uint64 `gorm:"primary_key;column:id" json:"id"`
+	UserID     uint64 `gorm:"column:user_id;not null;uniqueIndex:idx_like_user_target" json:"user_id"`
+	TargetType string `gorm:"column:target_type;size:16;not null;uniqueIndex:idx_like_user_target;index:idx_like_target" json:"target_type"`
+	TargetID   uint64 `gorm:"column:target_id;not null;uniqueIndex:idx_like_user_target;index:idx_like_target" json:"target_id"`
+	Base
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
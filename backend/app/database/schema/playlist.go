@@ -0,0 +1,123 @@
+package schema
+
+import "time"
+
+// playlist member roles, the owner of a playlist is not a member
+const (
+	PlaylistRoleOwner  = "owner"
+	PlaylistRoleEditor = "editor"
+	PlaylistRoleViewer = "viewer"
+)
+
+// playlist activity actions
+const (
+	ActivityCreated       = "created"
+	ActivityUpdated       = "updated"
+	ActivityCoverChanged  = "cover_changed"
+	ActivityCoverRemoved  = "cover_removed"
+	ActivityEntriesAdded  = "entries_added"
+	ActivityEntryRemoved  = "entry_removed"
+	ActivityEntriesMoved  = "entries_moved"
+	ActivityRefreshed     = "refreshed"
+	ActivityTrackDeleted  = "track_deleted"
+	ActivityMemberAdded   = "member_added"
+	ActivityMemberUpdated = "member_updated"
+	ActivityMemberRemoved = "member_removed"
+)
+
+// Playlist is an ordered list of tracks owned by a user. Private playlists are only visible to
+// their owner and members, public ones can be viewed by every user. Editors change the entries,
+// everything else is changed by the owner only. Version counts the changes of the entries.
+type Playlist struct {
+	ID          uint64  `gorm:"primary_key;column:id" json:"id"`
+	UserID      uint64  `gorm:"column:user_id;not null;index:idx_playlist_user" json:"user_id"`
+	Name        string  `gorm:"column:name;size:255;not null" json:"name"`
+	Description *string `gorm:"column:description;type:text" json:"description"`
+	Public      bool    `gorm:"column:public;not null;default:false" json:"public"`
+
+	// smart playlists list the tracks matching Rules, sorted by RuleSort and capped at RuleLimit,
+	// instead of entries added by hand. Live ones are evaluated on every request, materialized
+	// ones store the matching tracks as entries on each refresh.
+	Rules        *SmartRule `gorm:"column:rules;type:text;serializer:json" json:"rules"`
+	RuleLimit    *int       `gorm:"column:rule_limit" json:"rule_limit"`
+	RuleSort     *string    `gorm:"column:rule_sort;size:32" json:"rule_sort"`
+	Materialized bool       `gorm:"column:materialized;not null;default:false;index:idx_playlist_materialized" json:"materialized"`
+	RefreshedAt  *time.Time `gorm:"column:refreshed_at" json:"refreshed_at"`
+	Version      uint64     `gorm:"column:version;not null;default:0" json:"version"`
+	Base
+
+	User       User               `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Members    []PlaylistMember   `gorm:"foreignKey:PlaylistID;constraint:OnDelete:CASCADE" json:"members,omitempty"`
+	Covers     []PlaylistCover    `gorm:"foreignKey:PlaylistID;constraint:OnDelete:CASCADE" json:"covers,omitempty"`
+	Entries    []PlaylistEntry    `gorm:"foreignKey:PlaylistID;constraint:OnDelete:CASCADE" json:"entries,omitempty"`
+	Activities []PlaylistActivity `gorm:"foreignKey:PlaylistID;constraint:OnDelete:CASCADE" json:"activities,omitempty"`
+}
+
+// PlaylistCover is one square size of the cover image of a playlist, like TrackArtwork
+type PlaylistCover struct {
+	ID              uint64 `gorm:"primary_key;column:id" json:"id"`
+	PlaylistID      uint64 `gorm:"column:playlist_id;not null;index:idx_playlist_cover" json:"playlist_id"`
+	Size            int    `gorm:"column:size;not null" json:"size"`
+	StorageFilename string `gorm:"column:storage_filename;not null" json:"storage_filename"`
+	Base
+}
+
+// PlaylistEntry places a track in a playlist, a track may be placed several times. Position
+// orders the entries of a playlist from 0 without gaps. AddedByID is the user who placed the
+// track, nil for tracks of smart playlists and for removed users.
+type PlaylistEntry struct {
+	ID         uint64  `gorm:"primary_key;column:id" json:"id"`
+	PlaylistID uint64  `gorm:"column:playlist_id;not null;index:idx_entry_playlist_position" json:"playlist_id"`
+	TrackID    uint64  `gorm:"column:track_id;not null;index:idx_entry_track" json:"track_id"`
+	Position   int     `gorm:"column:position;not null;default:0;index:idx_entry_playlist_position" json:"position"`
+	AddedByID  *uint64 `gorm:"column:added_by_id" json:"added_by_id"`
+	Base
+
+	Track   Track `gorm:"foreignKey:TrackID;constraint:OnDelete:CASCADE" json:"track,omitempty"`
+	AddedBy *User `gorm:"foreignKey:AddedByID;constraint:OnDelete:SET NULL" json:"added_by,omitempty"`
+}
+
+// PlaylistMember gives a user other than the owner access to a playlist as editor or viewer
+type PlaylistMember struct {
+	ID         uint64 `gorm:"primary_key;column:id" json:"id"`
+	PlaylistID uint64 `gorm:"column:playlist_id;not null;uniqueIndex:idx_member_playlist_user" json:"playlist_id"`
+	UserID     uint64 `gorm:"column:user_id;not null;uniqueIndex:idx_member_playlist_user;index:idx_member_user" json:"user_id"`
+	Role       string `gorm:"column:role;size:16;not null" json:"role"`
+	Base
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// PlaylistActivity records a change of a playlist by a user, or by the system when UserID is nil.
+// Data holds the details of the action.
+type PlaylistActivity struct {
+	ID         uint64         `gorm:"primary_key;column:id" json:"id"`
+	PlaylistID uint64         `gorm:"column:playlist_id;not null;index:idx_activity_playlist" json:"playlist_id"`
+	UserID     *uint64        `gorm:"column:user_id" json:"user_id"`
+	Action     string         `gorm:"column:action;size:32;not null" json:"action"`
+	Data       map[string]any `gorm:"column:data;type:text;serializer:json" json:"data"`
+	Base
+
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL" json:"user,omitempty"`
+}
+
+// IsSmart reports whether the playlist is filled by rules
+func (p *Playlist) IsSmart() bool {
+	return p.Rules != nil
+}
+
+// Role returns the role of a user in the playlist, empty for users who are neither owner nor
+// member. Members must be loaded for the user.
+func (p *Playlist) Role(userID uint64) string {
+	if p.UserID == userID {
+		return PlaylistRoleOwner
+	}
+
+	for _, m := range p.Members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,10 @@
+package schema
+
+type TrackPreview struct {
+	ID              uint64 `gorm:"primary_key;column:id" json:"id"`
+	TrackID         uint64 `gorm:"column:track_id;not null;uniqueIndex:idx_track_preview" json:"track_id"`
+	StartMs         int64  `gorm:"column:start_ms;not null;uniqueIndex:idx_track_preview" json:"start_ms"`
+	LengthMs        int64  `gorm:"column:length_ms;not null;uniqueIndex:idx_track_preview" json:"length_ms"`
+	StorageFilename string `gorm:"column:storage_filename;not null" json:"storage_filename"`
+	Base
+}
@@ -0,0 +1,14 @@
+package schema
+
+// Rating is the star rating a user gives a track, from 0 to 5 in steps of 0.5 (whole stars
+// unless track.ratings.half_stars is enabled). Ratings are removed with the track.
+type Rating struct {
+	ID      uint64  `gorm:"primary_key;column:id" json:"id"`
+	UserID  uint64  `gorm:"column:user_id;not null;uniqueIndex:idx_rating_user_track" json:"user_id"`
+	TrackID uint64  `gorm:"column:track_id;not null;uniqueIndex:idx_rating_user_track;index:idx_rating_track" json:"track_id"`
+	Stars   float64 `gorm:"column:stars;type:decimal(2,1);not null" json:"stars"`
+	Base
+
+	User  User  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Track Track `gorm:"foreignKey:TrackID;constraint:OnDelete:CASCADE" json:"track,omitempty"`
+}
@@ -0,0 +1,9 @@
+package schema
+
+// Setting is a server option changed at runtime with an admin command, it overrides the
+// matching value of the config file until it is reset
+type Setting struct {
+	Key   string `gorm:"primary_key;column:setting_key;size:100" json:"key"`
+	Value string `gorm:"column:value;size:255;not null" json:"value"`
+	Base
+}
@@ -0,0 +1,24 @@
+package schema
+
+// smart playlist rule group matching
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// SmartRule is a node of the rule tree of a smart playlist. A node with Rules is a group whose
+// rules must all match (Match "all", the default) or of which one must match ("any"). Any other
+// node is a condition comparing a track Field with Value through Operator.
+type SmartRule struct {
+	Match string      `json:"match,omitempty"`
+	Rules []SmartRule `json:"rules,omitempty"`
+
+	Field    string `json:"field,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    any    `json:"value,omitempty"`
+}
+
+// IsGroup reports whether the rule combines other rules instead of testing a field
+func (r *SmartRule) IsGroup() bool {
+	return r.Field == ""
+}
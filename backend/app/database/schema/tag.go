@@ -0,0 +1,10 @@
+package schema
+
+// Tag is a label users attach to tracks, e.g. "wedding set". Tags belong to the user in
+// OwnerID, or to everyone with OwnerID 0 when track.user_tags.shared is enabled.
+type Tag struct {
+	ID      uint64 `gorm:"primary_key;column:id" json:"id"`
+	OwnerID uint64 `gorm:"column:owner_id;not null;default:0;uniqueIndex:idx_tag_owner_name" json:"owner_id"`
+	Name    string `gorm:"column:name;size:64;not null;uniqueIndex:idx_tag_owner_name" json:"name"`
+	Base
+}
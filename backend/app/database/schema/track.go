@@ -7,11 +7,76 @@ type Track struct {
 	Artist           string  `gorm:"column:artist;default:'Unknown Artist';index:idx_artist" json:"artist"`
 	Album            *string `gorm:"column:album" json:"album"`
 	Duration         int     `gorm:"column:duration;default:0" json:"duration"`
+	DurationMs       *int64  `gorm:"column:duration_ms" json:"duration_ms"`
 	StorageFilename  string  `gorm:"column:storage_filename;not null" json:"storage_filename"`
 	OriginalFilename string  `gorm:"column:original_filename;not null" json:"original_filename"`
 	FileSize         int64   `gorm:"column:file_size;default:0" json:"file_size"`
 	MimeType         string  `gorm:"column:mime_type;default:'audio/mpeg'" json:"mime_type"`
+	WaveformFilename *string `gorm:"column:waveform_filename" json:"waveform_filename"`
+	// AnalysisError is why the audio could not be decoded or measured, reads and backfills
+	// do not retry such tracks
+	AnalysisError *string `gorm:"column:analysis_error;size:255" json:"analysis_error"`
+	// SourcePath is the absolute path of a file imported from the local filesystem
+	SourcePath *string `gorm:"column:source_path;size:1024;index:idx_source_path,length:255" json:"source_path"`
+	// IngestData is attached by ingest hooks when the file is uploaded
+	IngestData map[string]string `gorm:"column:ingest_data;type:text;serializer:json" json:"ingest_data"`
+
+	// library entities the artist and album strings are linked to
+	ArtistID *uint64 `gorm:"column:artist_id;index:idx_track_artist" json:"artist_id"`
+	AlbumID  *uint64 `gorm:"column:album_id;index:idx_track_album" json:"album_id"`
+	// AlbumArtist is the album artist a new track is linked with when it is saved, it is not stored
+	AlbumArtist string `gorm:"-" json:"-"`
+
+	// tag metadata, numbers count from 1. Year is taken from ReleaseDate, which may be
+	// partial: YYYY, YYYY-MM or YYYY-MM-DD
+	TrackNumber *int    `gorm:"column:track_number" json:"track_number"`
+	TrackTotal  *int    `gorm:"column:track_total" json:"track_total"`
+	DiscNumber  *int    `gorm:"column:disc_number" json:"disc_number"`
+	DiscTotal   *int    `gorm:"column:disc_total" json:"disc_total"`
+	ReleaseDate *string `gorm:"column:release_date;size:10" json:"release_date"`
+	Year        *int    `gorm:"column:year;index:idx_year" json:"year"`
+	Genre       *string `gorm:"column:genre;size:100;index:idx_genre" json:"genre"`
+	Composer    *string `gorm:"column:composer;size:255" json:"composer"`
+	ISRC        *string `gorm:"column:isrc;size:12;index:idx_isrc" json:"isrc"`
+	Label       *string `gorm:"column:label;size:255" json:"label"`
+	Comment     *string `gorm:"column:comment;type:text" json:"comment"`
+
+	// CUE sheets: the parent keeps the raw sheet, every entry becomes a virtual track
+	// pointing at the parent file with its own offsets
+	CueSheet       *string `gorm:"column:cue_sheet;type:text" json:"cue_sheet"`
+	ParentID       *uint64 `gorm:"column:parent_id;index:idx_parent" json:"parent_id"`
+	SegmentStartMs *int64  `gorm:"column:segment_start_ms" json:"segment_start_ms"`
+	SegmentEndMs   *int64  `gorm:"column:segment_end_ms" json:"segment_end_ms"`
+
+	LoudnessIntegrated  *float64 `gorm:"column:loudness_integrated" json:"loudness_integrated"`
+	LoudnessTruePeak    *float64 `gorm:"column:loudness_true_peak" json:"loudness_true_peak"`
+	LoudnessRange       *float64 `gorm:"column:loudness_range" json:"loudness_range"`
+	ReplayGainTrackGain *float64 `gorm:"column:replaygain_track_gain" json:"replaygain_track_gain"`
+	ReplayGainTrackPeak *float64 `gorm:"column:replaygain_track_peak" json:"replaygain_track_peak"`
+	ReplayGainAlbumGain *float64 `gorm:"column:replaygain_album_gain" json:"replaygain_album_gain"`
+	ReplayGainAlbumPeak *float64 `gorm:"column:replaygain_album_peak" json:"replaygain_album_peak"`
+
+	Bpm           *float64 `gorm:"column:bpm;index:idx_bpm" json:"bpm"`
+	BpmConfidence *float64 `gorm:"column:bpm_confidence" json:"bpm_confidence"`
+	Key           *string  `gorm:"column:musical_key;size:4;index:idx_musical_key" json:"key"`
+	KeyConfidence *float64 `gorm:"column:key_confidence" json:"key_confidence"`
+
+	// gapless playback: encoder priming and padding in samples, trims in milliseconds
+	SampleRate     *int   `gorm:"column:sample_rate" json:"sample_rate"`
+	EncoderDelay   *int   `gorm:"column:encoder_delay" json:"encoder_delay"`
+	EncoderPadding *int   `gorm:"column:encoder_padding" json:"encoder_padding"`
+	TrimStartMs    *int64 `gorm:"column:trim_start_ms" json:"trim_start_ms"`
+	TrimEndMs      *int64 `gorm:"column:trim_end_ms" json:"trim_end_ms"`
 	Base
 
-	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	User     User           `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Artworks []TrackArtwork `gorm:"foreignKey:TrackID;constraint:OnDelete:CASCADE" json:"artworks,omitempty"`
+	Parent   *Track         `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"parent,omitempty"`
+	Credits  []TrackCredit  `gorm:"foreignKey:TrackID;constraint:OnDelete:CASCADE" json:"credits,omitempty"`
+	Tags     []Tag          `gorm:"many2many:track_tags;constraint:OnDelete:CASCADE" json:"tags,omitempty"`
+}
+
+// IsSegment reports whether the track is a virtual track cut from a CUE sheet parent
+func (t *Track) IsSegment() bool {
+	return t.ParentID != nil
 }
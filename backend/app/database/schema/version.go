@@ -0,0 +1,13 @@
+package schema
+
+// TrackVersion is a previous audio file of a track, kept when the file is replaced.
+// CreatedAt is the moment the file stopped being the current one.
+type TrackVersion struct {
+	ID               uint64 `gorm:"primary_key;column:id" json:"id"`
+	TrackID          uint64 `gorm:"column:track_id;not null;index:idx_track_version" json:"track_id"`
+	StorageFilename  string `gorm:"column:storage_filename;not null" json:"storage_filename"`
+	OriginalFilename string `gorm:"column:original_filename;not null" json:"original_filename"`
+	FileSize         int64  `gorm:"column:file_size;default:0" json:"file_size"`
+	MimeType         string `gorm:"column:mime_type;default:'audio/mpeg'" json:"mime_type"`
+	Base
+}
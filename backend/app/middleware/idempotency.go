@@ -0,0 +1,229 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/idempotency/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyKeyMaxLength = 255
+	idempotencyDefaultTTL   = 24 * time.Hour
+	idempotencyPurgeEvery   = time.Hour
+
+	// a reservation whose request never completed, e.g. after a crash, can be taken again after
+	// this lease. It outlasts the upload timeout and analysis of the slowest request.
+	idempotencyLease = 15 * time.Minute
+)
+
+// unix time of the last purge of expired keys
+var idempotencyPurgedAt atomic.Int64
+
+// Idempotent replays the stored response when a request is repeated with the same Idempotency-Key.
+// It must run after Protected, keys are scoped to the authenticated user.
+func Idempotent(store repository.IdempotencyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+
+		if len(key) > idempotencyKeyMaxLength {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, idempotencyKeyMaxLength))
+		}
+
+		token, ok := c.Locals("user").(*jwt.Token)
+		if !ok {
+			return c.Next()
+		}
+
+		claims, ok := token.Claims.(*JWTClaims)
+		if !ok {
+			return c.Next()
+		}
+
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			return err
+		}
+
+		purgeIdempotencyKeys(store)
+
+		record := &schema.IdempotencyKey{
+			UserID:      claims.UserID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   time.Now().Add(idempotencyLease),
+		}
+
+		existing, err := store.Reserve(record)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			if existing.Fingerprint != fingerprint {
+				return fiber.NewError(fiber.StatusUnprocessableEntity, IdempotencyKeyHeader+" was already used with a different request")
+			}
+
+			if existing.CompletedAt == nil {
+				return fiber.NewError(fiber.StatusConflict, "A request with this "+IdempotencyKeyHeader+" is still in progress")
+			}
+
+			c.Set(IdempotencyReplayedHeader, "true")
+			if existing.ContentType != "" {
+				c.Set(fiber.HeaderContentType, existing.ContentType)
+			}
+
+			return c.Status(existing.StatusCode).Send(existing.Response)
+		}
+
+		// release the key when the handler panics, the panic itself is left to the recover middleware
+		defer func() {
+			if r := recover(); r != nil {
+				if err := store.Release(record.ID); err != nil {
+					log.Printf("[idempotency] release key %d: %v", record.ID, err)
+				}
+				panic(r)
+			}
+		}()
+
+		if err := c.Next(); err != nil {
+			// render the error now so its response can be stored
+			if err := c.App().Config().ErrorHandler(c, err); err != nil {
+				_ = store.Release(record.ID)
+				return err
+			}
+		}
+
+		status := c.Response().StatusCode()
+		if idempotencyRetryable(status) {
+			if err := store.Release(record.ID); err != nil {
+				log.Printf("[idempotency] release key %d: %v", record.ID, err)
+			}
+
+			return nil
+		}
+
+		body := bytes.Clone(c.Response().Body())
+		if err := store.Complete(record.ID, status, string(c.Response().Header.ContentType()), body, time.Now().Add(idempotencyTTL())); err != nil {
+			log.Printf("[idempotency] store response for key %d: %v", record.ID, err)
+			_ = store.Release(record.ID)
+		}
+
+		return nil
+	}
+}
+
+// idempotencyRetryable reports whether a response says nothing was done yet and a retry with the
+// same key may succeed. Its key is released rather than replaying the refusal.
+func idempotencyRetryable(status int) bool {
+	switch status {
+	case fiber.StatusRequestTimeout, fiber.StatusConflict, fiber.StatusTooManyRequests:
+		return true
+	}
+
+	return status >= fiber.StatusInternalServerError
+}
+
+func idempotencyTTL() time.Duration {
+	if cfg == nil || cfg.Middleware.Idempotency.TTL <= 0 {
+		return idempotencyDefaultTTL
+	}
+
+	return cfg.Middleware.Idempotency.TTL * time.Second
+}
+
+// purgeIdempotencyKeys deletes expired keys in the background, at most once per idempotencyPurgeEvery
+func purgeIdempotencyKeys(store repository.IdempotencyRepository) {
+	now := time.Now()
+	last := idempotencyPurgedAt.Load()
+	if now.Sub(time.Unix(last, 0)) < idempotencyPurgeEvery || !idempotencyPurgedAt.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+
+	go func() {
+		if _, err := store.DeleteExpired(now); err != nil {
+			log.Printf("[idempotency] purge expired keys: %v", err)
+		}
+	}()
+}
+
+// requestFingerprint hashes the method, path and body of the request. Multipart bodies are hashed
+// by their fields and file contents, so a retry with a new boundary still matches.
+func requestFingerprint(c *fiber.Ctx) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", c.Method(), c.Path())
+
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		h.Write(c.Body())
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	for _, name := range sortedKeys(form.Value) {
+		for _, value := range form.Value[name] {
+			fmt.Fprintf(h, "value %q %q\n", name, value)
+		}
+	}
+
+	for _, name := range sortedKeys(form.File) {
+		for _, header := range form.File[name] {
+			fmt.Fprintf(h, "file %q %q %d ", name, header.Filename, header.Size)
+
+			file, err := header.Open()
+			if err != nil {
+				return "", err
+			}
+
+			err = hashFile(h, file)
+			_ = file.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h hash.Hash, r io.Reader) error {
+	sum := sha256.New()
+	if _, err := io.Copy(sum, r); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(h, "%x\n", sum.Sum(nil))
+
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
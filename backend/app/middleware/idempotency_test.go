@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// idempotencyStore keeps idempotency keys in memory
+type idempotencyStore struct {
+	mu   sync.Mutex
+	keys map[uint64]*schema.IdempotencyKey
+	next uint64
+}
+
+func (s *idempotencyStore) Reserve(record *schema.IdempotencyKey) (*schema.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.keys {
+		if existing.UserID == record.UserID && existing.Key == record.Key {
+			return existing, nil
+		}
+	}
+
+	s.next++
+	record.ID = s.next
+	s.keys[record.ID] = record
+
+	return nil, nil
+}
+
+func (s *idempotencyStore) Complete(id uint64, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	record := s.keys[id]
+	record.StatusCode, record.ContentType, record.Response = statusCode, contentType, body
+	record.CompletedAt, record.ExpiresAt = &now, expiresAt
+
+	return nil
+}
+
+func (s *idempotencyStore) Release(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id)
+	return nil
+}
+
+func (s *idempotencyStore) DeleteExpired(time.Time) (int64, error) {
+	return 0, nil
+}
+
+// withUser stands in for Protected, authenticating every request as user 1
+func withUser(c *fiber.Ctx) error {
+	c.Locals("user", &jwt.Token{Claims: &JWTClaims{UserID: 1}})
+	return c.Next()
+}
+
+func TestIdempotentRetryAfterRefusal(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "too many requests", status: fiber.StatusTooManyRequests},
+		{name: "conflict", status: fiber.StatusConflict},
+		{name: "request timeout", status: fiber.StatusRequestTimeout},
+		{name: "server error", status: fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &idempotencyStore{keys: map[uint64]*schema.IdempotencyKey{}}
+			app := fiber.New(fiber.Config{ErrorHandler: uresponse.ErrorHandler})
+
+			calls := 0
+			app.Post("/music", withUser, Idempotent(store), func(c *fiber.Ctx) error {
+				calls++
+				if calls == 1 {
+					return &uresponse.Error{Code: tt.status, Message: "busy", RetryAfter: 5 * time.Second}
+				}
+
+				return c.Status(fiber.StatusCreated).SendString("created")
+			})
+
+			send := func() (int, string) {
+				req := httptest.NewRequest(fiber.MethodPost, "/music", strings.NewReader(`{"title":"a"}`))
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				req.Header.Set(IdempotencyKeyHeader, "key-1")
+
+				res, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer res.Body.Close()
+
+				body, _ := io.ReadAll(res.Body)
+				return res.StatusCode, string(body)
+			}
+
+			if status, _ := send(); status != tt.status {
+				t.Fatalf("first request status = %d, want %d", status, tt.status)
+			}
+
+			status, body := send()
+			if status != fiber.StatusCreated || body != "created" {
+				t.Errorf("retry = %d %q, want %d %q", status, body, fiber.StatusCreated, "created")
+			}
+
+			// the success is what later retries replay
+			status, body = send()
+			if status != fiber.StatusCreated || body != "created" || calls != 2 {
+				t.Errorf("replay = %d %q after %d calls, want %d %q after 2", status, body, calls, fiber.StatusCreated, "created")
+			}
+		})
+	}
+}
+
+func TestIdempotentReplaysValidationError(t *testing.T) {
+	store := &idempotencyStore{keys: map[uint64]*schema.IdempotencyKey{}}
+	app := fiber.New(fiber.Config{ErrorHandler: uresponse.ErrorHandler})
+
+	calls := 0
+	app.Post("/music", withUser, Idempotent(store), func(c *fiber.Ctx) error {
+		calls++
+		return &uresponse.Error{Code: fiber.StatusBadRequest, Message: "title is required"}
+	})
+
+	for i := range 2 {
+		req := httptest.NewRequest(fiber.MethodPost, "/music", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+
+		res, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+
+		if res.StatusCode != fiber.StatusBadRequest {
+			t.Fatalf("request %d status = %d, want %d", i+1, res.StatusCode, fiber.StatusBadRequest)
+		}
+		if replayed := res.Header.Get(IdempotencyReplayedHeader) == "true"; replayed != (i == 1) {
+			t.Errorf("request %d replayed = %v", i+1, replayed)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
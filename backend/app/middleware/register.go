@@ -9,6 +9,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/expvar"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/monitor"
@@ -68,6 +69,10 @@ func (m *Middleware) Register() {
 		MaxAge: m.Cfg.Middleware.FileSystem.MaxAge,
 	}))
 
+	m.App.Use(expvar.New(expvar.Config{
+		Next: utils.IsEnabled(m.Cfg.Middleware.Expvar.Enable),
+	}))
+
 	m.App.Get(m.Cfg.Middleware.Monitor.Path, monitor.New(monitor.Config{
 		Next: utils.IsEnabled(m.Cfg.Middleware.Monitor.Enable),
 	}))
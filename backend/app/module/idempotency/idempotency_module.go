@@ -0,0 +1,11 @@
+package idempotency
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/idempotency/repository"
+	"go.uber.org/fx"
+)
+
+var NewIdempotencyModule = fx.Options(
+	// register repository of idempotency module
+	fx.Provide(repository.NewIdempotencyRepository),
+)
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepository struct {
+	DB *database.Database
+}
+
+type IdempotencyRepository interface {
+	Reserve(record *schema.IdempotencyKey) (existing *schema.IdempotencyKey, err error)
+	Complete(id uint64, statusCode int, contentType string, body []byte, expiresAt time.Time) (err error)
+	Release(id uint64) (err error)
+	DeleteExpired(now time.Time) (deleted int64, err error)
+}
+
+func NewIdempotencyRepository(db *database.Database) IdempotencyRepository {
+	return &idempotencyRepository{
+		DB: db,
+	}
+}
+
+// Reserve inserts record unless the user already used its key. It returns nil when the key was
+// reserved for this request, otherwise the stored record. Expired records are replaced.
+func (_i *idempotencyRepository) Reserve(record *schema.IdempotencyKey) (existing *schema.IdempotencyKey, err error) {
+	for range 2 {
+		res := _i.DB.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+
+		if res.RowsAffected > 0 {
+			return nil, nil
+		}
+
+		existing = new(schema.IdempotencyKey)
+		if err := _i.DB.DB.Unscoped().Where("user_id = ? AND idempotency_key = ?", record.UserID, record.Key).First(existing).Error; err != nil {
+			return nil, err
+		}
+
+		if existing.ExpiresAt.After(time.Now()) {
+			return existing, nil
+		}
+
+		if err := _i.DB.DB.Unscoped().Delete(existing).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response of a reserved key, replaying it until expiresAt
+func (_i *idempotencyRepository) Complete(id uint64, statusCode int, contentType string, body []byte, expiresAt time.Time) (err error) {
+	return _i.DB.DB.Model(&schema.IdempotencyKey{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status_code":  statusCode,
+			"content_type": contentType,
+			"response":     body,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// Release frees a reserved key whose request failed, so it can be retried
+func (_i *idempotencyRepository) Release(id uint64) (err error) {
+	return _i.DB.DB.Unscoped().Delete(&schema.IdempotencyKey{}, id).Error
+}
+
+func (_i *idempotencyRepository) DeleteExpired(now time.Time) (deleted int64, err error) {
+	res := _i.DB.DB.Unscoped().Where("expires_at < ?", now).Delete(&schema.IdempotencyKey{})
+
+	return res.RowsAffected, res.Error
+}
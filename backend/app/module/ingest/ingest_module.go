@@ -0,0 +1,30 @@
+package ingest
+
+import (
+	"context"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/ingest/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/ingest/service"
+	"go.uber.org/fx"
+)
+
+var NewIngestModule = fx.Options(
+	// register repository of ingest module
+	fx.Provide(repository.NewIngestRepository),
+
+	// register watch folder
+	fx.Provide(service.NewWatcher),
+)
+
+// StartWatcher runs the watch folder with the app. Invoke it after the webserver so the
+// database is connected before the first file is imported.
+func StartWatcher(lifecycle fx.Lifecycle, watcher *service.Watcher) {
+	lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			return watcher.Start()
+		},
+		OnStop: func(context.Context) error {
+			return watcher.Stop()
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+)
+
+type ingestRepository struct {
+	DB *database.Database
+}
+
+type IngestRepository interface {
+	CreateFailure(failure *schema.IngestFailure) (err error)
+	HasFailure(path string, size int64, modifiedAt time.Time) (found bool, err error)
+}
+
+func NewIngestRepository(db *database.Database) IngestRepository {
+	return &ingestRepository{
+		DB: db,
+	}
+}
+
+func (_i *ingestRepository) CreateFailure(failure *schema.IngestFailure) (err error) {
+	return _i.DB.DB.Create(failure).Error
+}
+
+// HasFailure reports whether the file already failed to import without being changed since
+func (_i *ingestRepository) HasFailure(path string, size int64, modifiedAt time.Time) (found bool, err error) {
+	var count int64
+	err = _i.DB.DB.Model(&schema.IngestFailure{}).
+		Where("path = ? AND file_size = ? AND file_modified_at = ?", path, size, modifiedAt).
+		Count(&count).Error
+
+	return count > 0, err
+}
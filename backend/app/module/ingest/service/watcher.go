@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/ingest/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	"github.com/fsnotify/fsnotify"
+
+	trackService "git.dev.siap.id/kukuhkkh/app-music/app/module/track/service"
+)
+
+const (
+	defaultDebounce = 5 * time.Second
+	defaultDoneDir  = "imported"
+
+	// IngestSourceWatch and IngestSourceImport mark failures of the watch folder and of the import command
+	IngestSourceWatch  = "watch"
+	IngestSourceImport = "import"
+)
+
+// Watcher imports audio files dropped into the watched directories of the local storage
+// driver. A file is picked up once it has not changed for the debounce interval, so files
+// that are still being copied are never read half written.
+type Watcher struct {
+	cfg    *config.Config
+	repo   repository.IngestRepository
+	tracks trackService.TrackService
+
+	fsw     *fsnotify.Watcher
+	roots   []string
+	skip    map[string]bool
+	queue   chan string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	pending map[string]*pendingFile
+}
+
+// pendingFile is a file waiting for its writes to settle
+type pendingFile struct {
+	timer *time.Timer
+	size  int64
+}
+
+func NewWatcher(cfg *config.Config, repo repository.IngestRepository, tracks trackService.TrackService) *Watcher {
+	return &Watcher{
+		cfg:     cfg,
+		repo:    repo,
+		tracks:  tracks,
+		pending: make(map[string]*pendingFile),
+	}
+}
+
+// Start begins watching the configured directories and queues the audio files already in them.
+// It is a no-op unless the watch folder is enabled for the local storage driver.
+func (w *Watcher) Start() error {
+	watch := w.cfg.Storage.Watch
+	if !watch.Enable {
+		return nil
+	}
+
+	if w.cfg.Storage.Driver != "local" {
+		log.Printf("[ingest] watch folder needs the local storage driver, driver=%s", w.cfg.Storage.Driver)
+		return nil
+	}
+
+	if watch.UserID == 0 || len(watch.Dirs) == 0 {
+		return fmt.Errorf("storage.watch needs dirs and user_id")
+	}
+
+	if watch.After != "move" && watch.After != "delete" {
+		return fmt.Errorf("storage.watch.after must be move or delete, got %q", watch.After)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	w.fsw = fsw
+	w.skip = make(map[string]bool)
+	for _, dir := range watch.Dirs {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			_ = fsw.Close()
+			return err
+		}
+
+		w.roots = append(w.roots, root)
+		w.skip[w.doneDir(root)] = true
+	}
+
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.queue = make(chan string, 64)
+
+	w.wg.Add(2)
+	go w.watch(w.ctx)
+	go w.work(w.ctx)
+
+	for _, root := range w.roots {
+		if err := w.add(root); err != nil {
+			log.Printf("[ingest] watch %s err=%v", root, err)
+		}
+	}
+
+	log.Printf("[ingest] watching %s user=%d", strings.Join(w.roots, ", "), watch.UserID)
+	return nil
+}
+
+// Stop ends watching, files still waiting for the debounce interval are picked up on the next start
+func (w *Watcher) Stop() error {
+	if w.fsw == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	for path, p := range w.pending {
+		p.timer.Stop()
+		delete(w.pending, path)
+	}
+	w.mu.Unlock()
+
+	w.cancel()
+	err := w.fsw.Close()
+	w.wg.Wait()
+
+	return err
+}
+
+// add watches dir and its subdirectories and schedules the files already inside
+func (w *Watcher) add(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if w.skip[path] || (path != dir && isHidden(path)) {
+				return filepath.SkipDir
+			}
+			return w.fsw.Add(path)
+		}
+
+		w.schedule(path)
+		return nil
+	})
+}
+
+func (w *Watcher) watch(ctx context.Context) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("[ingest] watcher err=%v", err)
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+
+			switch {
+			case event.Has(fsnotify.Create):
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.add(event.Name); err != nil {
+						log.Printf("[ingest] watch %s err=%v", event.Name, err)
+					}
+					continue
+				}
+				w.schedule(event.Name)
+			case event.Has(fsnotify.Write):
+				w.schedule(event.Name)
+			case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+				w.forget(event.Name)
+			}
+		}
+	}
+}
+
+// schedule (re)starts the debounce timer of an audio file
+func (w *Watcher) schedule(path string) {
+	if isHidden(path) || audio.FormatOf("", path) == audio.FormatUnknown {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if p, ok := w.pending[path]; ok {
+		p.size = info.Size()
+		p.timer.Reset(w.debounce())
+		return
+	}
+
+	w.pending[path] = &pendingFile{
+		size:  info.Size(),
+		timer: time.AfterFunc(w.debounce(), func() { w.settle(path) }),
+	}
+}
+
+func (w *Watcher) forget(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if p, ok := w.pending[path]; ok {
+		p.timer.Stop()
+		delete(w.pending, path)
+	}
+}
+
+// settle queues a file whose size did not change during the debounce interval
+func (w *Watcher) settle(path string) {
+	w.mu.Lock()
+	p, ok := w.pending[path]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err == nil && info.Size() != p.size {
+		// written without events, e.g. on network shares
+		p.size = info.Size()
+		p.timer.Reset(w.debounce())
+		w.mu.Unlock()
+		return
+	}
+
+	delete(w.pending, path)
+	w.mu.Unlock()
+
+	if err != nil {
+		return
+	}
+
+	select {
+	case w.queue <- path:
+	case <-w.ctx.Done():
+	}
+}
+
+// work imports queued files one at a time
+func (w *Watcher) work(ctx context.Context) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path := <-w.queue:
+			w.ingest(ctx, path)
+		}
+	}
+}
+
+func (w *Watcher) ingest(ctx context.Context, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	modifiedAt := info.ModTime().Truncate(time.Second)
+
+	failed, err := w.repo.HasFailure(path, info.Size(), modifiedAt)
+	if err != nil {
+		log.Printf("[ingest] check failures name=%s err=%v", path, err)
+		return
+	}
+	if failed {
+		return
+	}
+
+	start := time.Now()
+	track, err := w.tracks.ImportFile(ctx, w.rootOf(path), path, w.cfg.Storage.Watch.UserID)
+	if err != nil {
+		log.Printf("[ingest] import failed name=%s err=%v", path, err)
+
+		if err := w.repo.CreateFailure(&schema.IngestFailure{
+			Source:         IngestSourceWatch,
+			Path:           path,
+			FileSize:       info.Size(),
+			FileModifiedAt: &modifiedAt,
+			Error:          err.Error(),
+		}); err != nil {
+			log.Printf("[ingest] record failure name=%s err=%v", path, err)
+		}
+		return
+	}
+
+	log.Printf("[ingest] imported name=%s id=%d dur=%s", path, track.ID, time.Since(start))
+
+	if err := w.finish(path); err != nil {
+		log.Printf("[ingest] clean up name=%s err=%v", path, err)
+	}
+}
+
+// finish moves an imported file into the done directory of its root, or deletes it
+func (w *Watcher) finish(path string) error {
+	if w.cfg.Storage.Watch.After == "delete" {
+		return os.Remove(path)
+	}
+
+	root := w.rootOf(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+
+	dst := filepath.Join(w.doneDir(root), rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dst); err == nil {
+		ext := filepath.Ext(dst)
+		dst = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(dst, ext), time.Now().Unix(), ext)
+	}
+
+	return moveFile(path, dst)
+}
+
+// doneDir is where imported files of a watched root are moved, relative paths are inside the root
+func (w *Watcher) doneDir(root string) string {
+	dir := w.cfg.Storage.Watch.DoneDir
+	if dir == "" {
+		dir = defaultDoneDir
+	}
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+
+	return filepath.Join(root, dir)
+}
+
+func (w *Watcher) rootOf(path string) string {
+	for _, root := range w.roots {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return root
+		}
+	}
+
+	return filepath.Dir(path)
+}
+
+func (w *Watcher) debounce() time.Duration {
+	if d := w.cfg.Storage.Watch.DebounceSeconds; d > 0 {
+		return time.Duration(d) * time.Second
+	}
+
+	return defaultDebounce
+}
+
+// moveFile renames src to dst, copying across filesystems when a rename is not possible
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+// isHidden skips dot files such as editor swap files and partial downloads
+func isHidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type albumController struct {
+	libraryService service.LibraryService
+}
+
+type AlbumController interface {
+	GetAlbums(c *fiber.Ctx) error
+	GetAlbumByID(c *fiber.Ctx) error
+	Like(c *fiber.Ctx) error
+	Unlike(c *fiber.Ctx) error
+}
+
+func NewAlbumController(libraryService service.LibraryService) AlbumController {
+	return &albumController{
+		libraryService: libraryService,
+	}
+}
+
+// GetAlbums godoc
+// @Summary      Get paginated albums
+// @Description  Get list of albums with their album artist, year, artwork, track count and total duration
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Param        search    query string false "Search by title"
+// @Param        artist_id query int    false "Only albums of this album artist"
+// @Param        page      query int    false "Page number"
+// @Param        limit     query int    false "Items per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /albums [get]
+func (_i *albumController) GetAlbums(c *fiber.Ctx) error {
+	p, _ := paginator.Paginate(c)
+
+	req := new(request.AlbumPaginationRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	albums, p, err := _i.libraryService.GetAlbums(*req, claims.UserID, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get albums success"},
+		Data:     albums,
+		Meta:     paginator.Paging(p),
+	})
+}
+
+// GetAlbumByID godoc
+// @Summary      Get album by ID
+// @Description  Get an album with a page of its tracks, the pagination applies to the tracks
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Param        id    path  uint64 true  "Album ID"
+// @Param        page  query int    false "Page number of the tracks"
+// @Param        limit query int    false "Tracks per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /albums/{id} [get]
+func (_i *albumController) GetAlbumByID(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	p, _ := paginator.Paginate(c)
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, p, err := _i.libraryService.GetAlbumByID(uint64(id), claims.UserID, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get album success"},
+		Data:     res,
+		Meta:     paginator.Paging(p),
+	})
+}
+
+// Like godoc
+// @Summary      Like album
+// @Description  Add an album to the likes of the current user, liking it again changes nothing
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Album ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /albums/{id}/like [post]
+func (_i *albumController) Like(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.libraryService.LikeAlbum(uint64(id), claims.UserID); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Like album success"},
+	})
+}
+
+// Unlike godoc
+// @Summary      Unlike album
+// @Description  Remove an album from the likes of the current user
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Album ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /albums/{id}/like [delete]
+func (_i *albumController) Unlike(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.libraryService.UnlikeAlbum(uint64(id), claims.UserID); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Unlike album success"},
+	})
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type artistController struct {
+	libraryService service.LibraryService
+}
+
+type ArtistController interface {
+	GetArtists(c *fiber.Ctx) error
+	GetArtistByID(c *fiber.Ctx) error
+}
+
+func NewArtistController(libraryService service.LibraryService) ArtistController {
+	return &artistController{
+		libraryService: libraryService,
+	}
+}
+
+// GetArtists godoc
+// @Summary      Get paginated artists
+// @Description  Get list of artists with their track count, album count and total duration
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Param        search query string false "Search by name"
+// @Param        page   query int    false "Page number"
+// @Param        limit  query int    false "Items per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /artists [get]
+func (_i *artistController) GetArtists(c *fiber.Ctx) error {
+	p, _ := paginator.Paginate(c)
+
+	req := new(request.ArtistPaginationRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	artists, p, err := _i.libraryService.GetArtists(*req, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get artists success"},
+		Data:     artists,
+		Meta:     paginator.Paging(p),
+	})
+}
+
+// GetArtistByID godoc
+// @Summary      Get artist by ID
+// @Description  Get an artist with its albums, its track count per credit role and a page of the tracks it is credited on in role, the pagination applies to the tracks
+// @Tags         Library
+// @Accept       json
+// @Produce      json
+// @Param        id    path  uint64 true  "Artist ID"
+// @Param        role  query string false "Credit role of the listed tracks: primary (default), featured, remixer, producer or composer"
+// @Param        page  query int    false "Page number of the tracks"
+// @Param        limit query int    false "Tracks per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /artists/{id} [get]
+func (_i *artistController) GetArtistByID(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	p, _ := paginator.Paginate(c)
+
+	req := new(request.ArtistTracksRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, p, err := _i.libraryService.GetArtistByID(uint64(id), claims.UserID, *req, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get artist success"},
+		Data:     res,
+		Meta:     paginator.Paging(p),
+	})
+}
@@ -0,0 +1,15 @@
+package controller
+
+import "git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+
+type Controller struct {
+	Artist ArtistController
+	Album  AlbumController
+}
+
+func NewController(libraryService service.LibraryService) *Controller {
+	return &Controller{
+		Artist: NewArtistController(libraryService),
+		Album:  NewAlbumController(libraryService),
+	}
+}
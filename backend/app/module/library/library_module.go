@@ -0,0 +1,55 @@
+package library
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/controller"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+)
+
+type LibraryRouter struct {
+	App        fiber.Router
+	Controller *controller.Controller
+}
+
+var NewLibraryModule = fx.Options(
+	// register repository of library module
+	fx.Provide(repository.NewLibraryRepository),
+
+	// register service of library module
+	fx.Provide(service.NewLibraryService),
+
+	// register controller of library module
+	fx.Provide(controller.NewController),
+
+	// register router of library module
+	fx.Provide(NewLibraryRouter),
+)
+
+func NewLibraryRouter(fiber *fiber.App, controller *controller.Controller) *LibraryRouter {
+	return &LibraryRouter{
+		App:        fiber,
+		Controller: controller,
+	}
+}
+
+func (_i *LibraryRouter) RegisterLibraryRoutes() {
+	// define controllers
+	artistController := _i.Controller.Artist
+	albumController := _i.Controller.Album
+
+	// define routes
+	_i.App.Route("/artists", func(router fiber.Router) {
+		router.Get("", middleware.Protected(), artistController.GetArtists)
+		router.Get("/:id", middleware.Protected(), artistController.GetArtistByID)
+	})
+
+	_i.App.Route("/albums", func(router fiber.Router) {
+		router.Get("", middleware.Protected(), albumController.GetAlbums)
+		router.Get("/:id", middleware.Protected(), albumController.GetAlbumByID)
+		router.Post("/:id/like", middleware.Protected(), albumController.Like)
+		router.Delete("/:id/like", middleware.Protected(), albumController.Unlike)
+	})
+}
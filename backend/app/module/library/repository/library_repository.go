@@ -0,0 +1,453 @@
+package repository
+
+import (
+	"errors"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	trackRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+)
+
+// Stats sums the listed tracks of an artist or album, CUE parents are counted through their segments
+type Stats struct {
+	ID         uint64
+	TrackCount int64
+	AlbumCount int64
+	DurationMs int64
+}
+
+type libraryRepository struct {
+	DB *database.Database
+}
+
+type LibraryRepository interface {
+	PaginateArtists(search string, p *paginator.Pagination) (artists []schema.Artist, pagination *paginator.Pagination, err error)
+	FindArtistByID(id uint64) (artist *schema.Artist, err error)
+	FirstOrCreateArtist(name string) (artist *schema.Artist, err error)
+	ArtistExists(name string) (found bool, err error)
+	ArtistStats(ids []uint64) (stats map[uint64]Stats, err error)
+	ArtistRoles(id uint64) (roles map[string]int64, err error)
+	PaginateAlbums(search string, artistID uint64, p *paginator.Pagination) (albums []schema.Album, pagination *paginator.Pagination, err error)
+	ListArtistAlbums(artistID uint64) (albums []schema.Album, err error)
+	FindAlbumByID(id uint64) (album *schema.Album, err error)
+	FindAlbumsByIDs(ids []uint64) (albums []schema.Album, err error)
+	FirstOrCreateAlbum(artistID uint64, title string, year *int) (album *schema.Album, err error)
+	AlbumStats(ids []uint64) (stats map[uint64]Stats, err error)
+	AlbumArtworks(ids []uint64) (artworks map[uint64][]schema.TrackArtwork, err error)
+	PaginateTracks(column string, id uint64, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error)
+	PaginateCreditedTracks(artistID uint64, role string, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error)
+	ListUnlinkedTracks(afterID uint64, limit int) (tracks []schema.Track, err error)
+	CountAlbumArtists(userID uint64, album string) (count int64, err error)
+	LinkTrack(track *schema.Track) (err error)
+	DeleteUnused(albumIDs []uint64, artistIDs []uint64) (err error)
+	Transaction(fn func(tx *gorm.DB) error) (err error)
+	WithTx(tx *gorm.DB) LibraryRepository
+}
+
+func NewLibraryRepository(db *database.Database) LibraryRepository {
+	return &libraryRepository{
+		DB: db,
+	}
+}
+
+// Transaction runs fn in a database transaction, repositories join it through WithTx
+func (_i *libraryRepository) Transaction(fn func(tx *gorm.DB) error) (err error) {
+	return _i.DB.DB.Transaction(fn)
+}
+
+// WithTx returns the repository running its queries in the transaction tx
+func (_i *libraryRepository) WithTx(tx *gorm.DB) LibraryRepository {
+	db := *_i.DB
+	db.DB = tx
+
+	return &libraryRepository{
+		DB: &db,
+	}
+}
+
+// listedTracks are the tracks shown in the library, CUE parents are listed through their segments
+func (_i *libraryRepository) listedTracks() *gorm.DB {
+	return _i.DB.DB.Model(&schema.Track{}).Where("tracks.cue_sheet IS NULL")
+}
+
+func (_i *libraryRepository) PaginateArtists(search string, p *paginator.Pagination) (artists []schema.Artist, pagination *paginator.Pagination, err error) {
+	query := _i.DB.DB.Model(&schema.Artist{})
+	if search != "" {
+		query = query.Where("name LIKE ?", "%"+search+"%")
+	}
+
+	if err = query.Count(&p.Count).Error; err != nil {
+		return
+	}
+
+	err = query.Offset(p.Offset).Limit(p.Limit).Order("name ASC, id ASC").Find(&artists).Error
+
+	return artists, p, err
+}
+
+func (_i *libraryRepository) FindArtistByID(id uint64) (artist *schema.Artist, err error) {
+	if err := _i.DB.DB.First(&artist, id).Error; err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// FirstOrCreateArtist returns the artist named name, creating it when it does not exist yet.
+// Concurrent calls with the same name return the same row.
+func (_i *libraryRepository) FirstOrCreateArtist(name string) (artist *schema.Artist, err error) {
+	return firstOrCreate(_i.DB.DB, &schema.Artist{Name: name}, "name = ?", name)
+}
+
+// ArtistExists reports whether an artist is named name
+func (_i *libraryRepository) ArtistExists(name string) (found bool, err error) {
+	var count int64
+	err = _i.DB.DB.Model(&schema.Artist{}).Where("name = ?", name).Limit(1).Count(&count).Error
+
+	return count > 0, err
+}
+
+// creditedTracks are the listed tracks joined with their credits in role
+func (_i *libraryRepository) creditedTracks(role string) *gorm.DB {
+	query := _i.listedTracks().Joins("JOIN track_credits ON track_credits.track_id = tracks.id AND track_credits.deleted_at IS NULL")
+	if role != "" {
+		query = query.Where("track_credits.role = ?", role)
+	}
+
+	return query
+}
+
+// ArtistStats sums the tracks the artists are a primary artist of
+func (_i *libraryRepository) ArtistStats(ids []uint64) (stats map[uint64]Stats, err error) {
+	stats = make(map[uint64]Stats, len(ids))
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	var rows []Stats
+	err = _i.creditedTracks(schema.RolePrimary).
+		Select("track_credits.artist_id AS id, COUNT(*) AS track_count, COUNT(DISTINCT tracks.album_id) AS album_count, "+durationSum).
+		Where("track_credits.artist_id IN ?", ids).
+		Group("track_credits.artist_id").
+		Scan(&rows).Error
+
+	for _, row := range rows {
+		stats[row.ID] = row
+	}
+
+	return stats, err
+}
+
+// ArtistRoles counts the tracks an artist is credited on per role
+func (_i *libraryRepository) ArtistRoles(id uint64) (roles map[string]int64, err error) {
+	var rows []struct {
+		Role  string
+		Count int64
+	}
+	err = _i.creditedTracks("").
+		Select("track_credits.role AS role, COUNT(*) AS count").
+		Where("track_credits.artist_id = ?", id).
+		Group("track_credits.role").
+		Scan(&rows).Error
+
+	roles = make(map[string]int64, len(rows))
+	for _, row := range rows {
+		roles[row.Role] = row.Count
+	}
+
+	return roles, err
+}
+
+func (_i *libraryRepository) PaginateAlbums(search string, artistID uint64, p *paginator.Pagination) (albums []schema.Album, pagination *paginator.Pagination, err error) {
+	query := _i.DB.DB.Model(&schema.Album{}).Preload("Artist")
+	if search != "" {
+		query = query.Where("title LIKE ?", "%"+search+"%")
+	}
+
+	if artistID != 0 {
+		query = query.Where("artist_id = ?", artistID)
+	}
+
+	if err = query.Count(&p.Count).Error; err != nil {
+		return
+	}
+
+	err = query.Offset(p.Offset).Limit(p.Limit).Order("title ASC, id ASC").Find(&albums).Error
+
+	return albums, p, err
+}
+
+// ListArtistAlbums returns the albums an artist is the album artist of, newest first
+func (_i *libraryRepository) ListArtistAlbums(artistID uint64) (albums []schema.Album, err error) {
+	err = _i.DB.DB.Preload("Artist").
+		Where("artist_id = ?", artistID).
+		Order("year IS NULL, year DESC, title ASC").
+		Find(&albums).Error
+
+	return
+}
+
+func (_i *libraryRepository) FindAlbumByID(id uint64) (album *schema.Album, err error) {
+	if err := _i.DB.DB.Preload("Artist").First(&album, id).Error; err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// FindAlbumsByIDs returns the existing albums among ids, in no particular order
+func (_i *libraryRepository) FindAlbumsByIDs(ids []uint64) (albums []schema.Album, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	err = _i.DB.DB.Preload("Artist").Where("id IN ?", ids).Find(&albums).Error
+
+	return
+}
+
+// FirstOrCreateAlbum returns the album of an album artist titled title, creating it when it does
+// not exist yet. A year fills in an album that has none.
+func (_i *libraryRepository) FirstOrCreateAlbum(artistID uint64, title string, year *int) (album *schema.Album, err error) {
+	album, err = firstOrCreate(_i.DB.DB, &schema.Album{ArtistID: artistID, Title: title, Year: year}, "artist_id = ? AND title = ?", artistID, title)
+	if err != nil {
+		return nil, err
+	}
+
+	if album.Year == nil && year != nil {
+		if err := _i.DB.DB.Model(album).Where("year IS NULL").Update("year", *year).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return album, nil
+}
+
+func (_i *libraryRepository) AlbumStats(ids []uint64) (stats map[uint64]Stats, err error) {
+	stats = make(map[uint64]Stats, len(ids))
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	var rows []Stats
+	err = _i.listedTracks().
+		Select("album_id AS id, COUNT(*) AS track_count, "+durationSum).
+		Where("album_id IN ?", ids).
+		Group("album_id").
+		Scan(&rows).Error
+
+	for _, row := range rows {
+		stats[row.ID] = row
+	}
+
+	return stats, err
+}
+
+// AlbumArtworks returns the artwork of every album, taken from its first track that has artwork.
+// CUE parents are included, their segments share the parent artwork.
+func (_i *libraryRepository) AlbumArtworks(ids []uint64) (artworks map[uint64][]schema.TrackArtwork, err error) {
+	artworks = make(map[uint64][]schema.TrackArtwork, len(ids))
+	if len(ids) == 0 {
+		return artworks, nil
+	}
+
+	var covers []struct {
+		AlbumID uint64
+		TrackID uint64
+	}
+	err = _i.DB.DB.Model(&schema.Track{}).
+		Select("album_id, MIN(id) AS track_id").
+		Where("album_id IN ?", ids).
+		Where("EXISTS (?)", _i.DB.DB.Model(&schema.TrackArtwork{}).Select("1").Where("track_artworks.track_id = tracks.id")).
+		Group("album_id").
+		Scan(&covers).Error
+	if err != nil || len(covers) == 0 {
+		return artworks, err
+	}
+
+	albums := make(map[uint64]uint64, len(covers))
+	trackIDs := make([]uint64, 0, len(covers))
+	for _, c := range covers {
+		albums[c.TrackID] = c.AlbumID
+		trackIDs = append(trackIDs, c.TrackID)
+	}
+
+	var rows []schema.TrackArtwork
+	if err := _i.DB.DB.Where("track_id IN ?", trackIDs).Find(&rows).Error; err != nil {
+		return artworks, err
+	}
+
+	for _, a := range rows {
+		albumID := albums[a.TrackID]
+		artworks[albumID] = append(artworks[albumID], a)
+	}
+
+	return artworks, nil
+}
+
+// albumOrder lists tracks grouped by album in play order
+const albumOrder = "tracks.album_id, tracks.disc_number IS NULL, tracks.disc_number, tracks.track_number IS NULL, tracks.track_number, tracks.segment_start_ms, tracks.id"
+
+// PaginateTracks lists the tracks whose column (artist_id or album_id) is id, in album order
+func (_i *libraryRepository) PaginateTracks(column string, id uint64, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error) {
+	query := _i.listedTracks().
+		Where(clause.Eq{Column: clause.Column{Table: "tracks", Name: column}, Value: id})
+
+	return paginateTracks(query, p)
+}
+
+// PaginateCreditedTracks lists the tracks an artist is credited on in role, in album order
+func (_i *libraryRepository) PaginateCreditedTracks(artistID uint64, role string, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error) {
+	query := _i.creditedTracks(role).Where("track_credits.artist_id = ?", artistID)
+
+	return paginateTracks(query, p)
+}
+
+func paginateTracks(query *gorm.DB, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error) {
+	if err = query.Count(&p.Count).Error; err != nil {
+		return
+	}
+
+	err = query.Select("tracks.*").Scopes(trackRepository.PreloadTrack).
+		Offset(p.Offset).Limit(p.Limit).Order(albumOrder).Find(&tracks).Error
+
+	return tracks, p, err
+}
+
+// ListUnlinkedTracks returns a batch of tracks with their credits, ordered by ID, whose artist or
+// album is not linked to the library yet
+func (_i *libraryRepository) ListUnlinkedTracks(afterID uint64, limit int) (tracks []schema.Track, err error) {
+	err = _i.DB.DB.Preload("Credits").
+		Where("artist_id IS NULL OR (album_id IS NULL AND album IS NOT NULL AND album <> '') OR NOT EXISTS (?)",
+			_i.DB.DB.Model(&schema.TrackCredit{}).Select("1").Where("track_credits.track_id = tracks.id")).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&tracks).Error
+
+	return
+}
+
+// CountAlbumArtists counts the distinct artists of the tracks of a user tagged with album
+func (_i *libraryRepository) CountAlbumArtists(userID uint64, album string) (count int64, err error) {
+	err = _i.listedTracks().
+		Where("user_id = ? AND album = ?", userID, album).
+		Distinct("artist").
+		Count(&count).Error
+
+	return
+}
+
+// LinkTrack saves the artist, album and credits of a saved track, replacing its previous credits
+func (_i *libraryRepository) LinkTrack(track *schema.Track) (err error) {
+	return _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&schema.Track{}).
+			Where("id = ?", track.ID).
+			Updates(map[string]any{
+				"artist_id": track.ArtistID,
+				"album_id":  track.AlbumID,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("track_id = ?", track.ID).Delete(&schema.TrackCredit{}).Error; err != nil {
+			return err
+		}
+
+		for i := range track.Credits {
+			track.Credits[i].TrackID = track.ID
+		}
+		if len(track.Credits) == 0 {
+			return nil
+		}
+
+		return tx.Omit("Artist").Create(&track.Credits).Error
+	})
+}
+
+// DeleteUnused removes the albums among albumIDs without tracks and their likes, then the artists
+// among artistIDs and the album artists of removed albums with neither tracks, albums nor credits
+func (_i *libraryRepository) DeleteUnused(albumIDs []uint64, artistIDs []uint64) (err error) {
+	if len(albumIDs) > 0 {
+		var unused []schema.Album
+		err = _i.DB.DB.Unscoped().
+			Where("id IN ?", albumIDs).
+			Where("NOT EXISTS (?)", _i.DB.DB.Model(&schema.Track{}).Select("1").Where("tracks.album_id = albums.id")).
+			Find(&unused).Error
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uint64, 0, len(unused))
+		for _, a := range unused {
+			ids = append(ids, a.ID)
+			artistIDs = append(artistIDs, a.ArtistID)
+		}
+
+		if len(ids) > 0 {
+			err = _i.DB.DB.Unscoped().
+				Where("id IN ?", ids).
+				Where("NOT EXISTS (?)", _i.DB.DB.Model(&schema.Track{}).Select("1").Where("tracks.album_id = albums.id")).
+				Delete(&schema.Album{}).Error
+			if err != nil {
+				return err
+			}
+
+			err = _i.DB.DB.Unscoped().
+				Where("target_type = ? AND target_id IN ?", schema.LikeAlbum, ids).
+				Where("NOT EXISTS (?)", _i.DB.DB.Unscoped().Model(&schema.Album{}).Select("1").Where("albums.id = likes.target_id")).
+				Delete(&schema.Like{}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return _i.deleteUnusedArtists(artistIDs)
+}
+
+func (_i *libraryRepository) deleteUnusedArtists(ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return _i.DB.DB.Unscoped().
+		Where("id IN ?", ids).
+		Where("NOT EXISTS (?)", _i.DB.DB.Model(&schema.Track{}).Select("1").Where("tracks.artist_id = artists.id")).
+		Where("NOT EXISTS (?)", _i.DB.DB.Model(&schema.Album{}).Select("1").Where("albums.artist_id = artists.id")).
+		Where("NOT EXISTS (?)", _i.DB.DB.Model(&schema.TrackCredit{}).Select("1").
+			Joins("JOIN tracks ON tracks.id = track_credits.track_id AND tracks.deleted_at IS NULL").
+			Where("track_credits.artist_id = artists.id")).
+		Delete(&schema.Artist{}).Error
+}
+
+// firstOrCreate loads the row matching query, inserting row first when there is none.
+// A row inserted concurrently by another request is loaded instead. The row is loaded with a
+// locking read, which sees rows committed after a transaction started and keeps a concurrent
+// prune from deleting the row until the transaction linking a track to it commits.
+func firstOrCreate[T any](db *gorm.DB, row *T, query string, args ...any) (*T, error) {
+	err := db.Where(query, args...).First(new(T)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// only existing rows are locked, locking a missing one takes a gap lock that makes
+		// concurrent inserts of the same name deadlock
+		err = db.Clauses(clause.OnConflict{DoNothing: true}).Create(row).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found := new(T)
+	if err := db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).Where(query, args...).First(found).Error; err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// durationSum adds up the exact duration of tracks, falling back to the rounded seconds
+const durationSum = "COALESCE(SUM(COALESCE(duration_ms, duration * 1000)), 0) AS duration_ms"
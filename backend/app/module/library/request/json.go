@@ -0,0 +1,21 @@
+package request
+
+type ArtistPaginationRequest struct {
+	Search string `query:"search"`
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+}
+
+// ArtistTracksRequest pages the tracks of an artist page, Role defaults to primary
+type ArtistTracksRequest struct {
+	Role  string `query:"role"`
+	Page  int    `query:"page"`
+	Limit int    `query:"limit"`
+}
+
+type AlbumPaginationRequest struct {
+	Search   string `query:"search"`
+	ArtistID uint64 `query:"artist_id"`
+	Page     int    `query:"page"`
+	Limit    int    `query:"limit"`
+}
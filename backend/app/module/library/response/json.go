@@ -0,0 +1,108 @@
+package response
+
+import (
+	"strconv"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/repository"
+
+	trackResponse "git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+)
+
+type ArtistResponse struct {
+	ID         uint64 `json:"id"`
+	Name       string `json:"name"`
+	TrackCount int64  `json:"track_count"`
+	AlbumCount int64  `json:"album_count"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// ArtistDetailResponse lists the albums of an artist and a page of the tracks it is credited on
+// in Role. Roles counts the tracks of every role the artist is credited in.
+type ArtistDetailResponse struct {
+	ArtistResponse
+	Roles  map[string]int64              `json:"roles"`
+	Albums []AlbumResponse               `json:"albums"`
+	Role   string                        `json:"role"`
+	Tracks []trackResponse.TrackResponse `json:"tracks"`
+}
+
+// AlbumArtistResponse is the album artist shown with an album
+type AlbumArtistResponse struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+type AlbumResponse struct {
+	ID          uint64              `json:"id"`
+	Title       string              `json:"title"`
+	Year        *int                `json:"year"`
+	Artist      AlbumArtistResponse `json:"artist"`
+	ArtworkURLs map[string]string   `json:"artwork_urls"`
+	TrackCount  int64               `json:"track_count"`
+	DurationMs  int64               `json:"duration_ms"`
+	IsLiked     bool                `json:"is_liked"`
+}
+
+// AlbumDetailResponse is an album with a page of its tracks
+type AlbumDetailResponse struct {
+	AlbumResponse
+	Tracks []trackResponse.TrackResponse `json:"tracks"`
+}
+
+func FromArtistSchema(artist schema.Artist, stats repository.Stats) ArtistResponse {
+	return ArtistResponse{
+		ID:         artist.ID,
+		Name:       artist.Name,
+		TrackCount: stats.TrackCount,
+		AlbumCount: stats.AlbumCount,
+		DurationMs: stats.DurationMs,
+	}
+}
+
+func FromArtistListSchema(artists []schema.Artist, stats map[uint64]repository.Stats) []ArtistResponse {
+	res := make([]ArtistResponse, 0, len(artists))
+	for _, a := range artists {
+		res = append(res, FromArtistSchema(a, stats[a.ID]))
+	}
+
+	return res
+}
+
+func FromAlbumSchema(album schema.Album, stats repository.Stats, artworks []schema.TrackArtwork, storage trackResponse.URLResolver) AlbumResponse {
+	return AlbumResponse{
+		ID:    album.ID,
+		Title: album.Title,
+		Year:  album.Year,
+		Artist: AlbumArtistResponse{
+			ID:   album.Artist.ID,
+			Name: album.Artist.Name,
+		},
+		ArtworkURLs: artworkURLs(artworks, storage),
+		TrackCount:  stats.TrackCount,
+		DurationMs:  stats.DurationMs,
+	}
+}
+
+func FromAlbumListSchema(albums []schema.Album, stats map[uint64]repository.Stats, artworks map[uint64][]schema.TrackArtwork, storage trackResponse.URLResolver) []AlbumResponse {
+	res := make([]AlbumResponse, 0, len(albums))
+	for _, a := range albums {
+		res = append(res, FromAlbumSchema(a, stats[a.ID], artworks[a.ID], storage))
+	}
+
+	return res
+}
+
+// artworkURLs maps every artwork size (in px) to its public URL
+func artworkURLs(artworks []schema.TrackArtwork, storage trackResponse.URLResolver) map[string]string {
+	if len(artworks) == 0 {
+		return nil
+	}
+
+	urls := make(map[string]string, len(artworks))
+	for _, a := range artworks {
+		urls[strconv.Itoa(a.Size)] = storage.GetURL(a.StorageFilename)
+	}
+
+	return urls
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+)
+
+var (
+	// "A feat. B", "A ft B", "A featuring B", optionally inside parentheses
+	featPattern = regexp.MustCompile(`(?i)\s*[(\[]?\s*\b(?:feat\.?|ft\.?|featuring)\s+`)
+	// "(feat. B)" or "[ft. B]" in a title
+	titleFeatPattern = regexp.MustCompile(`(?i)[(\[]\s*(?:feat\.?|ft\.?|featuring)\s+([^)\]]+)[)\]]`)
+	// "(B Remix)" or "[B Remix]" in a title
+	titleRemixPattern = regexp.MustCompile(`(?i)[(\[]\s*([^()\[\]]+?)\s+remix\s*[)\]]`)
+	// separator between several artist names. Commas and slashes are kept, they are part of
+	// names such as "Tyler, The Creator" and "AC/DC".
+	namesPattern = regexp.MustCompile(`\s*&\s*`)
+	// composer tags list names with any of these separators
+	composerPattern = regexp.MustCompile(`\s*(?:&|,|;|/)\s*`)
+
+	// words that describe a remix instead of naming its remixer, e.g. "(Extended Remix)"
+	remixWords = []string{"original", "extended", "radio", "club", "dub", "album", "official", "instrumental"}
+)
+
+// Credit names an artist credited on a track in one of the schema credit roles
+type Credit struct {
+	Artist string
+	Role   string
+}
+
+// ParseCredits derives the credits of a track from its artist, title and composer strings:
+// "A & B feat. C" credits A and B as primary and C as featured artist, "(feat. D)" and
+// "(E Remix)" in the title credit a featured artist and a remixer, and every composer name
+// becomes a composer credit. Names are deduplicated per role, without an artist the track
+// is credited to UnknownArtist.
+//
+// A string known reports as an existing artist is not split, so "Earth, Wind & Fire" stays one
+// artist once the library has it. known may be nil.
+func ParseCredits(artist string, title string, composer string, known func(name string) bool) []Credit {
+	if known == nil {
+		known = func(string) bool { return false }
+	}
+
+	var credits []Credit
+	add := func(role string, names ...string) {
+		for _, name := range names {
+			name = cleanName(name, "")
+			if name == "" || slices.ContainsFunc(credits, func(c Credit) bool {
+				return c.Role == role && strings.EqualFold(c.Artist, name)
+			}) {
+				continue
+			}
+			credits = append(credits, Credit{Artist: name, Role: role})
+		}
+	}
+
+	primary, featured := splitFeatured(artist, known)
+	add(schema.RolePrimary, primary...)
+	if len(credits) == 0 {
+		add(schema.RolePrimary, UnknownArtist)
+	}
+
+	add(schema.RoleFeatured, featured...)
+	for _, m := range titleFeatPattern.FindAllStringSubmatch(title, -1) {
+		add(schema.RoleFeatured, splitNames(m[1], namesPattern, known)...)
+	}
+
+	for _, m := range titleRemixPattern.FindAllStringSubmatch(title, -1) {
+		if !slices.Contains(remixWords, strings.ToLower(strings.TrimSpace(m[1]))) {
+			add(schema.RoleRemixer, splitNames(m[1], namesPattern, known)...)
+		}
+	}
+
+	add(schema.RoleComposer, splitNames(composer, composerPattern, known)...)
+
+	return SortCredits(credits)
+}
+
+// SortCredits orders credits by role, keeping the order of the credits of each role
+func SortCredits(credits []Credit) []Credit {
+	slices.SortStableFunc(credits, func(a, b Credit) int {
+		return slices.Index(schema.CreditRoles, a.Role) - slices.Index(schema.CreditRoles, b.Role)
+	})
+
+	return credits
+}
+
+// splitFeatured splits "A & B feat. C & D" into its primary and featured names
+func splitFeatured(artist string, known func(string) bool) (primary []string, featured []string) {
+	if known(cleanName(artist, "")) {
+		return []string{artist}, nil
+	}
+
+	parts := featPattern.Split(artist, 2)
+	primary = splitNames(parts[0], namesPattern, known)
+	if len(parts) == 2 {
+		featured = splitNames(strings.Trim(parts[1], " )]"), namesPattern, known)
+	}
+
+	return primary, featured
+}
+
+// splitNames splits s at the separators matched by pattern unless s is a known artist
+func splitNames(s string, pattern *regexp.Regexp, known func(string) bool) []string {
+	name := cleanName(s, "")
+	if name == "" {
+		return nil
+	}
+
+	if known(name) {
+		return []string{name}
+	}
+
+	return pattern.Split(s, -1)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+)
+
+func TestParseCredits(t *testing.T) {
+	// knownArtists stands in for the artists of the library
+	knownArtists := []string{"Earth, Wind & Fire", "Simon & Garfunkel", "Tyler, The Creator"}
+	known := func(name string) bool { return slices.Contains(knownArtists, name) }
+
+	primary := func(name string) Credit { return Credit{Artist: name, Role: schema.RolePrimary} }
+	featured := func(name string) Credit { return Credit{Artist: name, Role: schema.RoleFeatured} }
+	remixer := func(name string) Credit { return Credit{Artist: name, Role: schema.RoleRemixer} }
+	composer := func(name string) Credit { return Credit{Artist: name, Role: schema.RoleComposer} }
+
+	tests := []struct {
+		name     string
+		artist   string
+		title    string
+		composer string
+		known    func(string) bool
+		want     []Credit
+	}{
+		{
+			name:   "single artist",
+			artist: "  Daft   Punk ",
+			want:   []Credit{primary("Daft Punk")},
+		},
+		{
+			name: "no artist",
+			want: []Credit{primary(UnknownArtist)},
+		},
+		{
+			name:   "ampersand splits primary artists",
+			artist: "Calvin Harris & Dua Lipa",
+			want:   []Credit{primary("Calvin Harris"), primary("Dua Lipa")},
+		},
+		{
+			name:   "commas and slashes stay in names",
+			artist: "AC/DC, Live",
+			want:   []Credit{primary("AC/DC, Live")},
+		},
+		{
+			name:   "featured artists",
+			artist: "Drake ft. Rihanna & Future",
+			want:   []Credit{primary("Drake"), featured("Rihanna"), featured("Future")},
+		},
+		{
+			name:   "featuring in parentheses",
+			artist: "Gorillaz (featuring De La Soul)",
+			want:   []Credit{primary("Gorillaz"), featured("De La Soul")},
+		},
+		{
+			name:   "featured and remixer in the title",
+			artist: "Avicii",
+			title:  "Levels (feat. Etta James) [Skrillex Remix]",
+			want:   []Credit{primary("Avicii"), featured("Etta James"), remixer("Skrillex")},
+		},
+		{
+			name:   "remix words are not remixers",
+			artist: "Avicii",
+			title:  "Levels (Extended Remix)",
+			want:   []Credit{primary("Avicii")},
+		},
+		{
+			name:     "composers split on any separator",
+			artist:   "Orchestra",
+			composer: "Bach; Handel / Vivaldi, Bach",
+			want:     []Credit{primary("Orchestra"), composer("Bach"), composer("Handel"), composer("Vivaldi")},
+		},
+		{
+			name:   "duplicates per role",
+			artist: "A & a feat. A",
+			title:  "Song (feat. A)",
+			want:   []Credit{primary("A"), featured("A")},
+		},
+		{
+			name:   "known artist is not split",
+			artist: "Earth, Wind & Fire",
+			known:  known,
+			want:   []Credit{primary("Earth, Wind & Fire")},
+		},
+		{
+			name:   "unknown artist is split",
+			artist: "Earth, Wind & Fire",
+			want:   []Credit{primary("Earth, Wind"), primary("Fire")},
+		},
+		{
+			name:   "known names on both sides of feat.",
+			artist: "Simon & Garfunkel feat. Tyler, The Creator & X",
+			known:  known,
+			want:   []Credit{primary("Simon & Garfunkel"), featured("Tyler, The Creator"), featured("X")},
+		},
+		{
+			name:     "known composer is not split",
+			artist:   "Band",
+			composer: "Simon & Garfunkel",
+			known:    known,
+			want:     []Credit{primary("Band"), composer("Simon & Garfunkel")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseCredits(tt.artist, tt.title, tt.composer, tt.known)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCredits(%q, %q, %q) = %v, want %v", tt.artist, tt.title, tt.composer, got, tt.want)
+			}
+		})
+	}
+}
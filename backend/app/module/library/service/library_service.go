@@ -0,0 +1,470 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library/response"
+	likeRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/like/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/helpers"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	trackRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+	trackResponse "git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+const (
+	// UnknownArtist is the artist of tracks uploaded without one, as in the tracks table default
+	UnknownArtist = "Unknown Artist"
+	// VariousArtists is the album artist of albums whose tracks have different artists
+	VariousArtists = "Various Artists"
+)
+
+type libraryService struct {
+	repo    repository.LibraryRepository
+	storage storage.Storage
+	likes   likeRepository.LikeRepository
+	tracks  trackRepository.TrackRepository
+}
+
+type LibraryService interface {
+	GetArtists(req request.ArtistPaginationRequest, p *paginator.Pagination) (artists []response.ArtistResponse, pagination *paginator.Pagination, err error)
+	GetArtistByID(id uint64, userID uint64, req request.ArtistTracksRequest, p *paginator.Pagination) (artist *response.ArtistDetailResponse, pagination *paginator.Pagination, err error)
+	GetAlbums(req request.AlbumPaginationRequest, userID uint64, p *paginator.Pagination) (albums []response.AlbumResponse, pagination *paginator.Pagination, err error)
+	GetAlbumByID(id uint64, userID uint64, p *paginator.Pagination) (album *response.AlbumDetailResponse, pagination *paginator.Pagination, err error)
+	GetAlbumsByIDs(ids []uint64, userID uint64) (albums []response.AlbumResponse, err error)
+	LikeAlbum(id uint64, userID uint64) (err error)
+	UnlikeAlbum(id uint64, userID uint64) (err error)
+	LinkTrack(track *schema.Track, albumArtist string, credits []Credit) (err error)
+	RelinkTrack(track *schema.Track, albumArtist string, credits []Credit) (err error)
+	MigrateTrack(track *schema.Track) (err error)
+	Prune(albumIDs []uint64, artistIDs []uint64)
+	WithTx(tx *gorm.DB) LibraryService
+}
+
+func NewLibraryService(repo repository.LibraryRepository, storage storage.Storage, likes likeRepository.LikeRepository, tracks trackRepository.TrackRepository) LibraryService {
+	return &libraryService{
+		repo:    repo,
+		storage: storage,
+		likes:   likes,
+		tracks:  tracks,
+	}
+}
+
+// WithTx returns the service linking tracks in the transaction tx, so the albums and artists it
+// creates are committed together with the tracks that use them
+func (s *libraryService) WithTx(tx *gorm.DB) LibraryService {
+	return s.withTx(tx)
+}
+
+func (s *libraryService) withTx(tx *gorm.DB) *libraryService {
+	return &libraryService{
+		repo:    s.repo.WithTx(tx),
+		storage: s.storage,
+		likes:   s.likes,
+		tracks:  s.tracks,
+	}
+}
+
+func (s *libraryService) GetArtists(req request.ArtistPaginationRequest, p *paginator.Pagination) ([]response.ArtistResponse, *paginator.Pagination, error) {
+	artists, p, err := s.repo.PaginateArtists(strings.TrimSpace(req.Search), p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	ids := make([]uint64, 0, len(artists))
+	for _, a := range artists {
+		ids = append(ids, a.ID)
+	}
+
+	stats, err := s.repo.ArtistStats(ids)
+	if err != nil {
+		return nil, p, err
+	}
+
+	return response.FromArtistListSchema(artists, stats), p, nil
+}
+
+func (s *libraryService) GetArtistByID(id uint64, userID uint64, req request.ArtistTracksRequest, p *paginator.Pagination) (*response.ArtistDetailResponse, *paginator.Pagination, error) {
+	role := cmp.Or(req.Role, schema.RolePrimary)
+	if !slices.Contains(schema.CreditRoles, role) {
+		return nil, p, &uresponse.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid role %q", req.Role),
+		}
+	}
+
+	artist, err := s.repo.FindArtistByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, p, &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Artist not found",
+		}
+	}
+	if err != nil {
+		return nil, p, err
+	}
+
+	stats, err := s.repo.ArtistStats([]uint64{id})
+	if err != nil {
+		return nil, p, err
+	}
+
+	albums, err := s.repo.ListArtistAlbums(id)
+	if err != nil {
+		return nil, p, err
+	}
+
+	albumRes, err := s.albumResponses(albums, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	roles, err := s.repo.ArtistRoles(id)
+	if err != nil {
+		return nil, p, err
+	}
+
+	tracks, p, err := s.repo.PaginateCreditedTracks(id, role, p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	trackRes, err := s.trackResponses(tracks, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	return &response.ArtistDetailResponse{
+		ArtistResponse: response.FromArtistSchema(*artist, stats[id]),
+		Roles:          roles,
+		Albums:         albumRes,
+		Role:           role,
+		Tracks:         trackRes,
+	}, p, nil
+}
+
+func (s *libraryService) GetAlbums(req request.AlbumPaginationRequest, userID uint64, p *paginator.Pagination) ([]response.AlbumResponse, *paginator.Pagination, error) {
+	albums, p, err := s.repo.PaginateAlbums(strings.TrimSpace(req.Search), req.ArtistID, p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	res, err := s.albumResponses(albums, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	return res, p, nil
+}
+
+func (s *libraryService) GetAlbumByID(id uint64, userID uint64, p *paginator.Pagination) (*response.AlbumDetailResponse, *paginator.Pagination, error) {
+	album, err := s.repo.FindAlbumByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, p, &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Album not found",
+		}
+	}
+	if err != nil {
+		return nil, p, err
+	}
+
+	res, err := s.albumResponses([]schema.Album{*album}, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	tracks, p, err := s.repo.PaginateTracks("album_id", id, p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	trackRes, err := s.trackResponses(tracks, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	return &response.AlbumDetailResponse{
+		AlbumResponse: res[0],
+		Tracks:        trackRes,
+	}, p, nil
+}
+
+// GetAlbumsByIDs returns the existing albums among ids in the order of ids
+func (s *libraryService) GetAlbumsByIDs(ids []uint64, userID uint64) ([]response.AlbumResponse, error) {
+	albums, err := s.repo.FindAlbumsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint64]schema.Album, len(albums))
+	for _, a := range albums {
+		byID[a.ID] = a
+	}
+
+	ordered := make([]schema.Album, 0, len(albums))
+	for _, id := range ids {
+		if a, ok := byID[id]; ok {
+			ordered = append(ordered, a)
+		}
+	}
+
+	return s.albumResponses(ordered, userID)
+}
+
+func (s *libraryService) LikeAlbum(id uint64, userID uint64) error {
+	if _, err := s.repo.FindAlbumByID(id); errors.Is(err, gorm.ErrRecordNotFound) {
+		return &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Album not found",
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return s.likes.CreateLike(userID, schema.LikeAlbum, id)
+}
+
+// UnlikeAlbum removes a like, albums that are not liked are left as they are
+func (s *libraryService) UnlikeAlbum(id uint64, userID uint64) error {
+	return s.likes.DeleteLike(userID, schema.LikeAlbum, id)
+}
+
+// trackResponses marks the listed tracks the user likes and adds their ratings
+func (s *libraryService) trackResponses(tracks []schema.Track, userID uint64) ([]trackResponse.TrackResponse, error) {
+	res := trackResponse.FromTrackListSchema(tracks, s.storage)
+	ids := trackResponse.TrackIDs(res)
+
+	likes, err := s.likes.LikeStats(userID, schema.LikeTrack, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := s.tracks.RatingStats(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range res {
+		like, rating := likes[res[i].ID], ratings[res[i].ID]
+		trackResponse.SetLike(&res[i], userID, like.Liked, like.Count)
+		res[i].Rating = trackResponse.FromRating(rating.Mine, rating.Average, rating.Count)
+	}
+
+	return res, nil
+}
+
+// albumResponses adds the counts, durations, artwork and likes of albums
+func (s *libraryService) albumResponses(albums []schema.Album, userID uint64) ([]response.AlbumResponse, error) {
+	ids := make([]uint64, 0, len(albums))
+	for _, a := range albums {
+		ids = append(ids, a.ID)
+	}
+
+	stats, err := s.repo.AlbumStats(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	artworks, err := s.repo.AlbumArtworks(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	likes, err := s.likes.LikeStats(userID, schema.LikeAlbum, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := response.FromAlbumListSchema(albums, stats, artworks, s.storage)
+	for i := range res {
+		res[i].IsLiked = likes[res[i].ID].Liked
+	}
+
+	return res, nil
+}
+
+// LinkTrack points a track at its artist, album and credits, creating the artists and album on
+// first use. Without credits they are parsed from the track with ParseCredits, credits without
+// a primary artist keep the parsed primary artists. The track is linked to its first primary
+// artist, which is also the default album artist. The year of the track fills in an album
+// without one. The track is not saved.
+func (s *libraryService) LinkTrack(track *schema.Track, albumArtist string, credits []Credit) error {
+	parsed := ParseCredits(track.Artist, track.Title, helpers.Value(track.Composer), s.knownArtist)
+	if credits == nil {
+		credits = parsed
+	} else if !slices.ContainsFunc(credits, isPrimary) {
+		credits = append(slices.DeleteFunc(parsed, func(c Credit) bool { return !isPrimary(c) }), credits...)
+	}
+
+	track.Credits = nil
+	for _, c := range SortCredits(slices.Clone(credits)) {
+		artist, err := s.repo.FirstOrCreateArtist(cleanName(c.Artist, UnknownArtist))
+		if err != nil {
+			return err
+		}
+
+		if slices.ContainsFunc(track.Credits, func(tc schema.TrackCredit) bool {
+			return tc.ArtistID == artist.ID && tc.Role == c.Role
+		}) {
+			continue
+		}
+
+		track.Credits = append(track.Credits, schema.TrackCredit{
+			TrackID:  track.ID,
+			ArtistID: artist.ID,
+			Role:     c.Role,
+			Position: len(track.Credits),
+			Artist:   *artist,
+		})
+	}
+
+	primary := track.Credits[0].Artist
+	track.ArtistID = &primary.ID
+	track.AlbumID = nil
+
+	title := ""
+	if track.Album != nil {
+		title = cleanName(*track.Album, "")
+	}
+	if title == "" {
+		return nil
+	}
+
+	owner := &primary
+	if name := cleanName(albumArtist, ""); name != "" && !strings.EqualFold(name, primary.Name) {
+		var err error
+		if owner, err = s.repo.FirstOrCreateArtist(name); err != nil {
+			return err
+		}
+	}
+
+	album, err := s.repo.FirstOrCreateAlbum(owner.ID, title, track.Year)
+	if err != nil {
+		return err
+	}
+
+	track.AlbumID = &album.ID
+	return nil
+}
+
+// RelinkTrack links a saved track again after its artist, album, title or credits changed, nil
+// credits are parsed again. Without an album artist the track stays with the album artist of its
+// current album when the title is unchanged.
+func (s *libraryService) RelinkTrack(track *schema.Track, albumArtist string, credits []Credit) error {
+	albumIDs, artistIDs := LinkedIDs(*track)
+
+	if albumArtist == "" && track.AlbumID != nil && track.Album != nil {
+		current, err := s.repo.FindAlbumByID(*track.AlbumID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if current != nil && strings.EqualFold(current.Title, cleanName(*track.Album, "")) {
+			albumArtist = current.Artist.Name
+		}
+	}
+
+	if err := s.linkSaved(track, albumArtist, credits); err != nil {
+		return err
+	}
+
+	s.Prune(albumIDs, artistIDs)
+	return nil
+}
+
+// MigrateTrack links a track stored before the library existed. Albums whose tracks of the same
+// owner have different artists are credited to VariousArtists.
+func (s *libraryService) MigrateTrack(track *schema.Track) error {
+	var albumArtist string
+	if track.Album != nil && *track.Album != "" {
+		count, err := s.repo.CountAlbumArtists(track.UserID, *track.Album)
+		if err != nil {
+			return err
+		}
+		if count > 1 {
+			albumArtist = VariousArtists
+		}
+	}
+
+	return s.linkSaved(track, albumArtist, nil)
+}
+
+// linkSaved links a saved track and stores its links in one transaction, so the albums and
+// artists it creates cannot be pruned before the track references them
+func (s *libraryService) linkSaved(track *schema.Track, albumArtist string, credits []Credit) error {
+	return s.repo.Transaction(func(tx *gorm.DB) error {
+		linker := s.withTx(tx)
+		if err := linker.LinkTrack(track, albumArtist, credits); err != nil {
+			return err
+		}
+
+		return linker.repo.LinkTrack(track)
+	})
+}
+
+// Prune removes the albums and artists among the ids that are no longer linked to any track,
+// callers pass the links a change removed, see LinkedIDs. Failures are logged.
+func (s *libraryService) Prune(albumIDs []uint64, artistIDs []uint64) {
+	if len(albumIDs) == 0 && len(artistIDs) == 0 {
+		return
+	}
+
+	if err := s.repo.DeleteUnused(albumIDs, artistIDs); err != nil {
+		log.Printf("[library] prune err=%v", err)
+	}
+}
+
+// LinkedIDs returns the albums and artists the tracks are linked to, including credited artists
+func LinkedIDs(tracks ...schema.Track) (albumIDs []uint64, artistIDs []uint64) {
+	for _, t := range tracks {
+		if t.AlbumID != nil && !slices.Contains(albumIDs, *t.AlbumID) {
+			albumIDs = append(albumIDs, *t.AlbumID)
+		}
+		if t.ArtistID != nil && !slices.Contains(artistIDs, *t.ArtistID) {
+			artistIDs = append(artistIDs, *t.ArtistID)
+		}
+		for _, c := range t.Credits {
+			if !slices.Contains(artistIDs, c.ArtistID) {
+				artistIDs = append(artistIDs, c.ArtistID)
+			}
+		}
+	}
+
+	return albumIDs, artistIDs
+}
+
+// cleanName trims a name and collapses its inner whitespace, an empty name becomes fallback
+func cleanName(name string, fallback string) string {
+	if name = strings.Join(strings.Fields(name), " "); name == "" {
+		return fallback
+	}
+
+	return name
+}
+
+// knownArtist reports whether the library has an artist named name. Lookup failures are logged
+// and treated as unknown, the name is then split as usual.
+func (s *libraryService) knownArtist(name string) bool {
+	found, err := s.repo.ArtistExists(name)
+	if err != nil {
+		log.Printf("[library] find artist %q err=%v", name, err)
+	}
+
+	return found
+}
+
+func isPrimary(c Credit) bool {
+	return c.Role == schema.RolePrimary
+}
@@ -0,0 +1,13 @@
+package controller
+
+import "git.dev.siap.id/kukuhkkh/app-music/app/module/like/service"
+
+type Controller struct {
+	Like LikeController
+}
+
+func NewController(likeService service.LikeService) *Controller {
+	return &Controller{
+		Like: NewLikeController(likeService),
+	}
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/service"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type likeController struct {
+	likeService service.LikeService
+}
+
+type LikeController interface {
+	GetLikes(c *fiber.Ctx) error
+}
+
+func NewLikeController(likeService service.LikeService) LikeController {
+	return &likeController{
+		likeService: likeService,
+	}
+}
+
+// GetLikes godoc
+// @Summary      Get liked items
+// @Description  Get the tracks, albums and playlists the current user likes by like date. Liked playlists that are no longer visible to the user are left out
+// @Tags         Likes
+// @Accept       json
+// @Produce      json
+// @Param        type  query string false "Only likes of this type: track, album or playlist"
+// @Param        sort  query string false "liked_at (oldest first) or -liked_at (newest first, default)"
+// @Param        page  query int    false "Page number"
+// @Param        limit query int    false "Items per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /me/likes [get]
+func (_i *likeController) GetLikes(c *fiber.Ctx) error {
+	p, _ := paginator.Paginate(c)
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.LikePaginationRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	likes, p, err := _i.likeService.GetLikes(claims.UserID, *req, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get likes success"},
+		Data:     likes,
+		Meta:     paginator.Paging(p),
+	})
+}
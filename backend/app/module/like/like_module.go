@@ -0,0 +1,48 @@
+package like
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/controller"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/service"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+)
+
+type LikeRouter struct {
+	App        fiber.Router
+	Controller *controller.Controller
+}
+
+var NewLikeModule = fx.Options(
+	// register repository of like module
+	fx.Provide(repository.NewLikeRepository),
+
+	// register service of like module
+	fx.Provide(service.NewLikeService),
+
+	// register controller of like module
+	fx.Provide(controller.NewController),
+
+	// register router of like module
+	fx.Provide(NewLikeRouter),
+)
+
+func NewLikeRouter(fiber *fiber.App, controller *controller.Controller) *LikeRouter {
+	return &LikeRouter{
+		App:        fiber,
+		Controller: controller,
+	}
+}
+
+// RegisterLikeRoutes registers the liked items listing, liking is done on the routes of the liked
+// tracks, albums and playlists
+func (_i *LikeRouter) RegisterLikeRoutes() {
+	// define controllers
+	likeController := _i.Controller.Like
+
+	// define routes
+	_i.App.Route("/me/likes", func(router fiber.Router) {
+		router.Get("", middleware.Protected(), likeController.GetLikes)
+	})
+}
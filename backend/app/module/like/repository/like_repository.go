@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stats tells whether the requesting user likes an item and how many users do
+type Stats struct {
+	TargetID uint64
+	Liked    bool
+	Count    int64
+}
+
+type likeRepository struct {
+	DB *database.Database
+}
+
+type LikeRepository interface {
+	CreateLike(userID uint64, targetType string, targetID uint64) (err error)
+	DeleteLike(userID uint64, targetType string, targetID uint64) (err error)
+	LikeStats(userID uint64, targetType string, ids []uint64) (stats map[uint64]Stats, err error)
+	PaginateLikes(userID uint64, targetType string, desc bool, p *paginator.Pagination) (likes []schema.Like, pagination *paginator.Pagination, err error)
+	DeleteTrackLikes(trackID uint64) (err error)
+	WithTx(tx *gorm.DB) LikeRepository
+}
+
+func NewLikeRepository(db *database.Database) LikeRepository {
+	return &likeRepository{
+		DB: db,
+	}
+}
+
+// WithTx returns the repository running its queries in the transaction tx
+func (_i *likeRepository) WithTx(tx *gorm.DB) LikeRepository {
+	db := *_i.DB
+	db.DB = tx
+
+	return &likeRepository{
+		DB: &db,
+	}
+}
+
+// CreateLike likes an item, liking it again keeps the first like
+func (_i *likeRepository) CreateLike(userID uint64, targetType string, targetID uint64) (err error) {
+	like := &schema.Like{UserID: userID, TargetType: targetType, TargetID: targetID}
+
+	return _i.DB.DB.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(like).Error
+}
+
+func (_i *likeRepository) DeleteLike(userID uint64, targetType string, targetID uint64) (err error) {
+	return _i.DB.DB.Unscoped().
+		Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+		Delete(&schema.Like{}).Error
+}
+
+// LikeStats counts the likes of items with one query, items without likes are left out
+func (_i *likeRepository) LikeStats(userID uint64, targetType string, ids []uint64) (stats map[uint64]Stats, err error) {
+	stats = make(map[uint64]Stats, len(ids))
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	var rows []Stats
+	err = _i.DB.DB.Model(&schema.Like{}).
+		Select("target_id, MAX(user_id = ?) AS liked, COUNT(*) AS count", userID).
+		Where("target_type = ? AND target_id IN ?", targetType, ids).
+		Group("target_id").
+		Scan(&rows).Error
+
+	for _, row := range rows {
+		stats[row.TargetID] = row
+	}
+
+	return stats, err
+}
+
+// PaginateLikes lists the likes of a user of one type, or of every type when targetType is
+// empty, by like date. Liked playlists the user can no longer view are left out, like
+// ListVisiblePlaylists of the playlist repository.
+func (_i *likeRepository) PaginateLikes(userID uint64, targetType string, desc bool, p *paginator.Pagination) (likes []schema.Like, pagination *paginator.Pagination, err error) {
+	member := _i.DB.DB.Model(&schema.PlaylistMember{}).Select("playlist_id").Where("user_id = ?", userID)
+	visible := _i.DB.DB.Model(&schema.Playlist{}).Select("id").
+		Where("(user_id = ? OR public = ? OR id IN (?))", userID, true, member)
+
+	query := _i.DB.DB.Model(&schema.Like{}).
+		Where("user_id = ?", userID).
+		Where("(target_type <> ? OR target_id IN (?))", schema.LikePlaylist, visible)
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	if err = query.Count(&p.Count).Error; err != nil {
+		return
+	}
+
+	order := "created_at, id"
+	if desc {
+		order = "created_at DESC, id DESC"
+	}
+
+	err = query.Offset(p.Offset).Limit(p.Limit).Order(order).Find(&likes).Error
+
+	return likes, p, err
+}
+
+// DeleteTrackLikes removes the likes of a deleted track and its CUE segments
+func (_i *likeRepository) DeleteTrackLikes(trackID uint64) (err error) {
+	segments := _i.DB.DB.Unscoped().Model(&schema.Track{}).Select("id").Where("parent_id = ?", trackID)
+
+	return _i.DB.DB.Unscoped().
+		Where("target_type = ? AND (target_id = ? OR target_id IN (?))", schema.LikeTrack, trackID, segments).
+		Delete(&schema.Like{}).Error
+}
@@ -0,0 +1,8 @@
+package request
+
+// LikePaginationRequest filters the likes of the current user, Sort is liked_at (oldest first) or
+// -liked_at (newest first, the default)
+type LikePaginationRequest struct {
+	Type string `query:"type" validate:"omitempty,oneof=track album playlist"`
+	Sort string `query:"sort" validate:"omitempty,oneof=liked_at -liked_at"`
+}
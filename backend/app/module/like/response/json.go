@@ -0,0 +1,16 @@
+package response
+
+import (
+	libraryResponse "git.dev.siap.id/kukuhkkh/app-music/app/module/library/response"
+	playlistResponse "git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/response"
+	trackResponse "git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+)
+
+// LikeResponse is a liked item, only the field of its type is set
+type LikeResponse struct {
+	Type     string                             `json:"type"`
+	LikedAt  string                             `json:"liked_at"`
+	Track    *trackResponse.TrackResponse       `json:"track,omitempty"`
+	Album    *libraryResponse.AlbumResponse     `json:"album,omitempty"`
+	Playlist *playlistResponse.PlaylistResponse `json:"playlist,omitempty"`
+}
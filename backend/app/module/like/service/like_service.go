@@ -0,0 +1,93 @@
+package service
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like/response"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+
+	libraryService "git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+	playlistService "git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/service"
+	trackService "git.dev.siap.id/kukuhkkh/app-music/app/module/track/service"
+)
+
+type likeService struct {
+	repo      repository.LikeRepository
+	tracks    trackService.TrackService
+	library   libraryService.LibraryService
+	playlists playlistService.PlaylistService
+}
+
+type LikeService interface {
+	GetLikes(userID uint64, req request.LikePaginationRequest, p *paginator.Pagination) (likes []response.LikeResponse, pagination *paginator.Pagination, err error)
+}
+
+func NewLikeService(repo repository.LikeRepository, tracks trackService.TrackService, library libraryService.LibraryService, playlists playlistService.PlaylistService) LikeService {
+	return &likeService{
+		repo:      repo,
+		tracks:    tracks,
+		library:   library,
+		playlists: playlists,
+	}
+}
+
+// GetLikes lists a page of the likes of a user by like date. The items of a page are loaded with
+// one batch per type, liked playlists the user can no longer view are left out by the query.
+func (s *likeService) GetLikes(userID uint64, req request.LikePaginationRequest, p *paginator.Pagination) ([]response.LikeResponse, *paginator.Pagination, error) {
+	likes, p, err := s.repo.PaginateLikes(userID, req.Type, req.Sort != "liked_at", p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	ids := make(map[string][]uint64, len(schema.LikeTypes))
+	for _, like := range likes {
+		ids[like.TargetType] = append(ids[like.TargetType], like.TargetID)
+	}
+
+	res := make([]response.LikeResponse, len(likes))
+	index := make(map[string]map[uint64]*response.LikeResponse, len(schema.LikeTypes))
+	for i, like := range likes {
+		res[i] = response.LikeResponse{
+			Type:    like.TargetType,
+			LikedAt: like.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if index[like.TargetType] == nil {
+			index[like.TargetType] = make(map[uint64]*response.LikeResponse)
+		}
+		index[like.TargetType][like.TargetID] = &res[i]
+	}
+
+	tracks, err := s.tracks.GetTracksByIDs(ids[schema.LikeTrack], userID)
+	if err != nil {
+		return nil, p, err
+	}
+	for i := range tracks {
+		index[schema.LikeTrack][tracks[i].ID].Track = &tracks[i]
+	}
+
+	albums, err := s.library.GetAlbumsByIDs(ids[schema.LikeAlbum], userID)
+	if err != nil {
+		return nil, p, err
+	}
+	for i := range albums {
+		index[schema.LikeAlbum][albums[i].ID].Album = &albums[i]
+	}
+
+	playlists, err := s.playlists.GetPlaylistsByIDs(ids[schema.LikePlaylist], userID)
+	if err != nil {
+		return nil, p, err
+	}
+	for i := range playlists {
+		index[schema.LikePlaylist][playlists[i].ID].Playlist = &playlists[i]
+	}
+
+	found := res[:0]
+	for _, like := range res {
+		if like.Track != nil || like.Album != nil || like.Playlist != nil {
+			found = append(found, like)
+		}
+	}
+
+	return found, p, nil
+}
@@ -0,0 +1,13 @@
+package controller
+
+import "git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/service"
+
+type Controller struct {
+	Playlist PlaylistController
+}
+
+func NewController(playlistService service.PlaylistService) *Controller {
+	return &Controller{
+		Playlist: NewPlaylistController(playlistService),
+	}
+}
@@ -0,0 +1,659 @@
+package controller
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/service"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var allowedCoverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type playlistController struct {
+	playlistService service.PlaylistService
+}
+
+type PlaylistController interface {
+	GetPlaylists(c *fiber.Ctx) error
+	GetPlaylistByID(c *fiber.Ctx) error
+	Create(c *fiber.Ctx) error
+	Update(c *fiber.Ctx) error
+	Delete(c *fiber.Ctx) error
+	UpdateCover(c *fiber.Ctx) error
+	DeleteCover(c *fiber.Ctx) error
+	AddEntries(c *fiber.Ctx) error
+	RemoveEntry(c *fiber.Ctx) error
+	MoveEntries(c *fiber.Ctx) error
+	Refresh(c *fiber.Ctx) error
+	GetMembers(c *fiber.Ctx) error
+	SaveMember(c *fiber.Ctx) error
+	RemoveMember(c *fiber.Ctx) error
+	GetActivities(c *fiber.Ctx) error
+	Like(c *fiber.Ctx) error
+	Unlike(c *fiber.Ctx) error
+}
+
+func NewPlaylistController(playlistService service.PlaylistService) PlaylistController {
+	return &playlistController{
+		playlistService: playlistService,
+	}
+}
+
+// GetPlaylists godoc
+// @Summary      Get paginated playlists
+// @Description  Get the playlists the user owns or is a member of with their track count, total duration, cover and the role of the user, the most recently changed first
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        search query string false "Search by name"
+// @Param        page   query int    false "Page number"
+// @Param        limit  query int    false "Items per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists [get]
+func (_i *playlistController) GetPlaylists(c *fiber.Ctx) error {
+	p, _ := paginator.Paginate(c)
+
+	req := new(request.PlaylistPaginationRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	playlists, p, err := _i.playlistService.GetPlaylists(claims.UserID, *req, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get playlists success"},
+		Data:     playlists,
+		Meta:     paginator.Paging(p),
+	})
+}
+
+// GetPlaylistByID godoc
+// @Summary      Get playlist by ID
+// @Description  Get an own, shared or public playlist with a page of its entries in order and who added them. Live smart playlists list the tracks currently matching their rules
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id    path  uint64 true  "Playlist ID"
+// @Param        page  query int    false "Page number"
+// @Param        limit query int    false "Entries per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id} [get]
+func (_i *playlistController) GetPlaylistByID(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	p, _ := paginator.Paginate(c)
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, p, err := _i.playlistService.GetPlaylistByID(uint64(id), claims.UserID, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get playlist success"},
+		Data:     res,
+		Meta:     paginator.Paging(p),
+	})
+}
+
+// Create godoc
+// @Summary      Create playlist
+// @Description  Create a playlist, optionally with tracks in the given order, or a smart playlist listing the tracks matching its rules
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        body body request.CreatePlaylistRequest true "Playlist"
+// @Success      201 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists [post]
+func (_i *playlistController) Create(c *fiber.Ctx) error {
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.CreatePlaylistRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.playlistService.CreatePlaylist(claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Create playlist success"},
+		Data:     res,
+		Code:     fiber.StatusCreated,
+	})
+}
+
+// Update godoc
+// @Summary      Update playlist
+// @Description  Rename a playlist and change its description, visibility and smart rules. An omitted description, public flag or smart rules are kept
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64                        true "Playlist ID"
+// @Param        body body request.UpdatePlaylistRequest true "Playlist"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id} [put]
+func (_i *playlistController) Update(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.UpdatePlaylistRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.playlistService.UpdatePlaylist(uint64(id), claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Update playlist success"},
+		Data:     res,
+	})
+}
+
+// Delete godoc
+// @Summary      Delete playlist
+// @Description  Delete a playlist with its entries and cover, the tracks are kept
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id path uint64 true "Playlist ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id} [delete]
+func (_i *playlistController) Delete(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.playlistService.DeletePlaylist(uint64(id), claims.UserID); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Delete playlist success"},
+	})
+}
+
+// UpdateCover godoc
+// @Summary      Replace playlist cover
+// @Description  Replace the cover of a playlist with an uploaded image, rendered in the track.artwork sizes
+// @Tags         Playlists
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id   path     uint64 true "Playlist ID"
+// @Param        file formData file   true "Cover image (JPEG, PNG or GIF)"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/cover [put]
+func (_i *playlistController) UpdateCover(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Missing file",
+		}
+	}
+
+	if !allowedCoverTypes[fileHeader.Header.Get("Content-Type")] {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Cover type not allowed. Only JPEG, PNG and GIF images are permitted.",
+		}
+	}
+
+	res, err := _i.playlistService.ReplaceCover(c.Context(), uint64(id), claims.UserID, fileHeader)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Update playlist cover success"},
+		Data:     res,
+	})
+}
+
+// DeleteCover godoc
+// @Summary      Remove playlist cover
+// @Description  Remove the cover of a playlist
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id path uint64 true "Playlist ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/cover [delete]
+func (_i *playlistController) DeleteCover(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.playlistService.DeleteCover(uint64(id), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Delete playlist cover success"},
+		Data:     res,
+	})
+}
+
+// AddEntries godoc
+// @Summary      Add tracks to playlist
+// @Description  Place tracks in a playlist at a position, or at the end. A track may be placed more than once. The owner and editors can add tracks. The version of the playlist last read is required, the change is rejected with 409 when the playlist changed since. Returns the order and new version of the playlist
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64                    true "Playlist ID"
+// @Param        body body request.AddEntriesRequest true "Tracks"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/entries [post]
+func (_i *playlistController) AddEntries(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.AddEntriesRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.playlistService.AddEntries(uint64(id), claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Add playlist entries success"},
+		Data:     res,
+	})
+}
+
+// RemoveEntry godoc
+// @Summary      Remove entry from playlist
+// @Description  Remove one entry of a playlist, other entries of the same track are kept. The owner and editors can remove entries. The version of the playlist last read is required, the change is rejected with 409 when the playlist changed since. Returns the order and new version of the playlist
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id      path  uint64 true  "Playlist ID"
+// @Param        entryId path  uint64 true  "Entry ID"
+// @Param        version query uint64 true  "Version of the playlist the change is based on"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/entries/{entryId} [delete]
+func (_i *playlistController) RemoveEntry(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	entryID, err := c.ParamsInt("entryId")
+	if err != nil {
+		return err
+	}
+
+	req := new(request.VersionRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.playlistService.RemoveEntry(uint64(id), claims.UserID, uint64(entryID), req.Version)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Remove playlist entry success"},
+		Data:     res,
+	})
+}
+
+// MoveEntries godoc
+// @Summary      Reorder playlist
+// @Description  Move entries of a playlist, one move after the other. The owner and editors can reorder. The version of the playlist last read is required, the change is rejected with 409 when the playlist changed since. Returns the order and new version of the playlist
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64                     true "Playlist ID"
+// @Param        body body request.MoveEntriesRequest true "Moves"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/entries/move [post]
+func (_i *playlistController) MoveEntries(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.MoveEntriesRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.playlistService.MoveEntries(uint64(id), claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Move playlist entries success"},
+		Data:     res,
+	})
+}
+
+// Refresh godoc
+// @Summary      Refresh smart playlist
+// @Description  Store the tracks currently matching the rules of a materialized smart playlist, live smart playlists are always current. The owner and editors can refresh
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id path uint64 true "Playlist ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/refresh [post]
+func (_i *playlistController) Refresh(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.playlistService.RefreshPlaylist(uint64(id), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Refresh playlist success"},
+		Data:     res,
+	})
+}
+
+// Like godoc
+// @Summary      Like playlist
+// @Description  Add an own, shared or public playlist to the likes of the current user, liking it again changes nothing
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id path uint64 true "Playlist ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/like [post]
+func (_i *playlistController) Like(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.playlistService.LikePlaylist(uint64(id), claims.UserID); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Like playlist success"},
+	})
+}
+
+// Unlike godoc
+// @Summary      Unlike playlist
+// @Description  Remove a playlist from the likes of the current user
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id path uint64 true "Playlist ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/like [delete]
+func (_i *playlistController) Unlike(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.playlistService.UnlikePlaylist(uint64(id), claims.UserID); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Unlike playlist success"},
+	})
+}
+
+// GetMembers godoc
+// @Summary      Get playlist members
+// @Description  Get the owner and the members of an own, shared or public playlist with their roles
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id path uint64 true "Playlist ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/members [get]
+func (_i *playlistController) GetMembers(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.playlistService.GetMembers(uint64(id), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get playlist members success"},
+		Data:     res,
+	})
+}
+
+// SaveMember godoc
+// @Summary      Invite playlist member
+// @Description  Invite a user to a playlist as editor or viewer, or change the role of a member. Editors change the entries, viewers only view. Only the owner can manage members
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id     path uint64                true "Playlist ID"
+// @Param        userId path uint64                true "User ID"
+// @Param        body   body request.MemberRequest true "Role"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/members/{userId} [put]
+func (_i *playlistController) SaveMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	memberID, err := c.ParamsInt("userId")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.MemberRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.playlistService.SaveMember(uint64(id), claims.UserID, uint64(memberID), *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Save playlist member success"},
+		Data:     res,
+	})
+}
+
+// RemoveMember godoc
+// @Summary      Remove playlist member
+// @Description  Remove a member from a playlist. The owner removes any member, a member can remove themselves to leave
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id     path uint64 true "Playlist ID"
+// @Param        userId path uint64 true "User ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/members/{userId} [delete]
+func (_i *playlistController) RemoveMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	memberID, err := c.ParamsInt("userId")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.playlistService.RemoveMember(uint64(id), claims.UserID, uint64(memberID)); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Remove playlist member success"},
+	})
+}
+
+// GetActivities godoc
+// @Summary      Get playlist activity
+// @Description  Get the changes of a playlist with who made them, the latest first. Only the owner and members see the activity
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id    path  uint64 true  "Playlist ID"
+// @Param        page  query int    false "Page number"
+// @Param        limit query int    false "Items per page"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /playlists/{id}/activity [get]
+func (_i *playlistController) GetActivities(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	p, _ := paginator.Paginate(c)
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, p, err := _i.playlistService.GetActivities(uint64(id), claims.UserID, p)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get playlist activity success"},
+		Data:     res,
+		Meta:     paginator.Paging(p),
+	})
+}
@@ -0,0 +1,80 @@
+package playlist
+
+import (
+	"context"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/controller"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/service"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+)
+
+type PlaylistRouter struct {
+	App        fiber.Router
+	Controller *controller.Controller
+}
+
+var NewPlaylistModule = fx.Options(
+	// register repository of playlist module
+	fx.Provide(repository.NewPlaylistRepository),
+
+	// register service of playlist module
+	fx.Provide(service.NewPlaylistService),
+
+	// register smart playlist refresh
+	fx.Provide(service.NewRefresher),
+
+	// register controller of playlist module
+	fx.Provide(controller.NewController),
+
+	// register router of playlist module
+	fx.Provide(NewPlaylistRouter),
+)
+
+// StartRefresher refreshes the materialized smart playlists on a schedule while the app runs.
+// Invoke it after the webserver so the database is connected before the first refresh.
+func StartRefresher(lifecycle fx.Lifecycle, refresher *service.Refresher) {
+	lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			return refresher.Start()
+		},
+		OnStop: func(context.Context) error {
+			return refresher.Stop()
+		},
+	})
+}
+
+func NewPlaylistRouter(fiber *fiber.App, controller *controller.Controller) *PlaylistRouter {
+	return &PlaylistRouter{
+		App:        fiber,
+		Controller: controller,
+	}
+}
+
+func (_i *PlaylistRouter) RegisterPlaylistRoutes() {
+	// define controllers
+	playlistController := _i.Controller.Playlist
+
+	// define routes
+	_i.App.Route("/playlists", func(router fiber.Router) {
+		router.Get("", middleware.Protected(), playlistController.GetPlaylists)
+		router.Get("/:id", middleware.Protected(), playlistController.GetPlaylistByID)
+		router.Post("", middleware.Protected(), playlistController.Create)
+		router.Put("/:id", middleware.Protected(), playlistController.Update)
+		router.Delete("/:id", middleware.Protected(), playlistController.Delete)
+		router.Put("/:id/cover", middleware.Protected(), playlistController.UpdateCover)
+		router.Delete("/:id/cover", middleware.Protected(), playlistController.DeleteCover)
+		router.Post("/:id/refresh", middleware.Protected(), playlistController.Refresh)
+		router.Post("/:id/like", middleware.Protected(), playlistController.Like)
+		router.Delete("/:id/like", middleware.Protected(), playlistController.Unlike)
+		router.Get("/:id/members", middleware.Protected(), playlistController.GetMembers)
+		router.Put("/:id/members/:userId", middleware.Protected(), playlistController.SaveMember)
+		router.Delete("/:id/members/:userId", middleware.Protected(), playlistController.RemoveMember)
+		router.Get("/:id/activity", middleware.Protected(), playlistController.GetActivities)
+		router.Post("/:id/entries", middleware.Protected(), playlistController.AddEntries)
+		router.Post("/:id/entries/move", middleware.Protected(), playlistController.MoveEntries)
+		router.Delete("/:id/entries/:entryId", middleware.Protected(), playlistController.RemoveEntry)
+	})
+}
@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	trackRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+)
+
+// positionBatch is the number of entries renumbered by one UPDATE
+const positionBatch = 500
+
+// Stats sums the tracks of a playlist, a track placed twice counts twice
+type Stats struct {
+	ID         uint64
+	TrackCount int64
+	DurationMs int64
+}
+
+// ErrVersionConflict is returned by UpdateEntries when the entries changed since the version the
+// caller read
+var ErrVersionConflict = errors.New("playlist version conflict")
+
+// EditEntries receives the entries of a playlist in order and returns them in their new order.
+// Entries without an ID are created, entries left out are deleted.
+type EditEntries func(entries []schema.PlaylistEntry) ([]schema.PlaylistEntry, error)
+
+type playlistRepository struct {
+	DB *database.Database
+}
+
+type PlaylistRepository interface {
+	PaginatePlaylists(userID uint64, search string, p *paginator.Pagination) (playlists []schema.Playlist, pagination *paginator.Pagination, err error)
+	FindPlaylistByID(id uint64) (playlist *schema.Playlist, err error)
+	ListVisiblePlaylists(userID uint64, ids []uint64) (playlists []schema.Playlist, err error)
+	PlaylistStats(ids []uint64) (stats map[uint64]Stats, err error)
+	CreatePlaylist(playlist *schema.Playlist) (err error)
+	UpdatePlaylist(playlist *schema.Playlist) (err error)
+	DeletePlaylist(id uint64) (err error)
+	ReplaceCovers(playlistID uint64, covers []schema.PlaylistCover) (old []schema.PlaylistCover, err error)
+	PaginateEntries(playlistID uint64, p *paginator.Pagination) (entries []schema.PlaylistEntry, pagination *paginator.Pagination, err error)
+	ListTracks(ids []uint64) (tracks []schema.Track, err error)
+	CountTracks(ids []uint64) (count int64, err error)
+	UpdateEntries(playlistID uint64, version *uint64, edit EditEntries) (entries []schema.PlaylistEntry, newVersion uint64, err error)
+	DeleteTrackEntries(trackID uint64) (playlistIDs []uint64, err error)
+	ListMaterializedPlaylists() (playlists []schema.Playlist, err error)
+	SetRefreshedAt(id uint64, refreshedAt time.Time) (err error)
+	UserExists(id uint64) (exists bool, err error)
+	SaveMember(member *schema.PlaylistMember) (err error)
+	DeleteMember(playlistID uint64, userID uint64) (err error)
+	CreateActivity(activity *schema.PlaylistActivity) (err error)
+	PaginateActivities(playlistID uint64, p *paginator.Pagination) (activities []schema.PlaylistActivity, pagination *paginator.Pagination, err error)
+	WithTx(tx *gorm.DB) PlaylistRepository
+}
+
+func NewPlaylistRepository(db *database.Database) PlaylistRepository {
+	return &playlistRepository{
+		DB: db,
+	}
+}
+
+// WithTx returns the repository running its queries in the transaction tx
+func (_i *playlistRepository) WithTx(tx *gorm.DB) PlaylistRepository {
+	db := *_i.DB
+	db.DB = tx
+
+	return &playlistRepository{
+		DB: &db,
+	}
+}
+
+// PaginatePlaylists lists the playlists a user owns or is a member of, with the membership of the user
+func (_i *playlistRepository) PaginatePlaylists(userID uint64, search string, p *paginator.Pagination) (playlists []schema.Playlist, pagination *paginator.Pagination, err error) {
+	member := _i.DB.DB.Model(&schema.PlaylistMember{}).Select("playlist_id").Where("user_id = ?", userID)
+	query := _i.DB.DB.Model(&schema.Playlist{}).Where("(user_id = ? OR id IN (?))", userID, member)
+	if search != "" {
+		query = query.Where("name LIKE ?", "%"+search+"%")
+	}
+
+	if err = query.Count(&p.Count).Error; err != nil {
+		return
+	}
+
+	err = query.Preload("Covers").Preload("Members", "user_id = ?", userID).Offset(p.Offset).Limit(p.Limit).Order("updated_at DESC, id DESC").Find(&playlists).Error
+
+	return playlists, p, err
+}
+
+// ListVisiblePlaylists returns the playlists among ids the user owns, is a member of or that are
+// public, with the membership of the user, in no particular order
+func (_i *playlistRepository) ListVisiblePlaylists(userID uint64, ids []uint64) (playlists []schema.Playlist, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	member := _i.DB.DB.Model(&schema.PlaylistMember{}).Select("playlist_id").Where("user_id = ?", userID)
+	err = _i.DB.DB.Preload("Covers").Preload("Members", "user_id = ?", userID).
+		Where("id IN ?", ids).
+		Where("(user_id = ? OR public = ? OR id IN (?))", userID, true, member).
+		Find(&playlists).Error
+
+	return
+}
+
+// FindPlaylistByID returns a playlist with its owner, cover and members
+func (_i *playlistRepository) FindPlaylistByID(id uint64) (playlist *schema.Playlist, err error) {
+	if err := _i.DB.DB.Preload("User").Preload("Covers").
+		Preload("Members", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Members.User").
+		First(&playlist, id).Error; err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// PlaylistStats counts the entries of playlists and sums the duration of their tracks
+func (_i *playlistRepository) PlaylistStats(ids []uint64) (stats map[uint64]Stats, err error) {
+	stats = make(map[uint64]Stats, len(ids))
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	var rows []Stats
+	err = _i.DB.DB.Model(&schema.PlaylistEntry{}).
+		Select("playlist_entries.playlist_id AS id, COUNT(*) AS track_count, "+
+			"COALESCE(SUM(COALESCE(tracks.duration_ms, tracks.duration * 1000)), 0) AS duration_ms").
+		Joins("JOIN tracks ON tracks.id = playlist_entries.track_id AND tracks.deleted_at IS NULL").
+		Where("playlist_entries.playlist_id IN ?", ids).
+		Group("playlist_entries.playlist_id").
+		Scan(&rows).Error
+
+	for _, row := range rows {
+		stats[row.ID] = row
+	}
+
+	return stats, err
+}
+
+func (_i *playlistRepository) CreatePlaylist(playlist *schema.Playlist) (err error) {
+	return _i.DB.DB.Omit(clause.Associations).Create(playlist).Error
+}
+
+// UpdatePlaylist saves the name, description, visibility and smart rules of a playlist
+func (_i *playlistRepository) UpdatePlaylist(playlist *schema.Playlist) (err error) {
+	return _i.DB.DB.Model(playlist).
+		Select("name", "description", "public", "rules", "rule_limit", "rule_sort", "materialized").
+		Updates(playlist).Error
+}
+
+// DeletePlaylist deletes a playlist with its entries, cover rows, members, activity and likes, the
+// cover files are left to the caller
+func (_i *playlistRepository) DeletePlaylist(id uint64) (err error) {
+	return _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&schema.PlaylistEntry{}, &schema.PlaylistCover{}, &schema.PlaylistMember{}, &schema.PlaylistActivity{}} {
+			if err := tx.Unscoped().Where("playlist_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Where("target_type = ? AND target_id = ?", schema.LikePlaylist, id).Delete(&schema.Like{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&schema.Playlist{}, id).Error
+	})
+}
+
+// ReplaceCovers swaps every cover row of a playlist and returns the rows that were removed
+func (_i *playlistRepository) ReplaceCovers(playlistID uint64, covers []schema.PlaylistCover) (old []schema.PlaylistCover, err error) {
+	err = _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("playlist_id = ?", playlistID).Find(&old).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("playlist_id = ?", playlistID).Delete(&schema.PlaylistCover{}).Error; err != nil {
+			return err
+		}
+
+		if len(covers) == 0 {
+			return nil
+		}
+
+		for i := range covers {
+			covers[i].PlaylistID = playlistID
+		}
+
+		return tx.Create(&covers).Error
+	})
+
+	return old, err
+}
+
+// PaginateEntries lists the entries of a playlist in order, their tracks are loaded with ListTracks
+func (_i *playlistRepository) PaginateEntries(playlistID uint64, p *paginator.Pagination) (entries []schema.PlaylistEntry, pagination *paginator.Pagination, err error) {
+	query := _i.DB.DB.Model(&schema.PlaylistEntry{}).Where("playlist_id = ?", playlistID)
+
+	if err = query.Count(&p.Count).Error; err != nil {
+		return
+	}
+
+	err = query.Preload("AddedBy").Offset(p.Offset).Limit(p.Limit).Order("position ASC, id ASC").Find(&entries).Error
+
+	return entries, p, err
+}
+
+// ListTracks returns tracks with what a track response shows
+func (_i *playlistRepository) ListTracks(ids []uint64) (tracks []schema.Track, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	err = _i.DB.DB.Scopes(trackRepository.PreloadTrack).Where("id IN ?", ids).Find(&tracks).Error
+
+	return
+}
+
+// CountTracks counts the existing tracks among ids
+func (_i *playlistRepository) CountTracks(ids []uint64) (count int64, err error) {
+	err = _i.DB.DB.Model(&schema.Track{}).Where("id IN ?", ids).Count(&count).Error
+
+	return
+}
+
+// UpdateEntries changes the entries of a playlist through edit, renumbers them and returns the new
+// version of the playlist. The playlist row is locked, so concurrent edits of one playlist apply
+// one after the other. With a version the edit fails with ErrVersionConflict when the entries
+// changed since then.
+func (_i *playlistRepository) UpdateEntries(playlistID uint64, version *uint64, edit EditEntries) (entries []schema.PlaylistEntry, newVersion uint64, err error) {
+	err = _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		var playlist schema.Playlist
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "version").First(&playlist, playlistID).Error; err != nil {
+			return err
+		}
+
+		if version != nil && *version != playlist.Version {
+			return ErrVersionConflict
+		}
+
+		var current []schema.PlaylistEntry
+		if err := tx.Where("playlist_id = ?", playlistID).Order("position ASC, id ASC").Find(&current).Error; err != nil {
+			return err
+		}
+
+		// edit may reorder its slice in place, current is kept to find the removed entries
+		next, err := edit(slices.Clone(current))
+		if err != nil {
+			return err
+		}
+
+		kept := make(map[uint64]bool, len(next))
+		var created []*schema.PlaylistEntry
+		for i := range next {
+			if next[i].ID == 0 {
+				next[i].PlaylistID = playlistID
+				next[i].Position = i
+				created = append(created, &next[i])
+				continue
+			}
+			kept[next[i].ID] = true
+		}
+
+		var removed []uint64
+		for _, entry := range current {
+			if !kept[entry.ID] {
+				removed = append(removed, entry.ID)
+			}
+		}
+
+		if len(removed) > 0 {
+			if err := tx.Unscoped().Where("id IN ?", removed).Delete(&schema.PlaylistEntry{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := setPositions(tx, next); err != nil {
+			return err
+		}
+
+		if len(created) > 0 {
+			if err := tx.Omit(clause.Associations).Create(created).Error; err != nil {
+				return err
+			}
+		}
+
+		entries, newVersion = next, playlist.Version+1
+		return tx.Model(&schema.Playlist{}).Where("id = ?", playlistID).UpdateColumns(map[string]any{
+			"version":    newVersion,
+			"updated_at": time.Now(),
+		}).Error
+	})
+
+	return entries, newVersion, err
+}
+
+// DeleteTrackEntries removes a track and its CUE segments from every playlist, playlistIDs are the
+// playlists that lost entries
+func (_i *playlistRepository) DeleteTrackEntries(trackID uint64) (playlistIDs []uint64, err error) {
+	err = _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		segments := tx.Unscoped().Model(&schema.Track{}).Select("id").Where("parent_id = ?", trackID)
+
+		if err := tx.Model(&schema.PlaylistEntry{}).Distinct().
+			Where("track_id = ? OR track_id IN (?)", trackID, segments).
+			Pluck("playlist_id", &playlistIDs).Error; err != nil {
+			return err
+		}
+
+		if len(playlistIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Unscoped().
+			Where("track_id = ? OR track_id IN (?)", trackID, segments).
+			Delete(&schema.PlaylistEntry{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&schema.Playlist{}).Where("id IN ?", playlistIDs).
+			UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+			return err
+		}
+
+		// close the gaps left in each playlist
+		for _, playlistID := range playlistIDs {
+			var entries []schema.PlaylistEntry
+			if err := tx.Where("playlist_id = ?", playlistID).Order("position ASC, id ASC").Find(&entries).Error; err != nil {
+				return err
+			}
+
+			if err := setPositions(tx, entries); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return playlistIDs, err
+}
+
+// ListMaterializedPlaylists returns the smart playlists whose tracks are stored as entries
+func (_i *playlistRepository) ListMaterializedPlaylists() (playlists []schema.Playlist, err error) {
+	err = _i.DB.DB.Where("materialized = ? AND rules IS NOT NULL", true).Order("id").Find(&playlists).Error
+
+	return
+}
+
+func (_i *playlistRepository) SetRefreshedAt(id uint64, refreshedAt time.Time) (err error) {
+	return _i.DB.DB.Model(&schema.Playlist{}).Where("id = ?", id).UpdateColumn("refreshed_at", refreshedAt).Error
+}
+
+func (_i *playlistRepository) UserExists(id uint64) (exists bool, err error) {
+	var count int64
+	err = _i.DB.DB.Model(&schema.User{}).Where("id = ?", id).Count(&count).Error
+
+	return count > 0, err
+}
+
+// SaveMember adds a member to a playlist or changes the role of an existing one
+func (_i *playlistRepository) SaveMember(member *schema.PlaylistMember) (err error) {
+	return _i.DB.DB.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "playlist_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+}
+
+func (_i *playlistRepository) DeleteMember(playlistID uint64, userID uint64) (err error) {
+	return _i.DB.DB.Unscoped().Where("playlist_id = ? AND user_id = ?", playlistID, userID).Delete(&schema.PlaylistMember{}).Error
+}
+
+func (_i *playlistRepository) CreateActivity(activity *schema.PlaylistActivity) (err error) {
+	return _i.DB.DB.Omit(clause.Associations).Create(activity).Error
+}
+
+// PaginateActivities lists the activity of a playlist, the latest first
+func (_i *playlistRepository) PaginateActivities(playlistID uint64, p *paginator.Pagination) (activities []schema.PlaylistActivity, pagination *paginator.Pagination, err error) {
+	query := _i.DB.DB.Model(&schema.PlaylistActivity{}).Where("playlist_id = ?", playlistID)
+
+	if err = query.Count(&p.Count).Error; err != nil {
+		return
+	}
+
+	err = query.Preload("User").Offset(p.Offset).Limit(p.Limit).Order("id DESC").Find(&activities).Error
+
+	return activities, p, err
+}
+
+// setPositions stores the index of each saved entry as its position, skipping the unchanged ones
+func setPositions(tx *gorm.DB, entries []schema.PlaylistEntry) error {
+	var ids []uint64
+	var vars []any
+	flush := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+
+		expr := "CASE id " + strings.Repeat("WHEN ? THEN ? ", len(ids)) + "ELSE position END"
+		err := tx.Model(&schema.PlaylistEntry{}).Where("id IN ?", ids).Update("position", gorm.Expr(expr, vars...)).Error
+		ids, vars = nil, nil
+
+		return err
+	}
+
+	for i := range entries {
+		if entries[i].ID == 0 || entries[i].Position == i {
+			continue
+		}
+
+		entries[i].Position = i
+		ids = append(ids, entries[i].ID)
+		vars = append(vars, entries[i].ID, i)
+
+		if len(ids) == positionBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
@@ -0,0 +1,71 @@
+package request
+
+import "git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+
+type PlaylistPaginationRequest struct {
+	Search string `query:"search"`
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+}
+
+// CreatePlaylistRequest creates a playlist, optionally filled with tracks in the given order or
+// a smart playlist filled by rules
+type CreatePlaylistRequest struct {
+	Name        string        `json:"name" validate:"required,max=255" example:"Wedding set"`
+	Description *string       `json:"description" validate:"omitempty,max=5000"`
+	Public      bool          `json:"public"`
+	TrackIDs    []uint64      `json:"track_ids" validate:"omitempty,max=500"`
+	Smart       *SmartRequest `json:"smart"`
+}
+
+// UpdatePlaylistRequest renames a playlist. An omitted description or public flag is left
+// unchanged, an empty description clears it. Smart replaces the rules of a smart playlist.
+type UpdatePlaylistRequest struct {
+	Name        string        `json:"name" validate:"required,max=255"`
+	Description *string       `json:"description" validate:"omitempty,max=5000"`
+	Public      *bool         `json:"public"`
+	Smart       *SmartRequest `json:"smart"`
+}
+
+// SmartRequest defines a smart playlist: the tracks matching Rules in the order of Sort, a track
+// sort field as in the music listing, at most Limit of them. Materialized playlists store the
+// matching tracks on a schedule, others are evaluated on every request.
+type SmartRequest struct {
+	Rules       schema.SmartRule `json:"rules"`
+	Limit       *int             `json:"limit" validate:"omitempty,min=1,max=10000"`
+	Sort        string           `json:"sort" example:"-created_at"`
+	Materialize bool             `json:"materialize"`
+}
+
+// AddEntriesRequest places tracks in a playlist, at Position or at the end when it is omitted.
+// A track may be placed more than once. Version is required, see VersionRequest.
+type AddEntriesRequest struct {
+	TrackIDs []uint64 `json:"track_ids" validate:"required,min=1,max=500"`
+	Position *int     `json:"position" validate:"omitempty,min=0"`
+	Version  *uint64  `json:"version" validate:"required"`
+}
+
+// MoveEntriesRequest reorders a playlist, moves are applied one after the other. Version is
+// required, see VersionRequest.
+type MoveEntriesRequest struct {
+	Moves   []MoveRequest `json:"moves" validate:"required,min=1,max=100,dive"`
+	Version *uint64       `json:"version" validate:"required"`
+}
+
+// MoveRequest moves an entry so that it ends up at Position, counted from 0. Positions past the
+// end move the entry to the end.
+type MoveRequest struct {
+	EntryID  uint64 `json:"entry_id" validate:"required"`
+	Position *int   `json:"position" validate:"required,min=0"`
+}
+
+// VersionRequest is the version of the playlist the client last read, required for every change of
+// the entries. The change is rejected if someone else changed the entries since.
+type VersionRequest struct {
+	Version *uint64 `query:"version" validate:"required"`
+}
+
+// MemberRequest sets the role of a playlist member
+type MemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=editor viewer" enums:"editor,viewer"`
+}
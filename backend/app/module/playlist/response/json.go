@@ -0,0 +1,248 @@
+package response
+
+import (
+	"strconv"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/repository"
+
+	trackResponse "git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+)
+
+type PlaylistResponse struct {
+	ID          uint64            `json:"id"`
+	UserID      uint64            `json:"user_id"`
+	Name        string            `json:"name"`
+	Description *string           `json:"description"`
+	Public      bool              `json:"public"`
+	Role        string            `json:"role"`
+	Version     uint64            `json:"version"`
+	CoverURLs   map[string]string `json:"cover_urls"`
+	Smart       *SmartResponse    `json:"smart"`
+	TrackCount  int64             `json:"track_count"`
+	DurationMs  int64             `json:"duration_ms"`
+	IsLiked     bool              `json:"is_liked"`
+	LikeCount   *int64            `json:"like_count,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   string            `json:"updated_at"`
+}
+
+// SmartResponse are the rules of a smart playlist, RefreshedAt is the last refresh of a
+// materialized one
+type SmartResponse struct {
+	Rules        *schema.SmartRule `json:"rules"`
+	Limit        *int              `json:"limit"`
+	Sort         *string           `json:"sort"`
+	Materialized bool              `json:"materialized"`
+	RefreshedAt  *string           `json:"refreshed_at"`
+}
+
+// PlaylistDetailResponse is a playlist with a page of its entries
+type PlaylistDetailResponse struct {
+	PlaylistResponse
+	Entries []EntryResponse `json:"entries"`
+}
+
+// EntryResponse is a track placed in a playlist. Tracks of a live smart playlist are not stored
+// as entries and have neither ID nor added_at.
+type EntryResponse struct {
+	ID       uint64                       `json:"id,omitempty"`
+	Position int                          `json:"position"`
+	AddedAt  string                       `json:"added_at,omitempty"`
+	AddedBy  *schema.User                 `json:"added_by,omitempty"`
+	Track    *trackResponse.TrackResponse `json:"track"`
+}
+
+// EntryOrderResponse is the order of a playlist after a change with its new version
+type EntryOrderResponse struct {
+	Version uint64                  `json:"version"`
+	Entries []EntryPositionResponse `json:"entries"`
+}
+
+// EntryPositionResponse is an entry in the order of a playlist after a change
+type EntryPositionResponse struct {
+	ID       uint64 `json:"id"`
+	TrackID  uint64 `json:"track_id"`
+	Position int    `json:"position"`
+}
+
+// MemberResponse is a user with access to a playlist, the owner is listed first
+type MemberResponse struct {
+	User    schema.User `json:"user"`
+	Role    string      `json:"role"`
+	AddedAt string      `json:"added_at,omitempty"`
+}
+
+// ActivityResponse is a change of a playlist, User is missing for removed users and for changes
+// made by the system
+type ActivityResponse struct {
+	ID        uint64         `json:"id"`
+	Action    string         `json:"action"`
+	User      *schema.User   `json:"user"`
+	Data      map[string]any `json:"data"`
+	CreatedAt string         `json:"created_at"`
+}
+
+// FromPlaylistSchema describes a playlist to a user with the given role
+func FromPlaylistSchema(playlist schema.Playlist, role string, stats repository.Stats, storage trackResponse.URLResolver) PlaylistResponse {
+	return PlaylistResponse{
+		ID:          playlist.ID,
+		UserID:      playlist.UserID,
+		Name:        playlist.Name,
+		Description: playlist.Description,
+		Public:      playlist.Public,
+		Role:        role,
+		Version:     playlist.Version,
+		CoverURLs:   coverURLs(playlist.Covers, storage),
+		Smart:       smart(playlist),
+		TrackCount:  stats.TrackCount,
+		DurationMs:  stats.DurationMs,
+		CreatedAt:   playlist.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   playlist.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// FromPlaylistListSchema describes the playlists of a user, their members must be loaded for the user
+func FromPlaylistListSchema(playlists []schema.Playlist, userID uint64, stats map[uint64]repository.Stats, storage trackResponse.URLResolver) []PlaylistResponse {
+	res := make([]PlaylistResponse, 0, len(playlists))
+	for _, p := range playlists {
+		res = append(res, FromPlaylistSchema(p, p.Role(userID), stats[p.ID], storage))
+	}
+
+	return res
+}
+
+// PlaylistIDs returns the IDs of playlists in order
+func PlaylistIDs(playlists []PlaylistResponse) []uint64 {
+	ids := make([]uint64, 0, len(playlists))
+	for _, p := range playlists {
+		ids = append(ids, p.ID)
+	}
+
+	return ids
+}
+
+// SetLike marks a playlist userID likes and shows the like count when they own it
+func SetLike(playlist *PlaylistResponse, userID uint64, liked bool, count int64) {
+	playlist.IsLiked = liked
+	if playlist.UserID == userID {
+		playlist.LikeCount = &count
+	}
+}
+
+// FromEntryListSchema pairs entries with their tracks, entries of a track that is gone have no track
+func FromEntryListSchema(entries []schema.PlaylistEntry, tracks []schema.Track, storage trackResponse.URLResolver) []EntryResponse {
+	byID := make(map[uint64]trackResponse.TrackResponse, len(tracks))
+	for _, t := range tracks {
+		byID[t.ID] = trackResponse.FromTrackSchema(t, storage)
+	}
+
+	res := make([]EntryResponse, 0, len(entries))
+	for _, e := range entries {
+		entry := EntryResponse{
+			ID:       e.ID,
+			Position: e.Position,
+			AddedAt:  e.CreatedAt.Format("2006-01-02 15:04:05"),
+			AddedBy:  e.AddedBy,
+		}
+		if t, ok := byID[e.TrackID]; ok {
+			entry.Track = &t
+		}
+		res = append(res, entry)
+	}
+
+	return res
+}
+
+// FromMatchedTrackListSchema lists the tracks of a live smart playlist, offset is the position
+// of the first one
+func FromMatchedTrackListSchema(tracks []schema.Track, offset int, storage trackResponse.URLResolver) []EntryResponse {
+	res := make([]EntryResponse, 0, len(tracks))
+	for i, t := range tracks {
+		track := trackResponse.FromTrackSchema(t, storage)
+		res = append(res, EntryResponse{
+			Position: offset + i,
+			Track:    &track,
+		})
+	}
+
+	return res
+}
+
+func FromEntryOrderSchema(entries []schema.PlaylistEntry, version uint64) EntryOrderResponse {
+	res := EntryOrderResponse{
+		Version: version,
+		Entries: make([]EntryPositionResponse, 0, len(entries)),
+	}
+	for _, e := range entries {
+		res.Entries = append(res.Entries, EntryPositionResponse{
+			ID:       e.ID,
+			TrackID:  e.TrackID,
+			Position: e.Position,
+		})
+	}
+
+	return res
+}
+
+// FromMemberListSchema lists the owner and the members of a playlist, members must be loaded with their user
+func FromMemberListSchema(playlist schema.Playlist) []MemberResponse {
+	res := make([]MemberResponse, 0, len(playlist.Members)+1)
+	res = append(res, MemberResponse{User: playlist.User, Role: schema.PlaylistRoleOwner})
+	for _, m := range playlist.Members {
+		res = append(res, MemberResponse{
+			User:    m.User,
+			Role:    m.Role,
+			AddedAt: m.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return res
+}
+
+func FromActivityListSchema(activities []schema.PlaylistActivity) []ActivityResponse {
+	res := make([]ActivityResponse, 0, len(activities))
+	for _, a := range activities {
+		res = append(res, ActivityResponse{
+			ID:        a.ID,
+			Action:    a.Action,
+			User:      a.User,
+			Data:      a.Data,
+			CreatedAt: a.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return res
+}
+
+func coverURLs(covers []schema.PlaylistCover, storage trackResponse.URLResolver) map[string]string {
+	if len(covers) == 0 {
+		return nil
+	}
+
+	urls := make(map[string]string, len(covers))
+	for _, c := range covers {
+		urls[strconv.Itoa(c.Size)] = storage.GetURL(c.StorageFilename)
+	}
+
+	return urls
+}
+
+func smart(playlist schema.Playlist) *SmartResponse {
+	if !playlist.IsSmart() {
+		return nil
+	}
+
+	res := &SmartResponse{
+		Rules:        playlist.Rules,
+		Limit:        playlist.RuleLimit,
+		Sort:         playlist.RuleSort,
+		Materialized: playlist.Materialized,
+	}
+	if playlist.RefreshedAt != nil {
+		refreshedAt := playlist.RefreshedAt.Format("2006-01-02 15:04:05")
+		res.RefreshedAt = &refreshedAt
+	}
+
+	return res
+}
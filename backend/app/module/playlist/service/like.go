@@ -0,0 +1,89 @@
+package service
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/response"
+
+	trackResponse "git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+)
+
+// GetPlaylistsByIDs returns the playlists among ids the user may view in the order of ids
+func (s *playlistService) GetPlaylistsByIDs(ids []uint64, userID uint64) ([]response.PlaylistResponse, error) {
+	playlists, err := s.repo.ListVisiblePlaylists(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint64]schema.Playlist, len(playlists))
+	for _, playlist := range playlists {
+		byID[playlist.ID] = playlist
+	}
+
+	ordered := make([]schema.Playlist, 0, len(playlists))
+	for _, id := range ids {
+		if playlist, ok := byID[id]; ok {
+			ordered = append(ordered, playlist)
+		}
+	}
+
+	return s.playlistResponses(ordered, userID)
+}
+
+// LikePlaylist likes a playlist the user may view
+func (s *playlistService) LikePlaylist(id uint64, userID uint64) error {
+	if _, err := s.findPlaylist(id, userID); err != nil {
+		return err
+	}
+
+	return s.likes.CreateLike(userID, schema.LikePlaylist, id)
+}
+
+// UnlikePlaylist removes a like, also of a playlist the user can no longer view
+func (s *playlistService) UnlikePlaylist(id uint64, userID uint64) error {
+	return s.likes.DeleteLike(userID, schema.LikePlaylist, id)
+}
+
+// loadLikes marks the playlists the user likes and counts the likes of the playlists they own
+func (s *playlistService) loadLikes(userID uint64, playlists []response.PlaylistResponse) error {
+	stats, err := s.likes.LikeStats(userID, schema.LikePlaylist, response.PlaylistIDs(playlists))
+	if err != nil {
+		return err
+	}
+
+	for i := range playlists {
+		stat := stats[playlists[i].ID]
+		response.SetLike(&playlists[i], userID, stat.Liked, stat.Count)
+	}
+
+	return nil
+}
+
+// loadEntryStats marks the tracks of entries the user likes and adds their ratings
+func (s *playlistService) loadEntryStats(userID uint64, entries []response.EntryResponse) error {
+	ids := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Track != nil {
+			ids = append(ids, entry.Track.ID)
+		}
+	}
+
+	likes, err := s.likes.LikeStats(userID, schema.LikeTrack, ids)
+	if err != nil {
+		return err
+	}
+
+	ratings, err := s.tracks.RatingStats(userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Track != nil {
+			like, rating := likes[entry.Track.ID], ratings[entry.Track.ID]
+			trackResponse.SetLike(entry.Track, userID, like.Liked, like.Count)
+			entry.Track.Rating = trackResponse.FromRating(rating.Mine, rating.Average, rating.Count)
+		}
+	}
+
+	return nil
+}
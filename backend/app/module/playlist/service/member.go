@@ -0,0 +1,133 @@
+package service
+
+import (
+	"log"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/response"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"github.com/gofiber/fiber/v2"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// GetMembers lists the owner and the members of a playlist the user may view
+func (s *playlistService) GetMembers(id uint64, userID uint64) ([]response.MemberResponse, error) {
+	playlist, err := s.findPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.FromMemberListSchema(*playlist), nil
+}
+
+// SaveMember invites a user to a playlist or changes their role, only the owner can
+func (s *playlistService) SaveMember(id uint64, userID uint64, memberID uint64, req request.MemberRequest) ([]response.MemberResponse, error) {
+	playlist, err := s.findOwnedPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if memberID == playlist.UserID {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "The owner of a playlist cannot be a member",
+		}
+	}
+
+	exists, err := s.repo.UserExists(memberID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "User not found",
+		}
+	}
+
+	action := schema.ActivityMemberAdded
+	if playlist.Role(memberID) != "" {
+		action = schema.ActivityMemberUpdated
+	}
+
+	if err := s.repo.SaveMember(&schema.PlaylistMember{PlaylistID: id, UserID: memberID, Role: req.Role}); err != nil {
+		return nil, err
+	}
+
+	s.record(id, userID, action, map[string]any{"user_id": memberID, "role": req.Role})
+
+	return s.GetMembers(id, userID)
+}
+
+// RemoveMember takes a member out of a playlist. The owner removes any member, members can
+// remove themselves to leave the playlist.
+func (s *playlistService) RemoveMember(id uint64, userID uint64, memberID uint64) error {
+	playlist, err := s.findPlaylist(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if playlist.UserID != userID && memberID != userID {
+		return &uresponse.Error{
+			Code:    fiber.StatusForbidden,
+			Message: "You don't have permission to change this playlist",
+		}
+	}
+
+	if memberID == playlist.UserID || playlist.Role(memberID) == "" {
+		return &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Member not found",
+		}
+	}
+
+	if err := s.repo.DeleteMember(id, memberID); err != nil {
+		return err
+	}
+
+	s.record(id, userID, schema.ActivityMemberRemoved, map[string]any{"user_id": memberID})
+
+	return nil
+}
+
+// GetActivities lists the changes of a playlist, the latest first. Only the owner and members
+// see the activity.
+func (s *playlistService) GetActivities(id uint64, userID uint64, p *paginator.Pagination) ([]response.ActivityResponse, *paginator.Pagination, error) {
+	playlist, err := s.findPlaylist(id, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	if playlist.Role(userID) == "" {
+		return nil, p, &uresponse.Error{
+			Code:    fiber.StatusForbidden,
+			Message: "You don't have permission to view the activity of this playlist",
+		}
+	}
+
+	activities, p, err := s.repo.PaginateActivities(id, p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	return response.FromActivityListSchema(activities), p, nil
+}
+
+// record adds a change to the activity of a playlist, failures are logged and never fail the change.
+// userID 0 records a change made by the system, like a deleted track or a scheduled refresh.
+func (s *playlistService) record(playlistID uint64, userID uint64, action string, data map[string]any) {
+	activity := &schema.PlaylistActivity{
+		PlaylistID: playlistID,
+		Action:     action,
+		Data:       data,
+	}
+	if userID != 0 {
+		activity.UserID = &userID
+	}
+
+	if err := s.repo.CreateActivity(activity); err != nil {
+		log.Printf("[playlist] record %s of playlist %d err=%v", action, playlistID, err)
+	}
+}
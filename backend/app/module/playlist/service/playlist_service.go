@@ -0,0 +1,681 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"slices"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/response"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/helpers"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/imaging"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+
+	likeRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/like/repository"
+	tagService "git.dev.siap.id/kukuhkkh/app-music/app/module/tag/service"
+	trackRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+)
+
+// MaxEntries is the largest number of entries a playlist holds
+const MaxEntries = 10000
+
+var (
+	// cover sizes and quality when track.artwork does not set them, as for track artwork
+	defaultCoverSizes   = []int{64, 300, 1000}
+	defaultCoverQuality = 85
+)
+
+type playlistService struct {
+	repo    repository.PlaylistRepository
+	tracks  trackRepository.TrackRepository
+	tags    tagService.TagService
+	likes   likeRepository.LikeRepository
+	storage storage.Storage
+	cfg     *config.Config
+}
+
+type PlaylistService interface {
+	GetPlaylists(userID uint64, req request.PlaylistPaginationRequest, p *paginator.Pagination) (playlists []response.PlaylistResponse, pagination *paginator.Pagination, err error)
+	GetPlaylistByID(id uint64, userID uint64, p *paginator.Pagination) (playlist *response.PlaylistDetailResponse, pagination *paginator.Pagination, err error)
+	CreatePlaylist(userID uint64, req request.CreatePlaylistRequest) (playlist *response.PlaylistResponse, err error)
+	UpdatePlaylist(id uint64, userID uint64, req request.UpdatePlaylistRequest) (playlist *response.PlaylistResponse, err error)
+	DeletePlaylist(id uint64, userID uint64) (err error)
+	ReplaceCover(ctx context.Context, id uint64, userID uint64, fileHeader *multipart.FileHeader) (playlist *response.PlaylistResponse, err error)
+	DeleteCover(id uint64, userID uint64) (playlist *response.PlaylistResponse, err error)
+	AddEntries(id uint64, userID uint64, req request.AddEntriesRequest) (order *response.EntryOrderResponse, err error)
+	RemoveEntry(id uint64, userID uint64, entryID uint64, version *uint64) (order *response.EntryOrderResponse, err error)
+	MoveEntries(id uint64, userID uint64, req request.MoveEntriesRequest) (order *response.EntryOrderResponse, err error)
+	RemoveTrack(trackID uint64) (err error)
+	WithTx(tx *gorm.DB) PlaylistService
+	GetMembers(id uint64, userID uint64) (members []response.MemberResponse, err error)
+	SaveMember(id uint64, userID uint64, memberID uint64, req request.MemberRequest) (members []response.MemberResponse, err error)
+	RemoveMember(id uint64, userID uint64, memberID uint64) (err error)
+	GetActivities(id uint64, userID uint64, p *paginator.Pagination) (activities []response.ActivityResponse, pagination *paginator.Pagination, err error)
+	RefreshPlaylist(id uint64, userID uint64) (playlist *response.PlaylistResponse, err error)
+	RefreshSmartPlaylists(ctx context.Context) (refreshed int, failed int, err error)
+	GetPlaylistsByIDs(ids []uint64, userID uint64) (playlists []response.PlaylistResponse, err error)
+	LikePlaylist(id uint64, userID uint64) (err error)
+	UnlikePlaylist(id uint64, userID uint64) (err error)
+}
+
+func NewPlaylistService(
+	repo repository.PlaylistRepository,
+	tracks trackRepository.TrackRepository,
+	tags tagService.TagService,
+	likes likeRepository.LikeRepository,
+	storage storage.Storage,
+	cfg *config.Config,
+) PlaylistService {
+	return &playlistService{
+		repo:    repo,
+		tracks:  tracks,
+		tags:    tags,
+		likes:   likes,
+		storage: storage,
+		cfg:     cfg,
+	}
+}
+
+func (s *playlistService) GetPlaylists(userID uint64, req request.PlaylistPaginationRequest, p *paginator.Pagination) ([]response.PlaylistResponse, *paginator.Pagination, error) {
+	playlists, p, err := s.repo.PaginatePlaylists(userID, strings.TrimSpace(req.Search), p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	res, err := s.playlistResponses(playlists, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	return res, p, nil
+}
+
+func (s *playlistService) GetPlaylistByID(id uint64, userID uint64, p *paginator.Pagination) (*response.PlaylistDetailResponse, *paginator.Pagination, error) {
+	playlist, err := s.findPlaylist(id, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	res, err := s.playlistResponse(playlist, userID)
+	if err != nil {
+		return nil, p, err
+	}
+
+	if isLive(playlist) {
+		tracks, p, err := s.tracks.PaginateTracks(s.smartFilter(playlist), p)
+		if err != nil {
+			return nil, p, err
+		}
+
+		entries := response.FromMatchedTrackListSchema(tracks, p.Offset, s.storage)
+		if err := s.loadEntryStats(userID, entries); err != nil {
+			return nil, p, err
+		}
+
+		return &response.PlaylistDetailResponse{
+			PlaylistResponse: *res,
+			Entries:          entries,
+		}, p, nil
+	}
+
+	entries, p, err := s.repo.PaginateEntries(id, p)
+	if err != nil {
+		return nil, p, err
+	}
+
+	trackIDs := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		trackIDs = append(trackIDs, entry.TrackID)
+	}
+
+	tracks, err := s.repo.ListTracks(trackIDs)
+	if err != nil {
+		return nil, p, err
+	}
+
+	entryRes := response.FromEntryListSchema(entries, tracks, s.storage)
+	if err := s.loadEntryStats(userID, entryRes); err != nil {
+		return nil, p, err
+	}
+
+	return &response.PlaylistDetailResponse{
+		PlaylistResponse: *res,
+		Entries:          entryRes,
+	}, p, nil
+}
+
+func (s *playlistService) CreatePlaylist(userID uint64, req request.CreatePlaylistRequest) (*response.PlaylistResponse, error) {
+	if req.Smart != nil && len(req.TrackIDs) > 0 {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "A smart playlist is filled by its rules and cannot have track_ids",
+		}
+	}
+
+	if err := s.checkTracks(req.TrackIDs); err != nil {
+		return nil, err
+	}
+
+	playlist := &schema.Playlist{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: optional(req.Description),
+		Public:      req.Public,
+	}
+	if req.Smart != nil {
+		if err := setSmart(playlist, *req.Smart); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.CreatePlaylist(playlist); err != nil {
+		return nil, err
+	}
+
+	if len(req.TrackIDs) > 0 {
+		_, version, err := s.repo.UpdateEntries(playlist.ID, nil, insertEntries(req.TrackIDs, nil, userID))
+		if err != nil {
+			return nil, err
+		}
+		playlist.Version = version
+	}
+
+	if playlist.Materialized {
+		if _, err := s.refresh(playlist); err != nil {
+			return nil, err
+		}
+	}
+
+	s.record(playlist.ID, userID, schema.ActivityCreated, nil)
+
+	return s.playlistResponse(playlist, userID)
+}
+
+func (s *playlistService) UpdatePlaylist(id uint64, userID uint64, req request.UpdatePlaylistRequest) (*response.PlaylistResponse, error) {
+	playlist, err := s.findOwnedPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist.Name = strings.TrimSpace(req.Name)
+	if req.Description != nil {
+		playlist.Description = optional(req.Description)
+	}
+	if req.Public != nil {
+		playlist.Public = *req.Public
+	}
+
+	wasMaterialized := playlist.Materialized
+	if req.Smart != nil {
+		if !playlist.IsSmart() {
+			return nil, &uresponse.Error{
+				Code:    fiber.StatusUnprocessableEntity,
+				Message: "Only smart playlists have rules",
+			}
+		}
+
+		if err := setSmart(playlist, *req.Smart); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.UpdatePlaylist(playlist); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.Smart != nil && playlist.Materialized:
+		if _, err := s.refresh(playlist); err != nil {
+			return nil, err
+		}
+	case wasMaterialized && !playlist.Materialized:
+		// a live playlist no longer reads its stored tracks
+		_, version, err := s.repo.UpdateEntries(id, nil, func([]schema.PlaylistEntry) ([]schema.PlaylistEntry, error) {
+			return nil, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		playlist.Version = version
+	}
+
+	s.record(id, userID, schema.ActivityUpdated, nil)
+
+	return s.playlistResponse(playlist, userID)
+}
+
+func (s *playlistService) DeletePlaylist(id uint64, userID uint64) error {
+	playlist, err := s.findOwnedPlaylist(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeletePlaylist(id); err != nil {
+		return err
+	}
+
+	s.deleteCoverFiles(playlist.Covers)
+
+	return nil
+}
+
+// ReplaceCover renders the configured square sizes of an uploaded image as the playlist cover
+func (s *playlistService) ReplaceCover(ctx context.Context, id uint64, userID uint64, fileHeader *multipart.FileHeader) (*response.PlaylistResponse, error) {
+	playlist, err := s.findOwnedPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readFileHeader(fileHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	covers, err := s.storeCover(ctx, playlist.Name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := s.repo.ReplaceCovers(id, covers)
+	if err != nil {
+		s.deleteCoverFiles(covers)
+		return nil, err
+	}
+
+	s.deleteCoverFiles(old)
+	playlist.Covers = covers
+	s.record(id, userID, schema.ActivityCoverChanged, nil)
+
+	return s.playlistResponse(playlist, userID)
+}
+
+func (s *playlistService) DeleteCover(id uint64, userID uint64) (*response.PlaylistResponse, error) {
+	playlist, err := s.findOwnedPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := s.repo.ReplaceCovers(id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.deleteCoverFiles(old)
+	playlist.Covers = nil
+	s.record(id, userID, schema.ActivityCoverRemoved, nil)
+
+	return s.playlistResponse(playlist, userID)
+}
+
+func (s *playlistService) AddEntries(id uint64, userID uint64, req request.AddEntriesRequest) (*response.EntryOrderResponse, error) {
+	if _, err := s.findEditablePlaylist(id, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkTracks(req.TrackIDs); err != nil {
+		return nil, err
+	}
+
+	entries, version, err := s.repo.UpdateEntries(id, req.Version, insertEntries(req.TrackIDs, req.Position, userID))
+	if err != nil {
+		return nil, versionError(err)
+	}
+
+	data := map[string]any{"track_ids": req.TrackIDs}
+	if req.Position != nil {
+		data["position"] = *req.Position
+	}
+	s.record(id, userID, schema.ActivityEntriesAdded, data)
+
+	res := response.FromEntryOrderSchema(entries, version)
+	return &res, nil
+}
+
+func (s *playlistService) RemoveEntry(id uint64, userID uint64, entryID uint64, version *uint64) (*response.EntryOrderResponse, error) {
+	if _, err := s.findEditablePlaylist(id, userID); err != nil {
+		return nil, err
+	}
+
+	var removed schema.PlaylistEntry
+	entries, newVersion, err := s.repo.UpdateEntries(id, version, func(entries []schema.PlaylistEntry) ([]schema.PlaylistEntry, error) {
+		i, err := entryIndex(entries, entryID)
+		if err != nil {
+			return nil, err
+		}
+
+		removed = entries[i]
+		return slices.Delete(entries, i, i+1), nil
+	})
+	if err != nil {
+		return nil, versionError(err)
+	}
+
+	s.record(id, userID, schema.ActivityEntryRemoved, map[string]any{
+		"entry_id": removed.ID,
+		"track_id": removed.TrackID,
+		"position": removed.Position,
+	})
+
+	res := response.FromEntryOrderSchema(entries, newVersion)
+	return &res, nil
+}
+
+func (s *playlistService) MoveEntries(id uint64, userID uint64, req request.MoveEntriesRequest) (*response.EntryOrderResponse, error) {
+	if _, err := s.findEditablePlaylist(id, userID); err != nil {
+		return nil, err
+	}
+
+	entries, version, err := s.repo.UpdateEntries(id, req.Version, func(entries []schema.PlaylistEntry) ([]schema.PlaylistEntry, error) {
+		for _, move := range req.Moves {
+			i, err := entryIndex(entries, move.EntryID)
+			if err != nil {
+				return nil, err
+			}
+
+			entry := entries[i]
+			entries = slices.Delete(entries, i, i+1)
+			entries = slices.Insert(entries, min(*move.Position, len(entries)), entry)
+		}
+
+		return entries, nil
+	})
+	if err != nil {
+		return nil, versionError(err)
+	}
+
+	s.record(id, userID, schema.ActivityEntriesMoved, map[string]any{"moves": req.Moves})
+
+	res := response.FromEntryOrderSchema(entries, version)
+	return &res, nil
+}
+
+// WithTx returns the service changing playlists in the transaction tx
+func (s *playlistService) WithTx(tx *gorm.DB) PlaylistService {
+	return &playlistService{
+		repo:    s.repo.WithTx(tx),
+		tracks:  s.tracks,
+		tags:    s.tags,
+		likes:   s.likes,
+		storage: s.storage,
+		cfg:     s.cfg,
+	}
+}
+
+// RemoveTrack takes a deleted track and its CUE segments out of every playlist, the change is
+// recorded in the activity of each playlist without a user
+func (s *playlistService) RemoveTrack(trackID uint64) error {
+	playlistIDs, err := s.repo.DeleteTrackEntries(trackID)
+	if err != nil {
+		return err
+	}
+
+	for _, playlistID := range playlistIDs {
+		s.record(playlistID, 0, schema.ActivityTrackDeleted, map[string]any{"track_id": trackID})
+	}
+
+	return nil
+}
+
+// findPlaylist returns a playlist the user may view: their own, one they are a member of or a
+// public one. Private playlists of other users are reported as missing.
+func (s *playlistService) findPlaylist(id uint64, userID uint64) (*schema.Playlist, error) {
+	playlist, err := s.repo.FindPlaylistByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !playlist.Public && playlist.Role(userID) == "") {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Playlist not found",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return playlist, nil
+}
+
+// findOwnedPlaylist returns a playlist the user may change, only the owner can
+func (s *playlistService) findOwnedPlaylist(id uint64, userID uint64) (*schema.Playlist, error) {
+	playlist, err := s.findPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if playlist.UserID != userID {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusForbidden,
+			Message: "You don't have permission to change this playlist",
+		}
+	}
+
+	return playlist, nil
+}
+
+// findEditablePlaylist returns a playlist whose entries the user may change, the owner and editors
+// can. The tracks of a smart playlist follow its rules instead.
+func (s *playlistService) findEditablePlaylist(id uint64, userID uint64) (*schema.Playlist, error) {
+	playlist, err := s.findPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role := playlist.Role(userID); role != schema.PlaylistRoleOwner && role != schema.PlaylistRoleEditor {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusForbidden,
+			Message: "You don't have permission to change the entries of this playlist",
+		}
+	}
+
+	if playlist.IsSmart() {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "The tracks of a smart playlist follow its rules and cannot be changed by hand",
+		}
+	}
+
+	return playlist, nil
+}
+
+func (s *playlistService) playlistResponse(playlist *schema.Playlist, userID uint64) (*response.PlaylistResponse, error) {
+	stats, err := s.repo.PlaylistStats([]uint64{playlist.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.liveStats([]schema.Playlist{*playlist}, stats); err != nil {
+		return nil, err
+	}
+
+	res := []response.PlaylistResponse{response.FromPlaylistSchema(*playlist, role(playlist, userID), stats[playlist.ID], s.storage)}
+	if err := s.loadLikes(userID, res); err != nil {
+		return nil, err
+	}
+
+	return &res[0], nil
+}
+
+// playlistResponses describes playlists whose members are loaded for the user
+func (s *playlistService) playlistResponses(playlists []schema.Playlist, userID uint64) ([]response.PlaylistResponse, error) {
+	ids := make([]uint64, 0, len(playlists))
+	for _, playlist := range playlists {
+		ids = append(ids, playlist.ID)
+	}
+
+	stats, err := s.repo.PlaylistStats(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.liveStats(playlists, stats); err != nil {
+		return nil, err
+	}
+
+	res := response.FromPlaylistListSchema(playlists, userID, stats, s.storage)
+	if err := s.loadLikes(userID, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// checkTracks reports tracks that do not exist
+func (s *playlistService) checkTracks(ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	unique := slices.Compact(slices.Sorted(slices.Values(ids)))
+	count, err := s.repo.CountTracks(unique)
+	if err != nil {
+		return err
+	}
+
+	if count != int64(len(unique)) {
+		return &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Track not found",
+		}
+	}
+
+	return nil
+}
+
+// insertEntries places the tracks, in order, at position or at the end when it is nil
+func insertEntries(trackIDs []uint64, position *int, userID uint64) repository.EditEntries {
+	return func(entries []schema.PlaylistEntry) ([]schema.PlaylistEntry, error) {
+		if len(entries)+len(trackIDs) > MaxEntries {
+			return nil, &uresponse.Error{
+				Code:    fiber.StatusUnprocessableEntity,
+				Message: fmt.Sprintf("A playlist holds at most %d entries", MaxEntries),
+			}
+		}
+
+		at := len(entries)
+		if position != nil {
+			at = min(*position, at)
+		}
+
+		added := make([]schema.PlaylistEntry, 0, len(trackIDs))
+		for _, trackID := range trackIDs {
+			added = append(added, schema.PlaylistEntry{TrackID: trackID, AddedByID: &userID})
+		}
+
+		return slices.Insert(entries, at, added...), nil
+	}
+}
+
+// role returns the role a user acts in on a playlist they can view, others view public playlists
+func role(playlist *schema.Playlist, userID uint64) string {
+	if r := playlist.Role(userID); r != "" {
+		return r
+	}
+
+	return schema.PlaylistRoleViewer
+}
+
+// versionError reports an edit based on an outdated version of the playlist
+func versionError(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return &uresponse.Error{
+			Code:    fiber.StatusConflict,
+			Message: "The playlist was changed by someone else, reload it and try again",
+		}
+	}
+
+	return err
+}
+
+func entryIndex(entries []schema.PlaylistEntry, entryID uint64) (int, error) {
+	i := slices.IndexFunc(entries, func(e schema.PlaylistEntry) bool {
+		return e.ID == entryID
+	})
+	if i < 0 {
+		return 0, &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Entry not found",
+		}
+	}
+
+	return i, nil
+}
+
+// storeCover renders the configured square sizes of an image and uploads them to storage
+func (s *playlistService) storeCover(ctx context.Context, name string, data []byte) ([]schema.PlaylistCover, error) {
+	sizes := s.cfg.Track.Artwork.Sizes
+	if len(sizes) == 0 {
+		sizes = defaultCoverSizes
+	}
+
+	quality := s.cfg.Track.Artwork.Quality
+	if quality <= 0 || quality > 100 {
+		quality = defaultCoverQuality
+	}
+
+	variants, err := imaging.SquareVariants(data, sizes, quality)
+	if err != nil {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid cover image",
+		}
+	}
+
+	prefix := fmt.Sprintf("playlist/%d_%s", time.Now().UnixNano(), helpers.Slug(name))
+	covers := make([]schema.PlaylistCover, 0, len(variants))
+	for _, v := range variants {
+		filename := fmt.Sprintf("%s_%d.jpg", prefix, v.Size)
+		if _, err := s.storage.Upload(ctx, filename, bytes.NewReader(v.Data)); err != nil {
+			s.deleteCoverFiles(covers)
+			return nil, err
+		}
+
+		covers = append(covers, schema.PlaylistCover{
+			Size:            v.Size,
+			StorageFilename: filename,
+		})
+	}
+
+	return covers, nil
+}
+
+func (s *playlistService) deleteCoverFiles(covers []schema.PlaylistCover) {
+	for _, c := range covers {
+		if err := s.storage.Delete(c.StorageFilename); err != nil {
+			log.Printf("[playlist] delete cover %s err=%v", c.StorageFilename, err)
+		}
+	}
+}
+
+// optional returns nil for a missing or blank description
+func optional(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+
+	v := strings.TrimSpace(*s)
+	return &v
+}
+
+func readFileHeader(fileHeader *multipart.FileHeader) ([]byte, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+)
+
+// Refresher refreshes the materialized smart playlists every track.smart_playlists.refresh_seconds
+type Refresher struct {
+	cfg       *config.Config
+	playlists PlaylistService
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRefresher(cfg *config.Config, playlists PlaylistService) *Refresher {
+	return &Refresher{
+		cfg:       cfg,
+		playlists: playlists,
+	}
+}
+
+// Start refreshes the playlists now and then on every interval, it is a no-op without an interval
+func (r *Refresher) Start() error {
+	interval := r.cfg.Track.SmartPlaylists.Refresh * time.Second
+	if interval <= 0 {
+		return nil
+	}
+
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			r.refresh(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Printf("[playlist] refreshing smart playlists every %s", interval)
+	return nil
+}
+
+// Stop ends the schedule, a refresh in progress stops after its current playlist
+func (r *Refresher) Stop() error {
+	if r.cancel == nil {
+		return nil
+	}
+
+	r.cancel()
+	r.wg.Wait()
+
+	return nil
+}
+
+func (r *Refresher) refresh(ctx context.Context) {
+	refreshed, failed, err := r.playlists.RefreshSmartPlaylists(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[playlist] refresh smart playlists err=%v", err)
+		return
+	}
+
+	if refreshed > 0 || failed > 0 {
+		log.Printf("[playlist] refreshed %d smart playlists, %d failed", refreshed, failed)
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/response"
+	"github.com/gofiber/fiber/v2"
+
+	trackRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// errUnchanged aborts the refresh of a smart playlist whose tracks did not change
+var errUnchanged = errors.New("smart playlist unchanged")
+
+// RefreshPlaylist stores the tracks currently matching the rules of a materialized smart playlist,
+// live smart playlists are always current. The owner and editors can refresh.
+func (s *playlistService) RefreshPlaylist(id uint64, userID uint64) (*response.PlaylistResponse, error) {
+	playlist, err := s.findPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role := playlist.Role(userID); role != schema.PlaylistRoleOwner && role != schema.PlaylistRoleEditor {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusForbidden,
+			Message: "You don't have permission to change the entries of this playlist",
+		}
+	}
+
+	if !playlist.IsSmart() {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "Only smart playlists can be refreshed",
+		}
+	}
+
+	if playlist.Materialized {
+		if _, err := s.refresh(playlist); err != nil {
+			return nil, err
+		}
+		s.record(id, userID, schema.ActivityRefreshed, nil)
+	}
+
+	return s.playlistResponse(playlist, userID)
+}
+
+// RefreshSmartPlaylists refreshes every materialized smart playlist, a playlist that fails is
+// logged and skipped. Playlists whose tracks changed record the refresh without a user.
+func (s *playlistService) RefreshSmartPlaylists(ctx context.Context) (refreshed int, failed int, err error) {
+	playlists, err := s.repo.ListMaterializedPlaylists()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range playlists {
+		if ctx.Err() != nil {
+			return refreshed, failed, ctx.Err()
+		}
+
+		changed, err := s.refresh(&playlists[i])
+		if err != nil {
+			log.Printf("[playlist] refresh smart playlist %d err=%v", playlists[i].ID, err)
+			failed++
+			continue
+		}
+
+		if changed {
+			s.record(playlists[i].ID, 0, schema.ActivityRefreshed, nil)
+		}
+		refreshed++
+	}
+
+	return refreshed, failed, nil
+}
+
+// refresh replaces the entries of a materialized smart playlist with the matching tracks. Tracks
+// that stay in the playlist keep their entry and its added date. When the tracks are unchanged the
+// entries and the version are left alone and changed is false.
+func (s *playlistService) refresh(playlist *schema.Playlist) (changed bool, err error) {
+	trackIDs, err := s.tracks.ListTrackIDs(s.smartFilter(playlist))
+	if err != nil {
+		return false, err
+	}
+
+	_, version, err := s.repo.UpdateEntries(playlist.ID, nil, func(entries []schema.PlaylistEntry) ([]schema.PlaylistEntry, error) {
+		if slices.EqualFunc(entries, trackIDs, func(entry schema.PlaylistEntry, trackID uint64) bool {
+			return entry.TrackID == trackID
+		}) {
+			return nil, errUnchanged
+		}
+
+		kept := make(map[uint64]schema.PlaylistEntry, len(entries))
+		for _, entry := range entries {
+			kept[entry.TrackID] = entry
+		}
+
+		next := make([]schema.PlaylistEntry, 0, len(trackIDs))
+		for _, trackID := range trackIDs {
+			entry, ok := kept[trackID]
+			if !ok {
+				entry = schema.PlaylistEntry{TrackID: trackID}
+			}
+			delete(kept, trackID)
+			next = append(next, entry)
+		}
+
+		return next, nil
+	})
+	if changed = !errors.Is(err, errUnchanged); changed && err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if err := s.repo.SetRefreshedAt(playlist.ID, now); err != nil {
+		return false, err
+	}
+	playlist.RefreshedAt = &now
+	if changed {
+		playlist.Version = version
+	}
+
+	return changed, nil
+}
+
+// smartFilter selects the tracks of a smart playlist, its tag rules test the tags its owner sees
+func (s *playlistService) smartFilter(playlist *schema.Playlist) trackRepository.TrackFilter {
+	filter := trackRepository.TrackFilter{
+		Rule:     playlist.Rules,
+		TagOwner: s.tags.Owner(playlist.UserID),
+		Rater:    playlist.UserID,
+		Max:      MaxEntries,
+	}
+
+	if playlist.RuleLimit != nil {
+		filter.Max = min(*playlist.RuleLimit, MaxEntries)
+	}
+
+	if playlist.RuleSort != nil {
+		filter.Sort, filter.Desc = strings.CutPrefix(*playlist.RuleSort, "-")
+	}
+
+	return filter
+}
+
+// liveStats counts the tracks currently matching live smart playlists with one query and adds
+// them to stats
+func (s *playlistService) liveStats(playlists []schema.Playlist, stats map[uint64]repository.Stats) error {
+	filters := make(map[uint64]trackRepository.TrackFilter)
+	for i := range playlists {
+		if isLive(&playlists[i]) {
+			filters[playlists[i].ID] = s.smartFilter(&playlists[i])
+		}
+	}
+
+	live, err := s.tracks.TrackStats(filters)
+	if err != nil {
+		return err
+	}
+
+	for id, row := range live {
+		stats[id] = repository.Stats{ID: id, TrackCount: row.Count, DurationMs: row.DurationMs}
+	}
+
+	return nil
+}
+
+// setSmart validates the rules of a smart playlist and applies them
+func setSmart(playlist *schema.Playlist, req request.SmartRequest) error {
+	if _, err := trackRepository.CompileRule(req.Rules, 0); err != nil {
+		return &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("Invalid rules: %v", err),
+		}
+	}
+
+	playlist.Rules = &req.Rules
+	playlist.RuleLimit = req.Limit
+	playlist.RuleSort = nil
+	playlist.Materialized = req.Materialize
+
+	if req.Sort != "" {
+		sort, _ := strings.CutPrefix(req.Sort, "-")
+		if _, ok := trackRepository.TrackSortColumns[sort]; !ok {
+			return &uresponse.Error{
+				Code:    fiber.StatusUnprocessableEntity,
+				Message: fmt.Sprintf("Invalid sort %q", req.Sort),
+			}
+		}
+		playlist.RuleSort = &req.Sort
+	}
+
+	return nil
+}
+
+// isLive reports whether a playlist lists the tracks matching its rules on every request
+func isLive(playlist *schema.Playlist) bool {
+	return playlist.IsSmart() && !playlist.Materialized
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"errors"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TrimStream switches cutting silence trims on the stream endpoint, "true" or "false"
+const TrimStream = "track.silence.trim_stream"
+
+type settingRepository struct {
+	DB *database.Database
+}
+
+type SettingRepository interface {
+	FindSetting(key string) (value string, found bool, err error)
+	SaveSetting(key string, value string) (err error)
+	DeleteSetting(key string) (err error)
+}
+
+func NewSettingRepository(db *database.Database) SettingRepository {
+	return &settingRepository{
+		DB: db,
+	}
+}
+
+// FindSetting returns the stored value of key, found is false while the config file applies
+func (_i *settingRepository) FindSetting(key string) (value string, found bool, err error) {
+	setting := new(schema.Setting)
+	err = _i.DB.DB.Where("setting_key = ?", key).First(setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return setting.Value, true, nil
+}
+
+func (_i *settingRepository) SaveSetting(key string, value string) (err error) {
+	return _i.DB.DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&schema.Setting{Key: key, Value: value}).Error
+}
+
+// DeleteSetting removes a stored value, the config file applies again
+func (_i *settingRepository) DeleteSetting(key string) (err error) {
+	return _i.DB.DB.Unscoped().Where("setting_key = ?", key).Delete(&schema.Setting{}).Error
+}
@@ -0,0 +1,11 @@
+package setting
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/setting/repository"
+	"go.uber.org/fx"
+)
+
+var NewSettingModule = fx.Options(
+	// register repository of setting module
+	fx.Provide(repository.NewSettingRepository),
+)
@@ -0,0 +1,13 @@
+package controller
+
+import "git.dev.siap.id/kukuhkkh/app-music/app/module/tag/service"
+
+type Controller struct {
+	Tag TagController
+}
+
+func NewController(tagService service.TagService) *Controller {
+	return &Controller{
+		Tag: NewTagController(tagService),
+	}
+}
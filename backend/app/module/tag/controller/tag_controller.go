@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"net/url"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/service"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type tagController struct {
+	tagService service.TagService
+}
+
+type TagController interface {
+	GetTags(c *fiber.Ctx) error
+	AddTrackTags(c *fiber.Ctx) error
+	RemoveTrackTag(c *fiber.Ctx) error
+	UpdateTags(c *fiber.Ctx) error
+}
+
+func NewTagController(tagService service.TagService) TagController {
+	return &tagController{
+		tagService: tagService,
+	}
+}
+
+// GetTags godoc
+// @Summary      Get tag cloud
+// @Description  Get the tags of the user, or the shared tags when track.user_tags.shared is enabled, with the number of tracks each is attached to, the most used first
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        search query string false "Search by name"
+// @Param        limit  query int    false "Only the most used tags, 1-1000"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /tags [get]
+func (_i *tagController) GetTags(c *fiber.Ctx) error {
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.TagsRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	tags, err := _i.tagService.GetTags(claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get tags success"},
+		Data:     tags,
+	})
+}
+
+// AddTrackTags godoc
+// @Summary      Add tags to a track
+// @Description  Attach tags to a track, creating the tags that do not exist yet. Names are compared case-insensitively
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Track ID"
+// @Param        body body request.TrackTagsRequest true "Tags"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/tags [post]
+func (_i *tagController) AddTrackTags(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.TrackTagsRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.tagService.AddTrackTags(uint64(id), claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Add tags success"},
+		Data:     res,
+	})
+}
+
+// RemoveTrackTag godoc
+// @Summary      Remove a tag from a track
+// @Description  Detach a tag from a track, a tag left on no track is deleted
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Track ID"
+// @Param        name path string true "Tag name, URL encoded"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/tags/{name} [delete]
+func (_i *tagController) RemoveTrackTag(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid tag name",
+		}
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.tagService.RemoveTrackTag(uint64(id), claims.UserID, name)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Remove tag success"},
+		Data:     res,
+	})
+}
+
+// UpdateTags godoc
+// @Summary      Add and remove tags in bulk
+// @Description  Attach the add tags to and detach the remove tags from every listed track. A tag in both lists is removed
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        body body request.BulkTagsRequest true "Tracks and tags"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /tags/bulk [post]
+func (_i *tagController) UpdateTags(c *fiber.Ctx) error {
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.BulkTagsRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.tagService.UpdateTags(claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Update tags success"},
+		Data:     res,
+	})
+}
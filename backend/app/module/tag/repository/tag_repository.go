@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagCount is a tag with the number of tracks it is attached to
+type TagCount struct {
+	ID    uint64
+	Name  string
+	Count int64
+}
+
+// trackTag is a row of the track_tags join table of schema.Track.Tags
+type trackTag struct {
+	TrackID uint64
+	TagID   uint64
+}
+
+type tagRepository struct {
+	DB *database.Database
+}
+
+type TagRepository interface {
+	ListTagCounts(ownerID uint64, search string, limit int) (tags []TagCount, err error)
+	ListTrackTags(ownerID uint64, trackIDs []uint64) (tags map[uint64][]schema.Tag, err error)
+	CountTracks(ids []uint64) (count int64, err error)
+	UpdateTrackTags(ownerID uint64, trackIDs []uint64, add []string, remove []string) (err error)
+}
+
+func NewTagRepository(db *database.Database) TagRepository {
+	return &tagRepository{
+		DB: db,
+	}
+}
+
+// ListTagCounts returns the tags of an owner attached to at least one track, the most used first
+func (_i *tagRepository) ListTagCounts(ownerID uint64, search string, limit int) (tags []TagCount, err error) {
+	query := _i.DB.DB.Model(&schema.Track{}).
+		Select("tags.id AS id, tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN track_tags ON track_tags.track_id = tracks.id").
+		Joins("JOIN tags ON tags.id = track_tags.tag_id AND tags.deleted_at IS NULL").
+		Where("tags.owner_id = ?", ownerID)
+
+	if search != "" {
+		query = query.Where("tags.name LIKE ?", "%"+search+"%")
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	err = query.Group("tags.id, tags.name").Order("count DESC, tags.name ASC").Scan(&tags).Error
+
+	return
+}
+
+// ListTrackTags returns the tags of an owner per track, ordered by name
+func (_i *tagRepository) ListTrackTags(ownerID uint64, trackIDs []uint64) (tags map[uint64][]schema.Tag, err error) {
+	tags = make(map[uint64][]schema.Tag, len(trackIDs))
+	if len(trackIDs) == 0 {
+		return tags, nil
+	}
+
+	var rows []struct {
+		TrackID uint64
+		ID      uint64
+		Name    string
+	}
+	err = _i.DB.DB.Model(&schema.Tag{}).
+		Select("track_tags.track_id AS track_id, tags.id AS id, tags.name AS name").
+		Joins("JOIN track_tags ON track_tags.tag_id = tags.id").
+		Where("tags.owner_id = ? AND track_tags.track_id IN ?", ownerID, trackIDs).
+		Order("tags.name ASC").
+		Scan(&rows).Error
+
+	for _, row := range rows {
+		tags[row.TrackID] = append(tags[row.TrackID], schema.Tag{ID: row.ID, OwnerID: ownerID, Name: row.Name})
+	}
+
+	return tags, err
+}
+
+// CountTracks counts the existing tracks among ids
+func (_i *tagRepository) CountTracks(ids []uint64) (count int64, err error) {
+	err = _i.DB.DB.Model(&schema.Track{}).Where("id IN ?", ids).Count(&count).Error
+
+	return
+}
+
+// UpdateTrackTags attaches the add tags of an owner to every track, creating missing tags, then
+// detaches the remove tags. Removed tags no longer attached to any track are deleted.
+func (_i *tagRepository) UpdateTrackTags(ownerID uint64, trackIDs []uint64, add []string, remove []string) (err error) {
+	return _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		if len(add) > 0 {
+			tags := make([]schema.Tag, 0, len(add))
+			for _, name := range add {
+				tags = append(tags, schema.Tag{OwnerID: ownerID, Name: name})
+			}
+
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+				return err
+			}
+
+			// the created IDs are unreliable when some tags already existed
+			var tagIDs []uint64
+			if err := tx.Model(&schema.Tag{}).Where("owner_id = ? AND name IN ?", ownerID, add).Pluck("id", &tagIDs).Error; err != nil {
+				return err
+			}
+
+			rows := make([]trackTag, 0, len(trackIDs)*len(tagIDs))
+			for _, trackID := range trackIDs {
+				for _, tagID := range tagIDs {
+					rows = append(rows, trackTag{TrackID: trackID, TagID: tagID})
+				}
+			}
+
+			if err := tx.Table("track_tags").Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(remove) == 0 {
+			return nil
+		}
+
+		if err := tx.Exec(
+			"DELETE FROM track_tags WHERE track_id IN ? AND tag_id IN (SELECT id FROM tags WHERE owner_id = ? AND name IN ?)",
+			trackIDs, ownerID, remove,
+		).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().
+			Where("owner_id = ? AND name IN ?", ownerID, remove).
+			Where("NOT EXISTS (SELECT 1 FROM track_tags WHERE track_tags.tag_id = tags.id)").
+			Delete(&schema.Tag{}).Error
+	})
+}
@@ -0,0 +1,21 @@
+package request
+
+// TagsRequest filters the tag cloud, Limit keeps the most used tags
+type TagsRequest struct {
+	Search string `query:"search"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+// TrackTagsRequest lists tags to attach to a track. Names are trimmed and compared case-insensitively,
+// commas are not allowed as they separate the tags of the tags filter.
+type TrackTagsRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=50,dive,required,max=64,excludesall=0x2C" example:"wedding set"`
+}
+
+// BulkTagsRequest attaches and detaches tags on several tracks at once. A tag in both lists ends
+// up detached.
+type BulkTagsRequest struct {
+	TrackIDs []uint64 `json:"track_ids" validate:"required,min=1,max=500"`
+	Add      []string `json:"add" validate:"omitempty,max=50,dive,required,max=64,excludesall=0x2C"`
+	Remove   []string `json:"remove" validate:"omitempty,max=50,dive,required,max=64,excludesall=0x2C"`
+}
@@ -0,0 +1,36 @@
+package response
+
+import "git.dev.siap.id/kukuhkkh/app-music/app/module/tag/repository"
+
+// TagResponse is a tag of the tag cloud, Count is the number of tracks it is attached to
+type TagResponse struct {
+	ID    uint64 `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// TrackTagsResponse lists the tags of a track after a change
+type TrackTagsResponse struct {
+	TrackID uint64   `json:"track_id"`
+	Tags    []string `json:"tags"`
+}
+
+// BulkTagsResponse summarizes a bulk change
+type BulkTagsResponse struct {
+	Tracks  int      `json:"tracks"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func FromTagCountList(tags []repository.TagCount) []TagResponse {
+	res := make([]TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, TagResponse{
+			ID:    tag.ID,
+			Name:  tag.Name,
+			Count: tag.Count,
+		})
+	}
+
+	return res
+}
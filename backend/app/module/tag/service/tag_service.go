@@ -0,0 +1,170 @@
+package service
+
+import (
+	"slices"
+	"strings"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/response"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type tagService struct {
+	repo repository.TagRepository
+	cfg  *config.Config
+}
+
+type TagService interface {
+	GetTags(userID uint64, req request.TagsRequest) (tags []response.TagResponse, err error)
+	AddTrackTags(trackID uint64, userID uint64, req request.TrackTagsRequest) (res *response.TrackTagsResponse, err error)
+	RemoveTrackTag(trackID uint64, userID uint64, name string) (res *response.TrackTagsResponse, err error)
+	UpdateTags(userID uint64, req request.BulkTagsRequest) (res *response.BulkTagsResponse, err error)
+	Owner(userID uint64) uint64
+	ListTrackTags(userID uint64, trackIDs []uint64) (tags map[uint64][]schema.Tag, err error)
+}
+
+func NewTagService(repo repository.TagRepository, cfg *config.Config) TagService {
+	return &tagService{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// Owner returns the owner of the tags a user sees: the user, or 0 when tags are shared
+func (s *tagService) Owner(userID uint64) uint64 {
+	if s.cfg.Track.UserTags.Shared {
+		return 0
+	}
+
+	return userID
+}
+
+func (s *tagService) GetTags(userID uint64, req request.TagsRequest) ([]response.TagResponse, error) {
+	tags, err := s.repo.ListTagCounts(s.Owner(userID), strings.TrimSpace(req.Search), req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.FromTagCountList(tags), nil
+}
+
+// ListTrackTags returns the tags a user sees on each of the tracks
+func (s *tagService) ListTrackTags(userID uint64, trackIDs []uint64) (map[uint64][]schema.Tag, error) {
+	return s.repo.ListTrackTags(s.Owner(userID), trackIDs)
+}
+
+func (s *tagService) AddTrackTags(trackID uint64, userID uint64, req request.TrackTagsRequest) (*response.TrackTagsResponse, error) {
+	names := Names(req.Tags)
+	if len(names) == 0 {
+		return nil, errNoTags
+	}
+
+	if err := s.updateTags(userID, []uint64{trackID}, names, nil); err != nil {
+		return nil, err
+	}
+
+	return s.trackTags(trackID, userID)
+}
+
+func (s *tagService) RemoveTrackTag(trackID uint64, userID uint64, name string) (*response.TrackTagsResponse, error) {
+	names := Names([]string{name})
+	if len(names) == 0 {
+		return nil, errNoTags
+	}
+
+	if err := s.updateTags(userID, []uint64{trackID}, nil, names); err != nil {
+		return nil, err
+	}
+
+	return s.trackTags(trackID, userID)
+}
+
+func (s *tagService) UpdateTags(userID uint64, req request.BulkTagsRequest) (*response.BulkTagsResponse, error) {
+	trackIDs := slices.Compact(slices.Sorted(slices.Values(req.TrackIDs)))
+	remove := Names(req.Remove)
+	add := slices.DeleteFunc(Names(req.Add), func(name string) bool {
+		return containsName(remove, name)
+	})
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, errNoTags
+	}
+
+	if err := s.updateTags(userID, trackIDs, add, remove); err != nil {
+		return nil, err
+	}
+
+	return &response.BulkTagsResponse{
+		Tracks:  len(trackIDs),
+		Added:   add,
+		Removed: remove,
+	}, nil
+}
+
+// updateTags checks that all tracks exist before changing their tags. Tags label the library as
+// a user sees it, so any track can be tagged, not only the ones the user uploaded.
+func (s *tagService) updateTags(userID uint64, trackIDs []uint64, add []string, remove []string) error {
+	count, err := s.repo.CountTracks(trackIDs)
+	if err != nil {
+		return err
+	}
+
+	if count != int64(len(trackIDs)) {
+		return &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Track not found",
+		}
+	}
+
+	return s.repo.UpdateTrackTags(s.Owner(userID), trackIDs, add, remove)
+}
+
+func (s *tagService) trackTags(trackID uint64, userID uint64) (*response.TrackTagsResponse, error) {
+	tags, err := s.ListTrackTags(userID, []uint64{trackID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.TrackTagsResponse{
+		TrackID: trackID,
+		Tags:    TagNames(tags[trackID]),
+	}, nil
+}
+
+var errNoTags = &uresponse.Error{
+	Code:    fiber.StatusUnprocessableEntity,
+	Message: "No tags to add or remove",
+}
+
+// Names normalizes tag names: surrounding and repeated spaces are removed and names differing
+// only in case are kept once, in the spelling first given
+func Names(values []string) []string {
+	var names []string
+	for _, value := range values {
+		name := strings.Join(strings.Fields(value), " ")
+		if name != "" && !containsName(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// TagNames returns the names of tags, never nil
+func TagNames(tags []schema.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	return slices.ContainsFunc(names, func(n string) bool {
+		return strings.EqualFold(n, name)
+	})
+}
@@ -0,0 +1,52 @@
+package tag
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/controller"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag/service"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+)
+
+type TagRouter struct {
+	App        fiber.Router
+	Controller *controller.Controller
+}
+
+var NewTagModule = fx.Options(
+	// register repository of tag module
+	fx.Provide(repository.NewTagRepository),
+
+	// register service of tag module
+	fx.Provide(service.NewTagService),
+
+	// register controller of tag module
+	fx.Provide(controller.NewController),
+
+	// register router of tag module
+	fx.Provide(NewTagRouter),
+)
+
+func NewTagRouter(fiber *fiber.App, controller *controller.Controller) *TagRouter {
+	return &TagRouter{
+		App:        fiber,
+		Controller: controller,
+	}
+}
+
+func (_i *TagRouter) RegisterTagRoutes() {
+	// define controllers
+	tagController := _i.Controller.Tag
+
+	// define routes
+	_i.App.Route("/tags", func(router fiber.Router) {
+		router.Get("", middleware.Protected(), tagController.GetTags)
+		router.Post("/bulk", middleware.Protected(), tagController.UpdateTags)
+	})
+
+	_i.App.Route("/music/:id/tags", func(router fiber.Router) {
+		router.Post("", middleware.Protected(), tagController.AddTrackTags)
+		router.Delete("/:name", middleware.Protected(), tagController.RemoveTrackTag)
+	})
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"mime/multipart"
 	"strconv"
 
 	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
@@ -12,6 +13,25 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+var allowedArtworkTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+var allowedAudioTypes = map[string]bool{
+	"audio/mpeg":   true,
+	"audio/wav":    true,
+	"audio/ogg":    true,
+	"audio/flac":   true,
+	"audio/x-m4a":  true,
+	"audio/mp4":    true,
+	"audio/aac":    true,
+	"audio/midi":   true,
+	"audio/x-midi": true,
+	"audio/webm":   true,
+}
+
 type trackController struct {
 	trackService service.TrackService
 }
@@ -22,6 +42,17 @@ type TrackController interface {
 	Create(c *fiber.Ctx) error
 	Update(c *fiber.Ctx) error
 	Delete(c *fiber.Ctx) error
+	UpdateArtwork(c *fiber.Ctx) error
+	UpdateFile(c *fiber.Ctx) error
+	GetVersions(c *fiber.Ctx) error
+	RollbackVersion(c *fiber.Ctx) error
+	GetWaveform(c *fiber.Ctx) error
+	Stream(c *fiber.Ctx) error
+	Preview(c *fiber.Ctx) error
+	Like(c *fiber.Ctx) error
+	Unlike(c *fiber.Ctx) error
+	SetRating(c *fiber.Ctx) error
+	ClearRating(c *fiber.Ctx) error
 }
 
 func NewTrackController(trackService service.TrackService) TrackController {
@@ -32,20 +63,42 @@ func NewTrackController(trackService service.TrackService) TrackController {
 
 // GetTracks godoc
 // @Summary      Get paginated tracks
-// @Description  Get list of tracks with search and pagination
+// @Description  Get list of tracks with search, BPM, key, year, genre and tag filters, sorting and pagination
 // @Tags         Music
 // @Accept       json
 // @Produce      json
-// @Param        search query string false "Search by title or artist"
-// @Param        page   query int    false "Page number"
-// @Param        limit  query int    false "Items per page"
+// @Param        search    query string false "Search by title or artist"
+// @Param        bpm_min   query number false "Minimum BPM"
+// @Param        bpm_max   query number false "Maximum BPM"
+// @Param        key       query string false "Comma separated keys, e.g. Am,C"
+// @Param        year      query int    false "Release year"
+// @Param        genre     query string false "Genre"
+// @Param        tags      query string false "Comma separated tag names"
+// @Param        tags_mode query string false "or (default): tracks with any of the tags, and: tracks with all of them"
+// @Param        rating_min    query number false "Minimum average rating"
+// @Param        rating_max    query number false "Maximum average rating"
+// @Param        my_rating_min query number false "Minimum rating of the current user"
+// @Param        my_rating_max query number false "Maximum rating of the current user"
+// @Param        sort      query string false "created_at, title, artist, album, duration, bpm, key, track_number, disc_number, year, release_date, genre, composer, label, isrc, rating (average) or my_rating, prefix with - for descending. album and disc_number keep album play order"
+// @Param        page      query int    false "Page number"
+// @Param        limit     query int    false "Items per page"
 // @Success      200 {object} response.Response
 // @Router       /music [get]
 func (_i *trackController) GetTracks(c *fiber.Ctx) error {
 	p, _ := paginator.Paginate(c)
-	search := c.Query("search")
 
-	tracks, p, err := _i.trackService.GetPaginatedTracks(search, p)
+	req := new(request.TrackPaginationRequest)
+	if err := c.QueryParser(req); err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid query parameters",
+		}
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	tracks, p, err := _i.trackService.GetPaginatedTracks(*req, claims.UserID, p)
 	if err != nil {
 		return err
 	}
@@ -72,7 +125,10 @@ func (_i *trackController) GetTrackByID(c *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := _i.trackService.GetTrackByID(uint64(id))
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.trackService.GetTrackByID(uint64(id), claims.UserID)
 	if err != nil {
 		return err
 	}
@@ -89,11 +145,26 @@ func (_i *trackController) GetTrackByID(c *fiber.Ctx) error {
 // @Tags         Music
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        title    formData string true  "Track Title"
-// @Param        artist   formData string false "Artist Name"
-// @Param        album    formData string false "Album Name"
-// @Param        duration formData int    false "Duration in seconds"
-// @Param        file     formData file   true  "Audio File"
+// @Param        title        formData string true  "Track Title"
+// @Param        artist       formData string false "Artist Name"
+// @Param        album        formData string false "Album Name"
+// @Param        album_artist formData string false "Album artist, defaults to the album artist tag or the track artist"
+// @Param        duration     formData int    false "Duration in seconds"
+// @Param        track_number formData int    false "Track number, 1 to 999"
+// @Param        track_total  formData int    false "Number of tracks on the disc"
+// @Param        disc_number  formData int    false "Disc number, 1 to 999"
+// @Param        disc_total   formData int    false "Number of discs"
+// @Param        release_date formData string false "Release date as YYYY, YYYY-MM or YYYY-MM-DD"
+// @Param        genre        formData string false "Genre"
+// @Param        composer     formData string false "Composer"
+// @Param        isrc         formData string false "ISRC, e.g. USRC17607839"
+// @Param        label        formData string false "Record label"
+// @Param        comment      formData string false "Comment"
+// @Param        file         formData file   true  "Audio File"
+// @Param        artwork      formData file   false "Cover image, used when the file has no embedded artwork"
+// @Param        cue          formData file   false "CUE sheet splitting the file into virtual tracks"
+// @Param        flac         formData bool   false "Losslessly compress WAV uploads to FLAC, overrides the server default"
+// @Param        Idempotency-Key header string false "Repeats with the same key replay the first response"
 // @Success      201 {object} response.Response
 // @Security     Bearer
 // @Router       /music [post]
@@ -102,9 +173,10 @@ func (_i *trackController) Create(c *fiber.Ctx) error {
 	claims := userToken.Claims.(*middleware.JWTClaims)
 
 	req := request.CreateTrackRequest{
-		Title:  c.FormValue("title"),
-		Artist: c.FormValue("artist"),
-		Album:  c.FormValue("album"),
+		Title:       c.FormValue("title"),
+		Artist:      c.FormValue("artist"),
+		Album:       c.FormValue("album"),
+		AlbumArtist: c.FormValue("album_artist"),
 	}
 
 	if d := c.FormValue("duration"); d != "" {
@@ -118,33 +190,35 @@ func (_i *trackController) Create(c *fiber.Ctx) error {
 		req.Duration = di
 	}
 
-	fileHeader, err := c.FormFile("file")
+	metadata, err := formMetadata(c)
 	if err != nil {
-		return &response.Error{
-			Code:    fiber.StatusBadRequest,
-			Message: "Missing file",
-		}
+		return err
 	}
+	req.TrackMetadata = metadata
 
-	allowedMimeTypes := map[string]bool{
-		"audio/mpeg":   true,
-		"audio/wav":    true,
-		"audio/ogg":    true,
-		"audio/flac":   true,
-		"audio/x-m4a":  true,
-		"audio/mp4":    true,
-		"audio/aac":    true,
-		"audio/midi":   true,
-		"audio/x-midi": true,
-		"audio/webm":   true,
+	encodeFLAC, err := formFLAC(c)
+	if err != nil {
+		return err
 	}
+	req.EncodeFLAC = encodeFLAC
 
-	contentType := fileHeader.Header.Get("Content-Type")
-	if !allowedMimeTypes[contentType] {
-		return &response.Error{
-			Code:    fiber.StatusBadRequest,
-			Message: "File type not allowed. Only audio files are permitted.",
+	fileHeader, err := audioFormFile(c)
+	if err != nil {
+		return err
+	}
+
+	if artwork, err := c.FormFile("artwork"); err == nil {
+		if !allowedArtworkTypes[artwork.Header.Get("Content-Type")] {
+			return &response.Error{
+				Code:    fiber.StatusBadRequest,
+				Message: "Artwork type not allowed. Only JPEG, PNG and GIF images are permitted.",
+			}
 		}
+		req.Artwork = artwork
+	}
+
+	if cue, err := c.FormFile("cue"); err == nil {
+		req.CueSheet = cue
 	}
 
 	res, err := _i.trackService.CreateTrack(c.Context(), req, claims.UserID, fileHeader)
@@ -161,12 +235,13 @@ func (_i *trackController) Create(c *fiber.Ctx) error {
 
 // Update godoc
 // @Summary      Update track metadata
-// @Description  Update track title, artist and album
+// @Description  Update track title, artist, album, tag metadata and artist credits. Omitted fields, including title, artist and album, are kept; an empty string or 0 clears a metadata field. With write_tags (or track.tags.write_back) the tags of the stored audio file, metadata included, are rewritten too
 // @Tags         Music
 // @Accept       json
 // @Produce      json
 // @Param        id   path uint64 true "Track ID"
 // @Param        body body request.UpdateTrackRequest true "Track Metadata"
+// @Param        Idempotency-Key header string false "Repeats with the same key replay the first response"
 // @Success      200 {object} response.Response
 // @Security     Bearer
 // @Router       /music/{id} [put]
@@ -184,7 +259,11 @@ func (_i *trackController) Update(c *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := _i.trackService.UpdateTrack(uint64(id), *req, claims.UserID)
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.trackService.UpdateTrack(c.Context(), uint64(id), *req, claims.UserID)
 	if err != nil {
 		return err
 	}
@@ -202,6 +281,7 @@ func (_i *trackController) Update(c *fiber.Ctx) error {
 // @Accept       json
 // @Produce      json
 // @Param        id   path uint64 true "Track ID"
+// @Param        Idempotency-Key header string false "Repeats with the same key replay the first response"
 // @Success      200 {object} response.Response
 // @Security     Bearer
 // @Router       /music/{id} [delete]
@@ -223,3 +303,447 @@ func (_i *trackController) Delete(c *fiber.Ctx) error {
 		Messages: response.Messages{"Delete track success"},
 	})
 }
+
+// Like godoc
+// @Summary      Like track
+// @Description  Add a track to the liked songs of the current user, liking it again changes nothing
+// @Tags         Music
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Track ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/like [post]
+func (_i *trackController) Like(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.trackService.LikeTrack(uint64(id), claims.UserID); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Like track success"},
+	})
+}
+
+// Unlike godoc
+// @Summary      Unlike track
+// @Description  Remove a track from the liked songs of the current user
+// @Tags         Music
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Track ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/like [delete]
+func (_i *trackController) Unlike(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	if err := _i.trackService.UnlikeTrack(uint64(id), claims.UserID); err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Unlike track success"},
+	})
+}
+
+// SetRating godoc
+// @Summary      Rate track
+// @Description  Rate a track from 0 to 5 stars for the current user, replacing their previous rating. Half stars are accepted when track.ratings.half_stars is enabled. Returns the ratings of the track
+// @Tags         Music
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Track ID"
+// @Param        body body request.RatingRequest true "Rating"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/rating [put]
+func (_i *trackController) SetRating(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	req := new(request.RatingRequest)
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+
+	if err := response.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	res, err := _i.trackService.SetRating(uint64(id), claims.UserID, *req)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Rate track success"},
+		Data:     res,
+	})
+}
+
+// ClearRating godoc
+// @Summary      Clear track rating
+// @Description  Remove the rating the current user gave a track. Returns the ratings of the track
+// @Tags         Music
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Track ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/rating [delete]
+func (_i *trackController) ClearRating(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.trackService.ClearRating(uint64(id), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Clear track rating success"},
+		Data:     res,
+	})
+}
+
+// UpdateArtwork godoc
+// @Summary      Replace track artwork
+// @Description  Replace the artwork of a track with an uploaded image
+// @Tags         Music
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id   path     uint64 true "Track ID"
+// @Param        file formData file   true "Cover image (JPEG, PNG or GIF)"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/artwork [put]
+func (_i *trackController) UpdateArtwork(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Missing file",
+		}
+	}
+
+	if !allowedArtworkTypes[fileHeader.Header.Get("Content-Type")] {
+		return &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Artwork type not allowed. Only JPEG, PNG and GIF images are permitted.",
+		}
+	}
+
+	res, err := _i.trackService.ReplaceArtwork(c.Context(), uint64(id), claims.UserID, fileHeader)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Update track artwork success"},
+		Data:     res,
+	})
+}
+
+// UpdateFile godoc
+// @Summary      Replace track audio file
+// @Description  Upload a new audio file for a track, keeping its ID. The previous file is kept as a version, title, artist, album and tags are read from the new file and analysis runs again. Tracks split by a CUE sheet cannot be replaced (409)
+// @Tags         Music
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id   path     uint64 true  "Track ID"
+// @Param        file formData file   true  "Audio File"
+// @Param        flac formData bool   false "Losslessly compress WAV uploads to FLAC, overrides the server default"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/file [put]
+func (_i *trackController) UpdateFile(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	var req request.ReplaceFileRequest
+	if req.EncodeFLAC, err = formFLAC(c); err != nil {
+		return err
+	}
+
+	fileHeader, err := audioFormFile(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := _i.trackService.ReplaceFile(c.Context(), uint64(id), claims.UserID, req, fileHeader)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Update track file success"},
+		Data:     res,
+	})
+}
+
+// GetVersions godoc
+// @Summary      List track file versions
+// @Description  List the previous audio files of a track, newest first
+// @Tags         Music
+// @Accept       json
+// @Produce      json
+// @Param        id   path uint64 true "Track ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/versions [get]
+func (_i *trackController) GetVersions(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.trackService.GetVersions(uint64(id), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get track versions success"},
+		Data:     res,
+	})
+}
+
+// RollbackVersion godoc
+// @Summary      Roll back track file
+// @Description  Make a previous audio file current again, the replaced file becomes a version
+// @Tags         Music
+// @Accept       json
+// @Produce      json
+// @Param        id        path uint64 true "Track ID"
+// @Param        versionId path uint64 true "Version ID"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/versions/{versionId}/rollback [post]
+func (_i *trackController) RollbackVersion(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	versionID, err := c.ParamsInt("versionId")
+	if err != nil {
+		return err
+	}
+
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(*middleware.JWTClaims)
+
+	res, err := _i.trackService.RollbackVersion(c.Context(), uint64(id), uint64(versionID), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Rollback track file success"},
+		Data:     res,
+	})
+}
+
+// GetWaveform godoc
+// @Summary      Get track waveform
+// @Description  Get min/max peak pairs of a track, optionally limited to one resolution
+// @Tags         Music
+// @Accept       json
+// @Produce      json
+// @Param        id         path  uint64 true  "Track ID"
+// @Param        resolution query int    false "Number of peaks, one of the configured resolutions"
+// @Success      200 {object} response.Response
+// @Security     Bearer
+// @Router       /music/{id}/waveform [get]
+func (_i *trackController) GetWaveform(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	res, err := _i.trackService.GetWaveform(c.Context(), uint64(id), c.QueryInt("resolution"))
+	if err != nil {
+		return err
+	}
+
+	// waveform artifacts never change for a stored file
+	c.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+
+	return response.Resp(c, response.Response{
+		Messages: response.Messages{"Get track waveform success"},
+		Data:     res,
+	})
+}
+
+// Stream godoc
+// @Summary      Stream track audio
+// @Description  Redirect to the stored audio file. CUE tracks, and silence trims when enabled, are cut from the file on the fly
+// @Tags         Music
+// @Produce      octet-stream
+// @Param        id   path uint64 true "Track ID"
+// @Success      200 {file} binary
+// @Success      302
+// @Security     Bearer
+// @Router       /music/{id}/stream [get]
+func (_i *trackController) Stream(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	stream, err := _i.trackService.StreamTrack(c.Context(), uint64(id))
+	if err != nil {
+		return err
+	}
+
+	if stream.RedirectURL != "" {
+		return c.Redirect(stream.RedirectURL, fiber.StatusFound)
+	}
+
+	c.Set(fiber.HeaderContentType, stream.MimeType)
+	return c.SendStream(stream.Body)
+}
+
+// Preview godoc
+// @Summary      Get track preview clip
+// @Description  Redirect to a short clip of the track, cut on frame boundaries for MP3 and AAC and on samples for WAV
+// @Tags         Music
+// @Produce      octet-stream
+// @Param        id     path  uint64 true  "Track ID"
+// @Param        start  query int    false "Clip start in seconds, rounded down to the configured snap step"
+// @Param        length query int    false "Clip length in seconds, defaults to the configured preview length, rounded up to the snap step"
+// @Success      302
+// @Security     Bearer
+// @Router       /music/{id}/preview [get]
+func (_i *trackController) Preview(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	stream, err := _i.trackService.GetPreview(c.Context(), uint64(id), c.QueryInt("start"), c.QueryInt("length"))
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(stream.RedirectURL, fiber.StatusFound)
+}
+
+// formMetadata reads and validates the tag metadata fields of a multipart upload, absent fields stay nil
+func formMetadata(c *fiber.Ctx) (request.TrackMetadata, error) {
+	var m request.TrackMetadata
+
+	for name, field := range map[string]**string{
+		"release_date": &m.ReleaseDate,
+		"genre":        &m.Genre,
+		"composer":     &m.Composer,
+		"isrc":         &m.ISRC,
+		"label":        &m.Label,
+		"comment":      &m.Comment,
+	} {
+		if v := c.FormValue(name); v != "" {
+			*field = &v
+		}
+	}
+
+	for name, field := range map[string]**int{
+		"track_number": &m.TrackNumber,
+		"track_total":  &m.TrackTotal,
+		"disc_number":  &m.DiscNumber,
+		"disc_total":   &m.DiscTotal,
+	} {
+		v := c.FormValue(name)
+		if v == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return m, &response.Error{
+				Code:    fiber.StatusBadRequest,
+				Message: "Invalid " + name,
+			}
+		}
+		*field = &n
+	}
+
+	return m, response.ValidateStruct(m)
+}
+
+// formFLAC parses the optional flac form flag, nil when it is absent
+func formFLAC(c *fiber.Ctx) (*bool, error) {
+	v := c.FormValue("flac")
+	if v == "" {
+		return nil, nil
+	}
+
+	encode, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid flac flag",
+		}
+	}
+
+	return &encode, nil
+}
+
+// audioFormFile returns the uploaded audio file after checking its content type
+func audioFormFile(c *fiber.Ctx) (*multipart.FileHeader, error) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Missing file",
+		}
+	}
+
+	if !allowedAudioTypes[fileHeader.Header.Get("Content-Type")] {
+		return nil, &response.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "File type not allowed. Only audio files are permitted.",
+		}
+	}
+
+	return fileHeader, nil
+}
@@ -0,0 +1,138 @@
+package hook
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+)
+
+const (
+	clamAVChunkSize      = 64 << 10
+	clamAVDefaultTimeout = 60 * time.Second
+)
+
+// ClamAV scans uploads with a clamd daemon through its INSTREAM command
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+func NewClamAV(cfg *config.Config) *ClamAV {
+	conf := cfg.Track.Hooks.ClamAV
+
+	// "unix:/path/to/clamd.ctl" or "tcp:host:port", a bare value is a unix socket path
+	network, address, ok := strings.Cut(conf.Address, ":")
+	if !ok {
+		network, address = "unix", conf.Address
+	}
+
+	timeout := clamAVDefaultTimeout
+	if conf.TimeoutSeconds > 0 {
+		timeout = time.Duration(conf.TimeoutSeconds) * time.Second
+	}
+
+	return &ClamAV{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+func (h *ClamAV) Name() string {
+	return "clamav"
+}
+
+func (h *ClamAV) BeforeStore(ctx context.Context, in *Ingest) error {
+	if h.address == "" {
+		return errors.New("track.hooks.clamav.address is not set")
+	}
+
+	result, err := h.scan(ctx, in.File)
+	if err != nil {
+		return err
+	}
+
+	if signature, found := strings.CutSuffix(result, " FOUND"); found {
+		return Reject("File rejected by virus scan: %s", signature)
+	}
+
+	if result != "OK" {
+		return fmt.Errorf("clamd: %s", result)
+	}
+
+	in.Attach("clamav", result)
+	return nil
+}
+
+func (h *ClamAV) AfterStore(ctx context.Context, in *Ingest) error {
+	return nil
+}
+
+// scan streams r to clamd and returns its verdict, "OK" or "<signature> FOUND"
+func (h *ClamAV) scan(ctx context.Context, r io.Reader) (string, error) {
+	dialer := net.Dialer{Timeout: h.timeout}
+	conn, err := dialer.DialContext(ctx, h.network, h.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(h.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+
+	w := bufio.NewWriterSize(conn, clamAVChunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, clamAVChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := binary.Write(w, binary.BigEndian, uint32(n)); err != nil {
+				return "", err
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return "", err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// a zero length chunk ends the stream
+	if err := binary.Write(w, binary.BigEndian, uint32(0)); err != nil {
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", err
+	}
+
+	reply = strings.TrimRight(reply, "\x00\n")
+	result, _ := strings.CutPrefix(reply, "stream: ")
+
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// GroupTag is the fx value group ingest hooks are registered in
+const GroupTag = `group:"ingest_hooks"`
+
+// Ingest is an audio file entering the library. Hooks may change Title, Artist and Album,
+// attach data stored with the track, or reject the file by returning an error.
+type Ingest struct {
+	UserID   uint64
+	Filename string
+	MimeType string
+	Size     int64
+	// File is rewound before every hook
+	File io.ReadSeeker
+
+	Title  string
+	Artist string
+	Album  string
+
+	// Track is the stored and analyzed track, nil before the file is stored
+	Track *schema.Track
+
+	Data map[string]string
+}
+
+// Attach stores a value with the track under key
+func (in *Ingest) Attach(key string, value string) {
+	if in.Data == nil {
+		in.Data = make(map[string]string)
+	}
+
+	in.Data[key] = value
+}
+
+// IngestHook validates or enriches uploads. BeforeStore runs before the file is uploaded to
+// storage, AfterStore once it is stored and analyzed but before the track is saved.
+type IngestHook interface {
+	Name() string
+	BeforeStore(ctx context.Context, in *Ingest) error
+	AfterStore(ctx context.Context, in *Ingest) error
+}
+
+// MetadataHook is implemented by ingest hooks that also check metadata edited without a new file.
+// Ingest then has no File and Track is the track before the edit.
+type MetadataHook interface {
+	CheckMetadata(ctx context.Context, in *Ingest) error
+}
+
+// AsIngestHook annotates the constructor of a hook so fx adds it to the ingest hooks
+func AsIngestHook(constructor any) any {
+	return fx.Annotate(constructor, fx.As(new(IngestHook)), fx.ResultTags(GroupTag))
+}
+
+// Reject returns the error a hook uses to refuse a file, it is reported to the client as 422
+func Reject(format string, args ...any) error {
+	return &uresponse.Error{
+		Code:    fiber.StatusUnprocessableEntity,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Chain runs the hooks enabled in track.hooks.enabled, in the configured order
+type Chain struct {
+	hooks []IngestHook
+}
+
+func NewChain(cfg *config.Config, hooks []IngestHook) *Chain {
+	registered := make(map[string]IngestHook, len(hooks))
+	for _, h := range hooks {
+		if _, ok := registered[h.Name()]; ok {
+			log.Printf("[hook] duplicate ingest hook %q ignored", h.Name())
+			continue
+		}
+		registered[h.Name()] = h
+	}
+
+	chain := &Chain{}
+	for _, name := range cfg.Track.Hooks.Enabled {
+		h, ok := registered[name]
+		if !ok {
+			log.Printf("[hook] unknown ingest hook %q in track.hooks.enabled", name)
+			continue
+		}
+		chain.hooks = append(chain.hooks, h)
+	}
+
+	return chain
+}
+
+func (c *Chain) BeforeStore(ctx context.Context, in *Ingest) error {
+	return c.run(ctx, in, "before store", IngestHook.BeforeStore)
+}
+
+func (c *Chain) AfterStore(ctx context.Context, in *Ingest) error {
+	return c.run(ctx, in, "after store", IngestHook.AfterStore)
+}
+
+// CheckMetadata runs the hooks that implement MetadataHook
+func (c *Chain) CheckMetadata(ctx context.Context, in *Ingest) error {
+	return c.run(ctx, in, "check metadata", func(h IngestHook, ctx context.Context, in *Ingest) error {
+		if m, ok := h.(MetadataHook); ok {
+			return m.CheckMetadata(ctx, in)
+		}
+
+		return nil
+	})
+}
+
+func (c *Chain) run(ctx context.Context, in *Ingest, phase string, call func(IngestHook, context.Context, *Ingest) error) error {
+	if c == nil {
+		return nil
+	}
+
+	for _, h := range c.hooks {
+		if err := rewind(in); err != nil {
+			return err
+		}
+
+		if err := call(h, ctx, in); err != nil {
+			if _, ok := err.(*uresponse.Error); ok {
+				log.Printf("[hook] %s %s rejected name=%s: %v", h.Name(), phase, in.Filename, err)
+				return err
+			}
+
+			return fmt.Errorf("ingest hook %s %s: %w", h.Name(), phase, err)
+		}
+	}
+
+	return rewind(in)
+}
+
+func rewind(in *Ingest) error {
+	if in.File == nil {
+		return nil
+	}
+
+	_, err := in.File.Seek(0, io.SeekStart)
+	return err
+}
@@ -0,0 +1,101 @@
+package hook
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+)
+
+// MaxDuration rejects tracks longer than track.hooks.max_duration.seconds
+type MaxDuration struct {
+	limit time.Duration
+}
+
+func NewMaxDuration(cfg *config.Config) *MaxDuration {
+	return &MaxDuration{
+		limit: time.Duration(cfg.Track.Hooks.MaxDuration.Seconds) * time.Second,
+	}
+}
+
+func (h *MaxDuration) Name() string {
+	return "max_duration"
+}
+
+func (h *MaxDuration) BeforeStore(ctx context.Context, in *Ingest) error {
+	return nil
+}
+
+// AfterStore checks the analyzed duration, the one sent by the client cannot be trusted
+func (h *MaxDuration) AfterStore(ctx context.Context, in *Ingest) error {
+	if h.limit <= 0 || in.Track == nil {
+		return nil
+	}
+
+	duration := time.Duration(in.Track.Duration) * time.Second
+	if in.Track.DurationMs != nil {
+		duration = time.Duration(*in.Track.DurationMs) * time.Millisecond
+	}
+
+	if duration > h.limit {
+		return Reject("Track is longer than the allowed %s", h.limit)
+	}
+
+	return nil
+}
+
+// WordFilter rejects tracks whose title, artist or album contains one of the
+// track.hooks.word_filter.words, matched as whole words regardless of case
+type WordFilter struct {
+	pattern *regexp.Regexp
+}
+
+func NewWordFilter(cfg *config.Config) *WordFilter {
+	var words []string
+	for _, word := range cfg.Track.Hooks.WordFilter.Words {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+	}
+
+	if len(words) == 0 {
+		return &WordFilter{}
+	}
+
+	return &WordFilter{
+		pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`),
+	}
+}
+
+func (h *WordFilter) Name() string {
+	return "word_filter"
+}
+
+func (h *WordFilter) BeforeStore(ctx context.Context, in *Ingest) error {
+	if h.pattern == nil {
+		return nil
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"title", in.Title},
+		{"artist", in.Artist},
+		{"album", in.Album},
+	} {
+		if h.pattern.MatchString(field.value) {
+			return Reject("The %s contains a forbidden word", field.name)
+		}
+	}
+
+	return nil
+}
+
+func (h *WordFilter) AfterStore(ctx context.Context, in *Ingest) error {
+	return nil
+}
+
+// CheckMetadata applies the filter to a renamed title, artist or album
+func (h *WordFilter) CheckMetadata(ctx context.Context, in *Ingest) error {
+	return h.BeforeStore(ctx, in)
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"gorm.io/gorm/clause"
+)
+
+// RatingStats sums up the ratings of a track, Mine is the rating of the requesting user
+type RatingStats struct {
+	TrackID uint64
+	Mine    *float64
+	Average *float64
+	Count   int64
+}
+
+// TrackRef identifies a track when ratings are imported from another library
+type TrackRef struct {
+	ID         uint64
+	Title      string
+	Artist     string
+	ISRC       *string
+	SourcePath *string
+}
+
+// SaveRatings creates ratings, a user rating a track again replaces the stars and the rating date
+func (_i *trackRepository) SaveRatings(ratings []schema.Rating) (err error) {
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	return _i.DB.DB.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "track_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"stars", "updated_at"}),
+	}).Create(&ratings).Error
+}
+
+func (_i *trackRepository) DeleteRating(userID uint64, trackID uint64) (err error) {
+	return _i.DB.DB.Unscoped().Where("user_id = ? AND track_id = ?", userID, trackID).Delete(&schema.Rating{}).Error
+}
+
+// DeleteTrackRatings removes the ratings of a deleted track and its CUE segments
+func (_i *trackRepository) DeleteTrackRatings(trackID uint64) (err error) {
+	segments := _i.DB.DB.Unscoped().Model(&schema.Track{}).Select("id").Where("parent_id = ?", trackID)
+
+	return _i.DB.DB.Unscoped().
+		Where("track_id = ? OR track_id IN (?)", trackID, segments).
+		Delete(&schema.Rating{}).Error
+}
+
+// RatingStats averages and counts the ratings of tracks with one query, tracks without ratings
+// are left out
+func (_i *trackRepository) RatingStats(userID uint64, ids []uint64) (stats map[uint64]RatingStats, err error) {
+	stats = make(map[uint64]RatingStats, len(ids))
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	var rows []RatingStats
+	err = _i.DB.DB.Model(&schema.Rating{}).
+		Select("track_id, MAX(CASE WHEN user_id = ? THEN stars END) AS mine, AVG(stars) AS average, COUNT(*) AS count", userID).
+		Where("track_id IN ?", ids).
+		Group("track_id").
+		Scan(&rows).Error
+
+	for _, row := range rows {
+		stats[row.TrackID] = row
+	}
+
+	return stats, err
+}
+
+// ListRatings returns the ratings of a user, or of every user when userID is 0, with their track
+// after afterID in ID order
+func (_i *trackRepository) ListRatings(userID uint64, afterID uint64, limit int) (ratings []schema.Rating, err error) {
+	query := _i.DB.DB.Preload("Track").Where("id > ?", afterID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	err = query.Order("id ASC").Limit(limit).Find(&ratings).Error
+
+	return
+}
+
+// ListTrackRefs returns the fields identifying every track
+func (_i *trackRepository) ListTrackRefs() (refs []TrackRef, err error) {
+	err = _i.DB.DB.Model(&schema.Track{}).
+		Select("id", "title", "artist", "isrc", "source_path").
+		Order("id ASC").
+		Scan(&refs).Error
+
+	return
+}
+
+// UserExists reports whether a user can be rated as, imports check the user ids of their rows
+func (_i *trackRepository) UserExists(id uint64) (exists bool, err error) {
+	var count int64
+	err = _i.DB.DB.Model(&schema.User{}).Where("id = ?", id).Count(&count).Error
+
+	return count > 0, err
+}
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxRuleDepth and maxRuleCount bound the size of a smart playlist rule tree
+	maxRuleDepth = 5
+	maxRuleCount = 50
+)
+
+// kinds of values a rule field holds, they decide the operators a field accepts
+const (
+	ruleText = iota
+	ruleNumber
+	ruleDate
+	ruleTag
+)
+
+type ruleField struct {
+	column string
+	kind   int
+}
+
+// ruleFields maps the track fields smart playlist rules can test to their SQL expressions.
+// duration is in seconds, created_at is the upload time, format is the file extension and tag
+// tests the tags of the playlist owner.
+var ruleFields = map[string]ruleField{
+	"title":        {"tracks.title", ruleText},
+	"artist":       {"tracks.artist", ruleText},
+	"album":        {"tracks.album", ruleText},
+	"genre":        {"tracks.genre", ruleText},
+	"composer":     {"tracks.composer", ruleText},
+	"label":        {"tracks.label", ruleText},
+	"isrc":         {"tracks.isrc", ruleText},
+	"key":          {"tracks.musical_key", ruleText},
+	"release_date": {"tracks.release_date", ruleText},
+	"mime_type":    {"tracks.mime_type", ruleText},
+	"format":       {"LOWER(SUBSTRING_INDEX(tracks.storage_filename, '.', -1))", ruleText},
+	"duration":     {"tracks.duration", ruleNumber},
+	"bpm":          {"tracks.bpm", ruleNumber},
+	"year":         {"tracks.year", ruleNumber},
+	"track_number": {"tracks.track_number", ruleNumber},
+	"disc_number":  {"tracks.disc_number", ruleNumber},
+	"file_size":    {"tracks.file_size", ruleNumber},
+	"loudness":     {"tracks.loudness_integrated", ruleNumber},
+	"user_id":      {"tracks.user_id", ruleNumber},
+	"artist_id":    {"tracks.artist_id", ruleNumber},
+	"album_id":     {"tracks.album_id", ruleNumber},
+	"created_at":   {"tracks.created_at", ruleDate},
+	"tag":          {"", ruleTag},
+}
+
+// ruleOperators lists the operators of each field kind
+var ruleOperators = map[int][]string{
+	ruleText:   {"eq", "ne", "contains", "not_contains", "starts_with", "ends_with", "in"},
+	ruleNumber: {"eq", "ne", "gt", "gte", "lt", "lte", "between"},
+	ruleDate:   {"before", "after", "in_last", "not_in_last"},
+	ruleTag:    {"has", "has_not"},
+}
+
+// CompileRule turns a smart playlist rule tree into a condition on the tracks table. Tag rules
+// test the tags of tagOwner. Dates relative to now, as in "in_last", are resolved at compile time.
+func CompileRule(rule schema.SmartRule, tagOwner uint64) (clause.Expression, error) {
+	c := &ruleCompiler{tagOwner: tagOwner, now: time.Now()}
+
+	return c.compile(rule, 1)
+}
+
+type ruleCompiler struct {
+	tagOwner uint64
+	now      time.Time
+	count    int
+}
+
+func (c *ruleCompiler) compile(rule schema.SmartRule, depth int) (clause.Expression, error) {
+	if c.count++; c.count > maxRuleCount {
+		return nil, fmt.Errorf("rules can have at most %d conditions and groups", maxRuleCount)
+	}
+
+	if !rule.IsGroup() {
+		return c.condition(rule)
+	}
+
+	if depth > maxRuleDepth {
+		return nil, fmt.Errorf("rule groups can be nested at most %d deep", maxRuleDepth)
+	}
+
+	if len(rule.Rules) == 0 {
+		return nil, fmt.Errorf("rule group is empty")
+	}
+
+	exprs := make([]clause.Expression, 0, len(rule.Rules))
+	for _, r := range rule.Rules {
+		expr, err := c.compile(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+
+	switch rule.Match {
+	case "", schema.MatchAll:
+		return clause.And(exprs...), nil
+	case schema.MatchAny:
+		return clause.Or(exprs...), nil
+	}
+
+	return nil, fmt.Errorf("invalid rule match %q, use all or any", rule.Match)
+}
+
+func (c *ruleCompiler) condition(rule schema.SmartRule) (clause.Expression, error) {
+	field, ok := ruleFields[rule.Field]
+	if !ok {
+		return nil, fmt.Errorf("invalid rule field %q", rule.Field)
+	}
+
+	valid := false
+	for _, op := range ruleOperators[field.kind] {
+		valid = valid || op == rule.Operator
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid operator %q for rule field %q, use one of %s",
+			rule.Operator, rule.Field, strings.Join(ruleOperators[field.kind], ", "))
+	}
+
+	var expr clause.Expression
+	var err error
+	switch field.kind {
+	case ruleText:
+		expr, err = textCondition(field.column, rule.Operator, rule.Value)
+	case ruleNumber:
+		expr, err = numberCondition(field.column, rule.Operator, rule.Value)
+	case ruleDate:
+		expr, err = c.dateCondition(field.column, rule.Operator, rule.Value)
+	case ruleTag:
+		expr, err = c.tagCondition(rule.Operator, rule.Value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rule field %q: %w", rule.Field, err)
+	}
+
+	return expr, nil
+}
+
+func textCondition(column string, op string, value any) (clause.Expression, error) {
+	if op == "in" {
+		values, ok := value.([]any)
+		if !ok || len(values) == 0 {
+			return nil, fmt.Errorf("in needs a list of values")
+		}
+
+		texts := make([]string, 0, len(values))
+		for _, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("in needs a list of text values")
+			}
+			texts = append(texts, s)
+		}
+
+		return clause.Expr{SQL: column + " IN ?", Vars: []any{texts}}, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s needs a text value", op)
+	}
+
+	// negations also match tracks without a value
+	switch op {
+	case "eq":
+		return clause.Expr{SQL: column + " = ?", Vars: []any{s}}, nil
+	case "ne":
+		return clause.Expr{SQL: "(" + column + " IS NULL OR " + column + " <> ?)", Vars: []any{s}}, nil
+	case "contains":
+		return clause.Expr{SQL: column + " LIKE ?", Vars: []any{"%" + escapeLike(s) + "%"}}, nil
+	case "not_contains":
+		return clause.Expr{SQL: "(" + column + " IS NULL OR " + column + " NOT LIKE ?)", Vars: []any{"%" + escapeLike(s) + "%"}}, nil
+	case "starts_with":
+		return clause.Expr{SQL: column + " LIKE ?", Vars: []any{escapeLike(s) + "%"}}, nil
+	}
+
+	return clause.Expr{SQL: column + " LIKE ?", Vars: []any{"%" + escapeLike(s)}}, nil
+}
+
+func numberCondition(column string, op string, value any) (clause.Expression, error) {
+	if op == "between" {
+		values, ok := value.([]any)
+		if !ok || len(values) != 2 {
+			return nil, fmt.Errorf("between needs a list of two numbers")
+		}
+
+		low, lowOK := ruleNumberValue(values[0])
+		high, highOK := ruleNumberValue(values[1])
+		if !lowOK || !highOK {
+			return nil, fmt.Errorf("between needs a list of two numbers")
+		}
+
+		return clause.Expr{SQL: column + " BETWEEN ? AND ?", Vars: []any{low, high}}, nil
+	}
+
+	n, ok := ruleNumberValue(value)
+	if !ok {
+		return nil, fmt.Errorf("%s needs a number", op)
+	}
+
+	switch op {
+	case "eq":
+		return clause.Expr{SQL: column + " = ?", Vars: []any{n}}, nil
+	case "ne":
+		return clause.Expr{SQL: "(" + column + " IS NULL OR " + column + " <> ?)", Vars: []any{n}}, nil
+	case "gt":
+		return clause.Expr{SQL: column + " > ?", Vars: []any{n}}, nil
+	case "gte":
+		return clause.Expr{SQL: column + " >= ?", Vars: []any{n}}, nil
+	case "lt":
+		return clause.Expr{SQL: column + " < ?", Vars: []any{n}}, nil
+	}
+
+	return clause.Expr{SQL: column + " <= ?", Vars: []any{n}}, nil
+}
+
+func (c *ruleCompiler) dateCondition(column string, op string, value any) (clause.Expression, error) {
+	var t time.Time
+	switch op {
+	case "in_last", "not_in_last":
+		days, ok := ruleNumberValue(value)
+		if !ok || days < 0 {
+			return nil, fmt.Errorf("%s needs a number of days", op)
+		}
+		t = c.now.Add(-time.Duration(days * float64(24*time.Hour)))
+	default:
+		s, _ := value.(string)
+		date, err := time.ParseInLocation("2006-01-02", s, c.now.Location())
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a date as YYYY-MM-DD", op)
+		}
+		t = date
+	}
+
+	switch op {
+	case "before", "not_in_last":
+		return clause.Expr{SQL: column + " < ?", Vars: []any{t}}, nil
+	case "after":
+		// after a day means from the next day on
+		return clause.Expr{SQL: column + " >= ?", Vars: []any{t.AddDate(0, 0, 1)}}, nil
+	}
+
+	return clause.Expr{SQL: column + " >= ?", Vars: []any{t}}, nil
+}
+
+func (c *ruleCompiler) tagCondition(op string, value any) (clause.Expression, error) {
+	name, ok := value.(string)
+	if !ok || strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("%s needs a tag name", op)
+	}
+
+	tagged := "EXISTS (SELECT 1 FROM track_tags JOIN tags ON tags.id = track_tags.tag_id AND tags.deleted_at IS NULL " +
+		"WHERE track_tags.track_id = tracks.id AND tags.owner_id = ? AND tags.name = ?)"
+	if op == "has_not" {
+		tagged = "NOT " + tagged
+	}
+
+	return clause.Expr{SQL: tagged, Vars: []any{c.tagOwner, strings.Join(strings.Fields(name), " ")}}, nil
+}
+
+// ruleNumberValue reads a JSON number, or a string holding one
+func ruleNumberValue(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+
+	return 0, false
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"gorm.io/gorm/clause"
+)
+
+func TestCompileRule(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	tagged := "EXISTS (SELECT 1 FROM track_tags JOIN tags ON tags.id = track_tags.tag_id AND tags.deleted_at IS NULL " +
+		"WHERE track_tags.track_id = tracks.id AND tags.owner_id = ? AND tags.name = ?)"
+
+	tests := []struct {
+		name string
+		// rule is JSON, like the rules of a playlist request
+		rule string
+		want clause.Expression
+		err  string
+	}{
+		{
+			name: "text equals",
+			rule: `{"field": "artist", "operator": "eq", "value": "Bach"}`,
+			want: clause.Expr{SQL: "tracks.artist = ?", Vars: []any{"Bach"}},
+		},
+		{
+			name: "negation matches missing values",
+			rule: `{"field": "genre", "operator": "ne", "value": "Pop"}`,
+			want: clause.Expr{SQL: "(tracks.genre IS NULL OR tracks.genre <> ?)", Vars: []any{"Pop"}},
+		},
+		{
+			name: "contains escapes wildcards",
+			rule: `{"field": "title", "operator": "contains", "value": "100%_live"}`,
+			want: clause.Expr{SQL: "tracks.title LIKE ?", Vars: []any{`%100\%\_live%`}},
+		},
+		{
+			name: "ends with",
+			rule: `{"field": "album", "operator": "ends_with", "value": "Remastered"}`,
+			want: clause.Expr{SQL: "tracks.album LIKE ?", Vars: []any{"%Remastered"}},
+		},
+		{
+			name: "text in list",
+			rule: `{"field": "format", "operator": "in", "value": ["flac", "wav"]}`,
+			want: clause.Expr{SQL: "LOWER(SUBSTRING_INDEX(tracks.storage_filename, '.', -1)) IN ?", Vars: []any{[]string{"flac", "wav"}}},
+		},
+		{
+			name: "number from string",
+			rule: `{"field": "bpm", "operator": "gte", "value": "120"}`,
+			want: clause.Expr{SQL: "tracks.bpm >= ?", Vars: []any{120.0}},
+		},
+		{
+			name: "number between",
+			rule: `{"field": "year", "operator": "between", "value": [1990, 1999]}`,
+			want: clause.Expr{SQL: "tracks.year BETWEEN ? AND ?", Vars: []any{1990.0, 1999.0}},
+		},
+		{
+			name: "after a day starts the next day",
+			rule: `{"field": "created_at", "operator": "after", "value": "2026-01-31"}`,
+			want: clause.Expr{SQL: "tracks.created_at >= ?", Vars: []any{time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name: "in last days",
+			rule: `{"field": "created_at", "operator": "in_last", "value": 7}`,
+			want: clause.Expr{SQL: "tracks.created_at >= ?", Vars: []any{now.AddDate(0, 0, -7)}},
+		},
+		{
+			name: "tag of the owner",
+			rule: `{"field": "tag", "operator": "has_not", "value": " road   trip "}`,
+			want: clause.Expr{SQL: "NOT " + tagged, Vars: []any{uint64(7), "road trip"}},
+		},
+		{
+			name: "nested groups",
+			rule: `{"match": "any", "rules": [
+				{"field": "artist", "operator": "eq", "value": "A"},
+				{"rules": [
+					{"field": "bpm", "operator": "gt", "value": 100},
+					{"field": "bpm", "operator": "lt", "value": 140}
+				]}
+			]}`,
+			want: clause.Or(
+				clause.Expr{SQL: "tracks.artist = ?", Vars: []any{"A"}},
+				clause.And(
+					clause.Expr{SQL: "tracks.bpm > ?", Vars: []any{100.0}},
+					clause.Expr{SQL: "tracks.bpm < ?", Vars: []any{140.0}},
+				),
+			),
+		},
+		{
+			name: "unknown field",
+			rule: `{"field": "mood", "operator": "eq", "value": "happy"}`,
+			err:  `invalid rule field "mood"`,
+		},
+		{
+			name: "operator of another kind",
+			rule: `{"field": "bpm", "operator": "contains", "value": "1"}`,
+			err:  `invalid operator "contains" for rule field "bpm"`,
+		},
+		{
+			name: "text operator without text",
+			rule: `{"field": "title", "operator": "eq", "value": 5}`,
+			err:  "eq needs a text value",
+		},
+		{
+			name: "between needs two numbers",
+			rule: `{"field": "duration", "operator": "between", "value": [60]}`,
+			err:  "between needs a list of two numbers",
+		},
+		{
+			name: "bad date",
+			rule: `{"field": "created_at", "operator": "before", "value": "15/03/2026"}`,
+			err:  "before needs a date as YYYY-MM-DD",
+		},
+		{
+			name: "negative days",
+			rule: `{"field": "created_at", "operator": "in_last", "value": -1}`,
+			err:  "in_last needs a number of days",
+		},
+		{
+			name: "empty group",
+			rule: `{"match": "all", "rules": []}`,
+			err:  "rule group is empty",
+		},
+		{
+			name: "invalid match",
+			rule: `{"match": "none", "rules": [{"field": "bpm", "operator": "gt", "value": 1}]}`,
+			err:  `invalid rule match "none"`,
+		},
+		{
+			name: "too deep",
+			rule: `{"rules": [{"rules": [{"rules": [{"rules": [{"rules": [{"rules": [
+				{"field": "bpm", "operator": "gt", "value": 1}
+			]}]}]}]}]}]}`,
+			err: "nested at most 5 deep",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rule schema.SmartRule
+			if err := json.Unmarshal([]byte(tt.rule), &rule); err != nil {
+				t.Fatalf("unmarshal rule: %v", err)
+			}
+
+			c := &ruleCompiler{tagOwner: 7, now: now}
+			got, err := c.compile(rule, 1)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("compile() err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("compile() err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compile() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRuleCount(t *testing.T) {
+	rules := make([]schema.SmartRule, maxRuleCount)
+	for i := range rules {
+		rules[i] = schema.SmartRule{Field: "bpm", Operator: "gt", Value: float64(i)}
+	}
+
+	if _, err := CompileRule(schema.SmartRule{Rules: rules[:maxRuleCount-1]}, 0); err != nil {
+		t.Fatalf("CompileRule() with %d conditions err = %v", maxRuleCount-1, err)
+	}
+
+	if _, err := CompileRule(schema.SmartRule{Rules: rules}, 0); err == nil {
+		t.Fatalf("CompileRule() with %d conditions and a group succeeded", maxRuleCount)
+	}
+}
@@ -1,11 +1,85 @@
 package repository
 
 import (
+	"cmp"
+	"strings"
+
 	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
 	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
 	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TrackSortColumns maps the sort fields accepted by PaginateTracks to their columns
+var TrackSortColumns = map[string]string{
+	"created_at":   "created_at",
+	"title":        "title",
+	"artist":       "artist",
+	"duration":     "duration",
+	"bpm":          "bpm",
+	"key":          "musical_key",
+	"album":        "album",
+	"track_number": "track_number",
+	"disc_number":  "disc_number",
+	"year":         "year",
+	"release_date": "release_date",
+	"genre":        "genre",
+	"composer":     "composer",
+	"label":        "label",
+	"isrc":         "isrc",
+	"rating":       avgRatingColumn,
+	"my_rating":    myRatingColumn,
+}
+
+// avgRatingColumn is the average rating of a track, myRatingColumn the rating TrackFilter.Rater
+// gave it
+const (
+	avgRatingColumn = "(SELECT AVG(ratings.stars) FROM ratings WHERE ratings.track_id = tracks.id)"
+	myRatingColumn  = "(SELECT ratings.stars FROM ratings WHERE ratings.track_id = tracks.id AND ratings.user_id = ?)"
 )
 
+// trackSortTies orders tracks sharing a sort value, by default the newest come first.
+// Albums and discs list their tracks in play order.
+var trackSortTies = map[string]string{
+	"album":       "album_id, disc_number IS NULL, disc_number, track_number IS NULL, track_number, segment_start_ms, id",
+	"disc_number": "album_id, track_number IS NULL, track_number, id",
+}
+
+// trackMetadataColumns are the tag fields saved by UpdateTrackMetadata
+var trackMetadataColumns = []string{
+	"track_number", "track_total", "disc_number", "disc_total", "release_date", "year",
+	"genre", "composer", "isrc", "label", "comment",
+}
+
+// TrackFilter narrows and orders the library listing
+type TrackFilter struct {
+	Search string
+	BpmMin *float64
+	BpmMax *float64
+	Keys   []string
+	Year   *int
+	Genre  string
+	// Tags of TagOwner the tracks carry, all of them with AllTags or else any
+	Tags     []string
+	AllTags  bool
+	TagOwner uint64
+	// RatingMin and RatingMax bound the average rating, MyRatingMin and MyRatingMax the rating of
+	// Rater, which the my_rating sort reads too. Tracks without a rating fall outside any bound.
+	RatingMin   *float64
+	RatingMax   *float64
+	MyRatingMin *float64
+	MyRatingMax *float64
+	Rater       uint64
+	// Rule is the rule tree of a smart playlist, its tag rules test the tags of TagOwner
+	Rule *schema.SmartRule
+	// Max caps the matching tracks at the first Max in order, 0 lists all of them
+	Max int
+	// Sort is a key of TrackSortColumns, Desc reverses it
+	Sort string
+	Desc bool
+}
+
 type trackRepository struct {
 	DB *database.Database
 }
@@ -14,10 +88,43 @@ type trackRepository struct {
 type TrackRepository interface {
 	FindTrackByID(id uint64) (track *schema.Track, err error)
 	ListTracks() (tracks []schema.Track, err error)
-	PaginateTracks(search string, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error)
+	FindTracksByIDs(ids []uint64) (tracks []schema.Track, err error)
+	SaveRatings(ratings []schema.Rating) (err error)
+	DeleteRating(userID uint64, trackID uint64) (err error)
+	DeleteTrackRatings(trackID uint64) (err error)
+	RatingStats(userID uint64, ids []uint64) (stats map[uint64]RatingStats, err error)
+	ListRatings(userID uint64, afterID uint64, limit int) (ratings []schema.Rating, err error)
+	ListTrackRefs() (refs []TrackRef, err error)
+	UserExists(id uint64) (exists bool, err error)
+	PaginateTracks(filter TrackFilter, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error)
+	TrackStats(filters map[uint64]TrackFilter) (stats map[uint64]FilterStats, err error)
+	ListTrackIDs(filter TrackFilter) (ids []uint64, err error)
 	CreateTrack(track *schema.Track) (res *schema.Track, err error)
+	CreateTracks(tracks []*schema.Track) (err error)
+	ListSourcePaths(userID uint64, dir string) (paths []string, err error)
 	UpdateTrack(id uint64, track *schema.Track) (res *schema.Track, err error)
+	UpdateTrackMetadata(id uint64, track *schema.Track) (err error)
+	UpdateTrackFile(id uint64, storageFilename string, fileSize int64) (err error)
+	UpdateAnalysisError(id uint64, message *string) (err error)
 	DeleteTrack(id uint64) (err error)
+	ReplaceArtworks(trackID uint64, artworks []schema.TrackArtwork) (old []schema.TrackArtwork, err error)
+	ListAlbumTracks(userID uint64, album string) (tracks []schema.Track, err error)
+	UpdateAlbumGain(userID uint64, album string, gain *float64, peak *float64) (err error)
+	ListTracksMissing(column string, afterID uint64, limit int) (tracks []schema.Track, err error)
+	CreateSegments(segments []schema.Track) (err error)
+	DeleteSegments(parentID uint64) (err error)
+	FindPreview(trackID uint64, startMs int64, lengthMs int64) (preview *schema.TrackPreview, err error)
+	CreatePreview(preview *schema.TrackPreview) (err error)
+	ListPreviews(trackID uint64) (previews []schema.TrackPreview, err error)
+	ListStalePreviews(trackID uint64, keep int) (previews []schema.TrackPreview, err error)
+	DeletePreviews(previews []schema.TrackPreview) (err error)
+	ListVersions(trackID uint64) (versions []schema.TrackVersion, err error)
+	FindVersion(trackID uint64, versionID uint64) (version *schema.TrackVersion, err error)
+	DeleteVersions(versions []schema.TrackVersion) (err error)
+	SwapTrackFile(track *schema.Track, archived *schema.TrackVersion, restored *schema.TrackVersion) (err error)
+	ListSegments(parentID uint64) (segments []schema.Track, err error)
+	Transaction(fn func(tx *gorm.DB) error) (err error)
+	WithTx(tx *gorm.DB) TrackRepository
 }
 
 func NewTrackRepository(db *database.Database) TrackRepository {
@@ -26,25 +133,209 @@ func NewTrackRepository(db *database.Database) TrackRepository {
 	}
 }
 
-func (_i *trackRepository) PaginateTracks(search string, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error) {
-	query := _i.DB.DB.Model(&schema.Track{}).Preload("User")
+// Transaction runs fn in a database transaction, repositories join it through WithTx
+func (_i *trackRepository) Transaction(fn func(tx *gorm.DB) error) (err error) {
+	return _i.DB.DB.Transaction(fn)
+}
+
+// WithTx returns the repository running its queries in the transaction tx
+func (_i *trackRepository) WithTx(tx *gorm.DB) TrackRepository {
+	db := *_i.DB
+	db.DB = tx
+
+	return &trackRepository{
+		DB: &db,
+	}
+}
+
+// PreloadTrack loads what a track response shows: the owner, the artwork and the credits in order
+func PreloadTrack(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("Artworks").Preload("Parent.Artworks").
+		Preload("Credits", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Preload("Credits.Artist")
+}
 
-	if search != "" {
-		s := "%" + search + "%"
-		query = query.Where("title LIKE ? OR artist LIKE ?", s, s)
+func (_i *trackRepository) PaginateTracks(filter TrackFilter, p *paginator.Pagination) (tracks []schema.Track, pagination *paginator.Pagination, err error) {
+	query, err := _i.filterTracks(filter)
+	if err != nil {
+		return
 	}
 
 	if err = query.Count(&p.Count).Error; err != nil {
 		return
 	}
 
-	err = query.Offset(p.Offset).Limit(p.Limit).Order("created_at DESC").Find(&tracks).Error
+	limit := p.Limit
+	if filter.Max > 0 {
+		p.Count = min(p.Count, int64(filter.Max))
+		if limit = min(limit, filter.Max-p.Offset); limit <= 0 {
+			return nil, p, nil
+		}
+	}
+
+	err = query.Scopes(PreloadTrack).Offset(p.Offset).Limit(limit).Order(trackOrder(filter)).Find(&tracks).Error
 
 	return tracks, p, err
 }
 
+// FilterStats counts the tracks matching a filter and sums their duration
+type FilterStats struct {
+	Key        uint64
+	Count      int64
+	DurationMs int64
+}
+
+// TrackStats counts the tracks matching each filter with one query, stats are keyed like the
+// filters
+func (_i *trackRepository) TrackStats(filters map[uint64]TrackFilter) (stats map[uint64]FilterStats, err error) {
+	stats = make(map[uint64]FilterStats, len(filters))
+	if len(filters) == 0 {
+		return stats, nil
+	}
+
+	parts := make([]string, 0, len(filters))
+	args := make([]any, 0, 2*len(filters))
+	for key, filter := range filters {
+		query, err := _i.filterTracks(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		query = query.Select("COALESCE(tracks.duration_ms, tracks.duration * 1000) AS duration_ms")
+		if filter.Max > 0 {
+			query = query.Order(trackOrder(filter)).Limit(filter.Max)
+		}
+
+		parts = append(parts, "(SELECT ? AS `key`, COUNT(*) AS count, COALESCE(SUM(duration_ms), 0) AS duration_ms FROM (?) AS matched)")
+		args = append(args, key, query)
+	}
+
+	var rows []FilterStats
+	err = _i.DB.DB.Raw(strings.Join(parts, " UNION ALL "), args...).Scan(&rows).Error
+
+	for _, row := range rows {
+		stats[row.Key] = row
+	}
+
+	return stats, err
+}
+
+// ListTrackIDs returns the IDs of the tracks matching a filter in its order
+func (_i *trackRepository) ListTrackIDs(filter TrackFilter) (ids []uint64, err error) {
+	query, err := _i.filterTracks(filter)
+	if err != nil {
+		return
+	}
+
+	if filter.Max > 0 {
+		query = query.Limit(filter.Max)
+	}
+
+	err = query.Order(trackOrder(filter)).Pluck("tracks.id", &ids).Error
+
+	return
+}
+
+// filterTracks selects the tracks matching a filter
+func (_i *trackRepository) filterTracks(filter TrackFilter) (*gorm.DB, error) {
+	// CUE parents are listed through their segments
+	query := _i.DB.DB.Model(&schema.Track{}).Where("cue_sheet IS NULL")
+
+	if filter.Search != "" {
+		s := "%" + filter.Search + "%"
+		query = query.Where("(title LIKE ? OR artist LIKE ?)", s, s)
+	}
+
+	if filter.BpmMin != nil {
+		query = query.Where("bpm >= ?", *filter.BpmMin)
+	}
+
+	if filter.BpmMax != nil {
+		query = query.Where("bpm <= ?", *filter.BpmMax)
+	}
+
+	if len(filter.Keys) > 0 {
+		query = query.Where("musical_key IN ?", filter.Keys)
+	}
+
+	if filter.Year != nil {
+		query = query.Where("year = ?", *filter.Year)
+	}
+
+	if filter.Genre != "" {
+		query = query.Where("genre = ?", filter.Genre)
+	}
+
+	if len(filter.Tags) > 0 {
+		tagged := _i.DB.DB.Table("track_tags").
+			Joins("JOIN tags ON tags.id = track_tags.tag_id AND tags.deleted_at IS NULL").
+			Where("track_tags.track_id = tracks.id AND tags.owner_id = ? AND tags.name IN ?", filter.TagOwner, filter.Tags)
+
+		if filter.AllTags {
+			// tag names are unique per owner, so every name matches at most one row
+			query = query.Where("(?) = ?", tagged.Select("COUNT(*)"), len(filter.Tags))
+		} else {
+			query = query.Where("EXISTS (?)", tagged.Select("1"))
+		}
+	}
+
+	if filter.RatingMin != nil {
+		query = query.Where(avgRatingColumn+" >= ?", *filter.RatingMin)
+	}
+
+	if filter.RatingMax != nil {
+		query = query.Where(avgRatingColumn+" <= ?", *filter.RatingMax)
+	}
+
+	if filter.MyRatingMin != nil {
+		query = query.Where(myRatingColumn+" >= ?", filter.Rater, *filter.MyRatingMin)
+	}
+
+	if filter.MyRatingMax != nil {
+		query = query.Where(myRatingColumn+" <= ?", filter.Rater, *filter.MyRatingMax)
+	}
+
+	if filter.Rule != nil {
+		expr, err := CompileRule(*filter.Rule, filter.TagOwner)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(expr)
+	}
+
+	return query, nil
+}
+
+// trackOrder returns the ORDER BY of a filter, the newest tracks come first by default
+func trackOrder(filter TrackFilter) clause.OrderBy {
+	column, ok := TrackSortColumns[filter.Sort]
+	if !ok {
+		return orderBy("created_at DESC, id DESC")
+	}
+
+	// tracks without a value, e.g. not analyzed yet, go last in both directions
+	order := column + " IS NULL, " + column
+	if filter.Desc {
+		order += " DESC"
+	}
+
+	var vars []any
+	if column == myRatingColumn {
+		vars = []any{filter.Rater, filter.Rater}
+	}
+
+	return orderBy(order+", "+cmp.Or(trackSortTies[filter.Sort], "id DESC"), vars...)
+}
+
+// orderBy is an ORDER BY whose columns may bind vars
+func orderBy(sql string, vars ...any) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{SQL: sql, Vars: vars, WithoutParentheses: true}}
+}
+
 func (_i *trackRepository) FindTrackByID(id uint64) (track *schema.Track, err error) {
-	if err := _i.DB.DB.Preload("User").First(&track, id).Error; err != nil {
+	if err := _i.DB.DB.Scopes(PreloadTrack).First(&track, id).Error; err != nil {
 		return nil, err
 	}
 
@@ -52,29 +343,79 @@ func (_i *trackRepository) FindTrackByID(id uint64) (track *schema.Track, err er
 }
 
 func (_i *trackRepository) ListTracks() (tracks []schema.Track, err error) {
-	if err := _i.DB.DB.Preload("User").Find(&tracks).Error; err != nil {
+	if err := _i.DB.DB.Scopes(PreloadTrack).Find(&tracks).Error; err != nil {
 		return nil, err
 	}
 
 	return
 }
 
+// FindTracksByIDs returns the existing tracks among ids, in no particular order
+func (_i *trackRepository) FindTracksByIDs(ids []uint64) (tracks []schema.Track, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	err = _i.DB.DB.Scopes(PreloadTrack).Where("id IN ?", ids).Find(&tracks).Error
+
+	return
+}
+
 func (_i *trackRepository) CreateTrack(track *schema.Track) (res *schema.Track, err error) {
-	if err := _i.DB.DB.Create(&track).Error; err != nil {
+	if err := _i.DB.DB.Omit("Credits.Artist").Create(&track).Error; err != nil {
 		return nil, err
 	}
 
 	return track, nil
 }
 
+// CreateTracks inserts a batch of tracks with their artwork in one transaction
+func (_i *trackRepository) CreateTracks(tracks []*schema.Track) (err error) {
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	return _i.DB.DB.Omit("Credits.Artist").Create(&tracks).Error
+}
+
+// ListSourcePaths returns the source paths of the tracks of a user imported from below dir.
+// Wildcards in dir are not escaped, callers compare the returned paths exactly.
+func (_i *trackRepository) ListSourcePaths(userID uint64, dir string) (paths []string, err error) {
+	err = _i.DB.DB.Model(&schema.Track{}).
+		Where("user_id = ? AND source_path LIKE ?", userID, dir+"%").
+		Pluck("source_path", &paths).Error
+
+	return
+}
+
 func (_i *trackRepository) UpdateTrack(id uint64, track *schema.Track) (res *schema.Track, err error) {
-	if err := _i.DB.DB.Model(&schema.Track{}).Where("id = ?", id).Updates(track).Error; err != nil {
+	if err := _i.DB.DB.Model(&schema.Track{}).Where("id = ?", id).Omit(clause.Associations).Updates(track).Error; err != nil {
 		return nil, err
 	}
 
 	return track, nil
 }
 
+// UpdateTrackMetadata saves the tag fields of the track, nil fields are cleared
+func (_i *trackRepository) UpdateTrackMetadata(id uint64, track *schema.Track) (err error) {
+	return _i.DB.DB.Model(&schema.Track{}).Where("id = ?", id).Select(trackMetadataColumns).Updates(track).Error
+}
+
+// UpdateTrackFile points a track, and the CUE segments sharing its audio, at a new stored file
+func (_i *trackRepository) UpdateTrackFile(id uint64, storageFilename string, fileSize int64) (err error) {
+	return _i.DB.DB.Model(&schema.Track{}).
+		Where("id = ? OR parent_id = ?", id, id).
+		Updates(map[string]any{
+			"storage_filename": storageFilename,
+			"file_size":        fileSize,
+		}).Error
+}
+
+// UpdateAnalysisError stores why the audio of a track could not be decoded, nil clears it
+func (_i *trackRepository) UpdateAnalysisError(id uint64, message *string) (err error) {
+	return _i.DB.DB.Model(&schema.Track{}).Where("id = ?", id).Update("analysis_error", message).Error
+}
+
 func (_i *trackRepository) DeleteTrack(id uint64) (err error) {
 	if err := _i.DB.DB.Delete(&schema.Track{}, id).Error; err != nil {
 		return err
@@ -82,3 +423,172 @@ func (_i *trackRepository) DeleteTrack(id uint64) (err error) {
 
 	return nil
 }
+
+// ReplaceArtworks swaps every artwork row of a track and returns the rows that were removed
+func (_i *trackRepository) ReplaceArtworks(trackID uint64, artworks []schema.TrackArtwork) (old []schema.TrackArtwork, err error) {
+	err = _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("track_id = ?", trackID).Find(&old).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("track_id = ?", trackID).Delete(&schema.TrackArtwork{}).Error; err != nil {
+			return err
+		}
+
+		if len(artworks) == 0 {
+			return nil
+		}
+
+		for i := range artworks {
+			artworks[i].TrackID = trackID
+		}
+
+		return tx.Create(&artworks).Error
+	})
+
+	return old, err
+}
+
+func (_i *trackRepository) ListAlbumTracks(userID uint64, album string) (tracks []schema.Track, err error) {
+	err = _i.DB.DB.Where("user_id = ? AND album = ?", userID, album).Find(&tracks).Error
+
+	return
+}
+
+func (_i *trackRepository) UpdateAlbumGain(userID uint64, album string, gain *float64, peak *float64) (err error) {
+	return _i.DB.DB.Model(&schema.Track{}).
+		Where("user_id = ? AND album = ?", userID, album).
+		Updates(map[string]any{
+			"replaygain_album_gain": gain,
+			"replaygain_album_peak": peak,
+		}).Error
+}
+
+// ListTracksMissing returns a batch of tracks, ordered by ID, whose column is still NULL.
+// CUE segments are skipped, their audio is analyzed through the parent, and so are tracks
+// whose analysis failed before.
+func (_i *trackRepository) ListTracksMissing(column string, afterID uint64, limit int) (tracks []schema.Track, err error) {
+	err = _i.DB.DB.
+		Where(clause.Eq{Column: clause.Column{Name: column}, Value: nil}).
+		Where("parent_id IS NULL AND analysis_error IS NULL").
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&tracks).Error
+
+	return
+}
+
+func (_i *trackRepository) CreateSegments(segments []schema.Track) (err error) {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	return _i.DB.DB.Omit("User", "Artworks", "Parent", "Credits.Artist").Create(&segments).Error
+}
+
+// ListSegments returns the CUE segments of a parent with their credits
+func (_i *trackRepository) ListSegments(parentID uint64) (segments []schema.Track, err error) {
+	err = _i.DB.DB.Preload("Credits").Where("parent_id = ?", parentID).Find(&segments).Error
+
+	return
+}
+
+func (_i *trackRepository) DeleteSegments(parentID uint64) (err error) {
+	return _i.DB.DB.Where("parent_id = ?", parentID).Delete(&schema.Track{}).Error
+}
+
+func (_i *trackRepository) FindPreview(trackID uint64, startMs int64, lengthMs int64) (preview *schema.TrackPreview, err error) {
+	if err := _i.DB.DB.Where("track_id = ? AND start_ms = ? AND length_ms = ?", trackID, startMs, lengthMs).First(&preview).Error; err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (_i *trackRepository) CreatePreview(preview *schema.TrackPreview) (err error) {
+	return _i.DB.DB.Create(preview).Error
+}
+
+// ListPreviews returns the cached previews of a track and of its CUE segments
+func (_i *trackRepository) ListPreviews(trackID uint64) (previews []schema.TrackPreview, err error) {
+	err = _i.DB.DB.
+		Where("track_id = ? OR track_id IN (?)", trackID, _i.DB.DB.Unscoped().Model(&schema.Track{}).Select("id").Where("parent_id = ?", trackID)).
+		Find(&previews).Error
+
+	return
+}
+
+// ListStalePreviews returns the cached previews of a track beyond the newest keep
+func (_i *trackRepository) ListStalePreviews(trackID uint64, keep int) (previews []schema.TrackPreview, err error) {
+	err = _i.DB.DB.Where("track_id = ?", trackID).Order("created_at DESC, id DESC").Offset(keep).Find(&previews).Error
+
+	return
+}
+
+func (_i *trackRepository) DeletePreviews(previews []schema.TrackPreview) (err error) {
+	if len(previews) == 0 {
+		return nil
+	}
+
+	return _i.DB.DB.Unscoped().Delete(&previews).Error
+}
+
+// ListVersions returns the previous files of a track, newest first
+func (_i *trackRepository) ListVersions(trackID uint64) (versions []schema.TrackVersion, err error) {
+	err = _i.DB.DB.Where("track_id = ?", trackID).Order("created_at DESC, id DESC").Find(&versions).Error
+
+	return
+}
+
+func (_i *trackRepository) FindVersion(trackID uint64, versionID uint64) (version *schema.TrackVersion, err error) {
+	if err := _i.DB.DB.Where("track_id = ?", trackID).First(&version, versionID).Error; err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (_i *trackRepository) DeleteVersions(versions []schema.TrackVersion) (err error) {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	return _i.DB.DB.Unscoped().Delete(&versions).Error
+}
+
+// SwapTrackFile stores a track pointing at a new audio file in one transaction: the previous file
+// is archived as a version, a restored version is removed from the list and CUE segments follow
+// the parent file. Every column of the track is written, so cleared analysis values are persisted.
+func (_i *trackRepository) SwapTrackFile(track *schema.Track, archived *schema.TrackVersion, restored *schema.TrackVersion) (err error) {
+	return _i.DB.DB.Transaction(func(tx *gorm.DB) error {
+		if archived != nil {
+			if err := tx.Create(archived).Error; err != nil {
+				return err
+			}
+		}
+
+		if restored != nil {
+			if err := tx.Unscoped().Delete(restored).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&schema.Track{}).
+			Where("id = ?", track.ID).
+			Select("*").
+			Omit("id", "user_id", "created_at", clause.Associations).
+			Updates(track).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&schema.Track{}).
+			Where("parent_id = ?", track.ID).
+			Updates(map[string]any{
+				"storage_filename":  track.StorageFilename,
+				"original_filename": track.OriginalFilename,
+				"file_size":         track.FileSize,
+				"mime_type":         track.MimeType,
+			}).Error
+	})
+}
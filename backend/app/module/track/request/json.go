@@ -1,19 +1,110 @@
 package request
 
+import (
+	"mime/multipart"
+
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	response.RegisterValidation("release_date", func(fl validator.FieldLevel) bool {
+		_, err := audio.ParseReleaseDate(fl.Field().String())
+		return err == nil
+	}, "{0} must be a date formatted as YYYY, YYYY-MM or YYYY-MM-DD")
+
+	response.RegisterValidation("isrc", func(fl validator.FieldLevel) bool {
+		_, err := audio.ParseISRC(fl.Field().String())
+		return err == nil
+	}, "{0} must be a 12 character ISRC, e.g. USRC17607839")
+}
+
 type TrackPaginationRequest struct {
-	Search string `query:"search"`
-	Page   int    `query:"page"`
-	Limit  int    `query:"limit"`
+	Search string   `query:"search"`
+	Page   int      `query:"page"`
+	Limit  int      `query:"limit"`
+	BpmMin *float64 `query:"bpm_min"`
+	BpmMax *float64 `query:"bpm_max"`
+	Key    string   `query:"key"`
+	Year   *int     `query:"year"`
+	Genre  string   `query:"genre"`
+	// Tags lists comma separated tag names, TagsMode "and" requires all of them instead of any
+	Tags     string `query:"tags"`
+	TagsMode string `query:"tags_mode"`
+	// RatingMin and RatingMax bound the average rating, MyRatingMin and MyRatingMax the rating of
+	// the requesting user
+	RatingMin   *float64 `query:"rating_min"`
+	RatingMax   *float64 `query:"rating_max"`
+	MyRatingMin *float64 `query:"my_rating_min"`
+	MyRatingMax *float64 `query:"my_rating_max"`
+	Sort        string   `query:"sort"`
+}
+
+// RatingRequest rates a track from 0 to 5 stars, in steps of 0.5 when track.ratings.half_stars
+// is enabled
+type RatingRequest struct {
+	Stars *float64 `json:"stars" validate:"required,min=0,max=5" example:"4.5"`
+}
+
+// TrackMetadata are the optional tag fields of a track. Omitted fields are left unchanged,
+// an empty string or a 0 clears the field. Numbers count from 1 and cannot exceed their total.
+type TrackMetadata struct {
+	TrackNumber *int    `json:"track_number" form:"track_number" validate:"omitempty,min=0,max=999"`
+	TrackTotal  *int    `json:"track_total" form:"track_total" validate:"omitempty,min=0,max=999"`
+	DiscNumber  *int    `json:"disc_number" form:"disc_number" validate:"omitempty,min=0,max=999"`
+	DiscTotal   *int    `json:"disc_total" form:"disc_total" validate:"omitempty,min=0,max=999"`
+	ReleaseDate *string `json:"release_date" form:"release_date" validate:"omitempty,release_date" example:"1975-11-21"`
+	Genre       *string `json:"genre" form:"genre" validate:"omitempty,max=100"`
+	Composer    *string `json:"composer" form:"composer" validate:"omitempty,max=255"`
+	ISRC        *string `json:"isrc" form:"isrc" validate:"omitempty,isrc" example:"GBUM71029604"`
+	Label       *string `json:"label" form:"label" validate:"omitempty,max=255"`
+	Comment     *string `json:"comment" form:"comment" validate:"omitempty,max=1000"`
 }
+
 type CreateTrackRequest struct {
-	Title    string `form:"title" validate:"required"`
-	Artist   string `form:"artist"`
-	Album    string `form:"album"`
-	Duration int    `form:"duration"`
+	Title       string `form:"title" validate:"required"`
+	Artist      string `form:"artist"`
+	Album       string `form:"album"`
+	AlbumArtist string `form:"album_artist"`
+	Duration    int    `form:"duration"`
+	TrackMetadata
+
+	// Artwork is an optional cover image used when the audio file has no embedded picture
+	Artwork *multipart.FileHeader `form:"artwork" swaggerignore:"true"`
+
+	// CueSheet optionally splits the uploaded file into one virtual track per CUE entry
+	CueSheet *multipart.FileHeader `form:"cue" swaggerignore:"true"`
+
+	// EncodeFLAC overrides the global WAV to FLAC setting for this upload
+	EncodeFLAC *bool `form:"flac"`
+}
+
+// CreditRequest credits an artist on a track, credits are listed in request order per role.
+// Without a primary credit the primary artists are parsed from the artist field.
+type CreditRequest struct {
+	Artist string `json:"artist" validate:"required,max=255"`
+	Role   string `json:"role" validate:"required,oneof=primary featured remixer producer composer" enums:"primary,featured,remixer,producer,composer"`
+}
+
+type ReplaceFileRequest struct {
+	// EncodeFLAC overrides the global WAV to FLAC setting for this upload
+	EncodeFLAC *bool `form:"flac"`
 }
 
+// UpdateTrackRequest changes the fields it contains, omitted fields keep their value
 type UpdateTrackRequest struct {
-	Title  string `json:"title" validate:"required"`
-	Artist string `json:"artist"`
-	Album  string `json:"album"`
+	Title  *string `json:"title" validate:"omitempty,min=1"`
+	Artist *string `json:"artist"`
+	// Album moves the track to another album, an empty string removes it from its album
+	Album *string `json:"album"`
+	// AlbumArtist moves the track to the album of this artist, by default it stays with the
+	// album artist of its current album
+	AlbumArtist string `json:"album_artist"`
+	TrackMetadata
+	// Credits replace the artist credits of the track. Without them the credits are parsed
+	// again from the artist, title and composer when one of those changes.
+	Credits []CreditRequest `json:"credits" validate:"omitempty,max=50,dive"`
+	// WriteTags overrides the configured tag write-back for this update
+	WriteTags *bool `json:"write_tags"`
 }
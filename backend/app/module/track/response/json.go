@@ -1,42 +1,336 @@
 package response
 
 import (
+	"math"
+	"strconv"
+
 	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
 )
 
+// URLResolver resolves a storage filename into its public URL
+type URLResolver interface {
+	GetURL(filename string) string
+}
+
 type TrackResponse struct {
-	ID        uint64      `json:"id"`
-	Title     string      `json:"title"`
-	Artist    string      `json:"artist"`
-	Album     *string     `json:"album"`
-	Duration  int         `json:"duration"`
-	FileSize  int64       `json:"file_size"`
-	MimeType  string      `json:"mime_type"`
-	PublicURL string      `json:"public_url"`
-	CreatedAt string      `json:"created_at"`
-	User      schema.User `json:"user,omitempty"`
-}
-
-func FromTrackSchema(track schema.Track, publicURL string) TrackResponse {
+	ID          uint64           `json:"id"`
+	Title       string           `json:"title"`
+	Artist      string           `json:"artist"`
+	Album       *string          `json:"album"`
+	ArtistID    *uint64          `json:"artist_id"`
+	AlbumID     *uint64          `json:"album_id"`
+	TrackNumber *int             `json:"track_number"`
+	TrackTotal  *int             `json:"track_total"`
+	DiscNumber  *int             `json:"disc_number"`
+	DiscTotal   *int             `json:"disc_total"`
+	ReleaseDate *string          `json:"release_date"`
+	Year        *int             `json:"year"`
+	Genre       *string          `json:"genre"`
+	Composer    *string          `json:"composer"`
+	ISRC        *string          `json:"isrc"`
+	Label       *string          `json:"label"`
+	Comment     *string          `json:"comment"`
+	Credits     []CreditResponse `json:"credits"`
+	// Tags are the tags the requesting user sees, loaded by the list, detail and update endpoints
+	Tags []string `json:"tags,omitempty"`
+	// IsLiked tells whether the requesting user likes the track, LikeCount is only shown to its owner
+	IsLiked     bool              `json:"is_liked"`
+	LikeCount   *int64            `json:"like_count,omitempty"`
+	Rating      RatingResponse    `json:"rating"`
+	Duration    int               `json:"duration"`
+	DurationMs  *int64            `json:"duration_ms"`
+	FileSize    int64             `json:"file_size"`
+	MimeType    string            `json:"mime_type"`
+	PublicURL   string            `json:"public_url"`
+	ArtworkURLs map[string]string `json:"artwork_urls"`
+	Loudness    *LoudnessResponse `json:"loudness"`
+	Segment     *SegmentResponse  `json:"segment"`
+	Tempo       *TempoResponse    `json:"tempo"`
+	Key         *KeyResponse      `json:"key"`
+	Gapless     *GaplessResponse  `json:"gapless"`
+	Trim        *TrimResponse     `json:"trim"`
+	IngestData  map[string]string `json:"ingest_data,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+	User        schema.User       `json:"user,omitempty"`
+}
+
+// RatingResponse is the rating of the requesting user with the average of all ratings, rounded
+// to two decimals. Both are nil for tracks without ratings.
+type RatingResponse struct {
+	Mine    *float64 `json:"mine"`
+	Average *float64 `json:"average"`
+	Count   int64    `json:"count"`
+}
+
+// LoudnessResponse carries EBU R128 values and ReplayGain 2.0 gains for client side normalization
+type LoudnessResponse struct {
+	IntegratedLUFS *float64           `json:"integrated_lufs"`
+	TruePeakDBTP   *float64           `json:"true_peak_dbtp"`
+	RangeLU        *float64           `json:"range_lu"`
+	ReplayGain     ReplayGainResponse `json:"replaygain"`
+}
+
+// SegmentResponse locates a CUE track inside its parent file. EndMs is nil when the
+// segment plays to the end of the file.
+type SegmentResponse struct {
+	ParentID uint64 `json:"parent_id"`
+	StartMs  int64  `json:"start_ms"`
+	EndMs    *int64 `json:"end_ms"`
+}
+
+// CreditResponse is an artist credited on the track, in credit order
+type CreditResponse struct {
+	ArtistID uint64 `json:"artist_id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+type TempoResponse struct {
+	BPM        float64  `json:"bpm"`
+	Confidence *float64 `json:"confidence"`
+}
+
+type KeyResponse struct {
+	Name       string   `json:"name"`
+	Confidence *float64 `json:"confidence"`
+}
+
+// GaplessResponse carries the encoder priming and padding a player skips for gapless playback
+type GaplessResponse struct {
+	SampleRate     *int `json:"sample_rate"`
+	EncoderDelay   int  `json:"encoder_delay"`
+	EncoderPadding int  `json:"encoder_padding"`
+}
+
+// TrimResponse marks where audible content starts and ends, leading and trailing silence excluded
+type TrimResponse struct {
+	StartMs int64 `json:"start_ms"`
+	EndMs   int64 `json:"end_ms"`
+}
+
+// TrackVersionResponse is a previous audio file of a track, ReplacedAt is when it stopped being current
+type TrackVersionResponse struct {
+	ID               uint64 `json:"id"`
+	OriginalFilename string `json:"original_filename"`
+	FileSize         int64  `json:"file_size"`
+	MimeType         string `json:"mime_type"`
+	PublicURL        string `json:"public_url"`
+	ReplacedAt       string `json:"replaced_at"`
+}
+
+type ReplayGainResponse struct {
+	TrackGain *float64 `json:"track_gain"`
+	TrackPeak *float64 `json:"track_peak"`
+	AlbumGain *float64 `json:"album_gain"`
+	AlbumPeak *float64 `json:"album_peak"`
+}
+
+func FromTrackSchema(track schema.Track, storage URLResolver) TrackResponse {
 	return TrackResponse{
-		ID:        track.ID,
-		Title:     track.Title,
-		Artist:    track.Artist,
-		Album:     track.Album,
-		Duration:  track.Duration,
-		FileSize:  track.FileSize,
-		MimeType:  track.MimeType,
-		PublicURL: publicURL,
-		CreatedAt: track.CreatedAt.Format("2006-01-02 15:04:05"),
-		User:      track.User,
+		ID:          track.ID,
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Album:       track.Album,
+		ArtistID:    track.ArtistID,
+		AlbumID:     track.AlbumID,
+		TrackNumber: track.TrackNumber,
+		TrackTotal:  track.TrackTotal,
+		DiscNumber:  track.DiscNumber,
+		DiscTotal:   track.DiscTotal,
+		ReleaseDate: track.ReleaseDate,
+		Year:        track.Year,
+		Genre:       track.Genre,
+		Composer:    track.Composer,
+		ISRC:        track.ISRC,
+		Label:       track.Label,
+		Comment:     track.Comment,
+		Credits:     credits(track),
+		Tags:        tagNames(track.Tags),
+		Duration:    track.Duration,
+		DurationMs:  track.DurationMs,
+		FileSize:    track.FileSize,
+		MimeType:    track.MimeType,
+		PublicURL:   storage.GetURL(track.StorageFilename),
+		ArtworkURLs: artworkURLs(artworks(track), storage),
+		Loudness:    loudness(track),
+		Segment:     segment(track),
+		Tempo:       tempo(track),
+		Key:         key(track),
+		Gapless:     gapless(track),
+		Trim:        trim(track),
+		IngestData:  track.IngestData,
+		CreatedAt:   track.CreatedAt.Format("2006-01-02 15:04:05"),
+		User:        track.User,
 	}
 }
 
-func FromTrackListSchema(tracks []schema.Track, storage interface{ GetURL(string) string }) []TrackResponse {
+func FromTrackListSchema(tracks []schema.Track, storage URLResolver) []TrackResponse {
 	var res []TrackResponse
 	for _, t := range tracks {
-		res = append(res, FromTrackSchema(t, storage.GetURL(t.StorageFilename)))
+		res = append(res, FromTrackSchema(t, storage))
+	}
+
+	return res
+}
+
+// TrackIDs returns the IDs of tracks in order
+func TrackIDs(tracks []TrackResponse) []uint64 {
+	ids := make([]uint64, 0, len(tracks))
+	for _, t := range tracks {
+		ids = append(ids, t.ID)
+	}
+
+	return ids
+}
+
+// FromRating describes the rating of the requesting user and the average of count ratings
+func FromRating(mine *float64, average *float64, count int64) RatingResponse {
+	res := RatingResponse{Mine: mine, Count: count}
+	if average != nil {
+		rounded := math.Round(*average*100) / 100
+		res.Average = &rounded
+	}
+
+	return res
+}
+
+// SetLike marks a track userID likes and shows the like count when they own it
+func SetLike(track *TrackResponse, userID uint64, liked bool, count int64) {
+	track.IsLiked = liked
+	if track.User.ID == userID {
+		track.LikeCount = &count
+	}
+}
+
+func FromTrackVersionListSchema(versions []schema.TrackVersion, storage URLResolver) []TrackVersionResponse {
+	res := make([]TrackVersionResponse, 0, len(versions))
+	for _, v := range versions {
+		res = append(res, TrackVersionResponse{
+			ID:               v.ID,
+			OriginalFilename: v.OriginalFilename,
+			FileSize:         v.FileSize,
+			MimeType:         v.MimeType,
+			PublicURL:        storage.GetURL(v.StorageFilename),
+			ReplacedAt:       v.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
 	}
 
 	return res
 }
+
+// artworkURLs maps every artwork size (in px) to its public URL
+func artworkURLs(artworks []schema.TrackArtwork, storage URLResolver) map[string]string {
+	if len(artworks) == 0 {
+		return nil
+	}
+
+	urls := make(map[string]string, len(artworks))
+	for _, a := range artworks {
+		urls[strconv.Itoa(a.Size)] = storage.GetURL(a.StorageFilename)
+	}
+
+	return urls
+}
+
+// artworks returns the artwork of the track, CUE segments share the artwork of their parent
+func artworks(track schema.Track) []schema.TrackArtwork {
+	if len(track.Artworks) == 0 && track.Parent != nil {
+		return track.Parent.Artworks
+	}
+
+	return track.Artworks
+}
+
+// segment returns nil for tracks that are not cut from a CUE sheet
+func segment(track schema.Track) *SegmentResponse {
+	if track.ParentID == nil {
+		return nil
+	}
+
+	res := &SegmentResponse{
+		ParentID: *track.ParentID,
+		EndMs:    track.SegmentEndMs,
+	}
+	if track.SegmentStartMs != nil {
+		res.StartMs = *track.SegmentStartMs
+	}
+
+	return res
+}
+
+// loudness returns nil for tracks that have not been analyzed yet
+func loudness(track schema.Track) *LoudnessResponse {
+	if track.LoudnessIntegrated == nil {
+		return nil
+	}
+
+	return &LoudnessResponse{
+		IntegratedLUFS: track.LoudnessIntegrated,
+		TruePeakDBTP:   track.LoudnessTruePeak,
+		RangeLU:        track.LoudnessRange,
+		ReplayGain: ReplayGainResponse{
+			TrackGain: track.ReplayGainTrackGain,
+			TrackPeak: track.ReplayGainTrackPeak,
+			AlbumGain: track.ReplayGainAlbumGain,
+			AlbumPeak: track.ReplayGainAlbumPeak,
+		},
+	}
+}
+
+func credits(track schema.Track) []CreditResponse {
+	res := make([]CreditResponse, 0, len(track.Credits))
+	for _, c := range track.Credits {
+		res = append(res, CreditResponse{ArtistID: c.ArtistID, Name: c.Artist.Name, Role: c.Role})
+	}
+
+	return res
+}
+
+func tagNames(tags []schema.Tag) []string {
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+
+	return names
+}
+
+func tempo(track schema.Track) *TempoResponse {
+	if track.Bpm == nil {
+		return nil
+	}
+
+	return &TempoResponse{BPM: *track.Bpm, Confidence: track.BpmConfidence}
+}
+
+func key(track schema.Track) *KeyResponse {
+	if track.Key == nil {
+		return nil
+	}
+
+	return &KeyResponse{Name: *track.Key, Confidence: track.KeyConfidence}
+}
+
+func gapless(track schema.Track) *GaplessResponse {
+	if track.EncoderDelay == nil {
+		return nil
+	}
+
+	res := &GaplessResponse{
+		SampleRate:   track.SampleRate,
+		EncoderDelay: *track.EncoderDelay,
+	}
+	if track.EncoderPadding != nil {
+		res.EncoderPadding = *track.EncoderPadding
+	}
+
+	return res
+}
+
+func trim(track schema.Track) *TrimResponse {
+	if track.TrimStartMs == nil || track.TrimEndMs == nil {
+		return nil
+	}
+
+	return &TrimResponse{StartMs: *track.TrimStartMs, EndMs: *track.TrimEndMs}
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+	"github.com/gofiber/fiber/v2"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+var defaultWaveformResolutions = []int{100, 400, 1800}
+
+const (
+	defaultDurationTolerance = 2
+	defaultSilenceThreshold  = -60.0
+	// analysisTimeout bounds downloading and decoding a whole file
+	analysisTimeout = 10 * time.Minute
+)
+
+// AnalyzeTrack analyzes the stored audio of a track again and returns the updated track. Audio
+// that cannot be decoded is recorded on the track and reported as an error.
+func (s *trackService) AnalyzeTrack(ctx context.Context, id uint64) (*schema.Track, error) {
+	existingTrack, err := s.repo.FindTrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingTrack.IsSegment() {
+		return nil, errSegment
+	}
+
+	if err := s.reanalyze(ctx, existingTrack); err != nil {
+		return nil, err
+	}
+
+	s.refreshAlbumGain(existingTrack.UserID, existingTrack.Album)
+
+	if existingTrack.AnalysisError != nil {
+		return existingTrack, &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "Audio could not be analyzed: " + *existingTrack.AnalysisError,
+		}
+	}
+
+	return existingTrack, nil
+}
+
+func (s *trackService) GetWaveform(ctx context.Context, id uint64, resolution int) (*audio.Waveform, error) {
+	existingTrack, err := s.repo.FindTrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingTrack.IsSegment() {
+		return nil, errSegment
+	}
+
+	// tracks uploaded before waveforms existed are analyzed on first request, tracks that
+	// failed to decode are not tried again
+	if existingTrack.WaveformFilename == nil && existingTrack.AnalysisError == nil {
+		if err := s.reanalyze(ctx, existingTrack); err != nil {
+			return nil, err
+		}
+	}
+
+	if existingTrack.WaveformFilename == nil {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "Waveform is not available for this audio format",
+		}
+	}
+
+	waveform, err := s.loadWaveform(ctx, *existingTrack.WaveformFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolution > 0 {
+		peaks, ok := waveform.Resolutions[strconv.Itoa(resolution)]
+		if !ok {
+			return nil, &uresponse.Error{
+				Code:    fiber.StatusBadRequest,
+				Message: "Unsupported waveform resolution",
+			}
+		}
+
+		waveform.Resolutions = map[string]audio.Peaks{strconv.Itoa(resolution): peaks}
+	}
+
+	return waveform, nil
+}
+
+// reanalyze downloads the stored audio of a track, analyzes it again and persists the result.
+// Concurrent calls for the same track share a single run, which is detached from the context of
+// the caller that started it so the others do not fail when that caller goes away.
+func (s *trackService) reanalyze(ctx context.Context, track *schema.Track) error {
+	res, err, _ := s.analysis.Do(strconv.FormatUint(track.ID, 10), func() (any, error) {
+		ctx, cancel := analysisContext(ctx)
+		defer cancel()
+
+		file, err := storage.Download(ctx, s.storage, track.StorageFilename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		analyzed := *track
+		s.analyzeAudio(ctx, file, &analyzed)
+
+		if _, err := s.repo.UpdateTrack(track.ID, &analyzed); err != nil {
+			return nil, err
+		}
+
+		// Updates skips nil fields, a cleared failure is written on its own
+		if track.AnalysisError != nil && analyzed.AnalysisError == nil {
+			if err := s.repo.UpdateAnalysisError(track.ID, nil); err != nil {
+				return nil, err
+			}
+		}
+
+		return &analyzed, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*track = *res.(*schema.Track)
+	return nil
+}
+
+// analysisContext detaches analysis from the request that started it and gives it its own
+// deadline, long files take longer to decode than a request or an upload may last
+func analysisContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), analysisTimeout)
+}
+
+// analyzeAudio decodes the audio file once and stores the derived artifacts on the track.
+// Unsupported formats and decode errors are logged and recorded in AnalysisError.
+func (s *trackService) analyzeAudio(ctx context.Context, file io.ReadSeeker, track *schema.Track) {
+	track.AnalysisError = nil
+
+	format := audio.FormatOf(track.MimeType, track.OriginalFilename)
+	if d, err := audio.Duration(file, format); err == nil {
+		s.applyDuration(track, d.Milliseconds())
+	} else {
+		log.Printf("[track] duration probe name=%s err=%v", track.StorageFilename, err)
+	}
+
+	gapless, err := audio.ReadGapless(file, format)
+	if err != nil {
+		log.Printf("[track] gapless probe name=%s err=%v", track.StorageFilename, err)
+	}
+	applyGapless(track, gapless)
+
+	dec, err := audio.NewDecoder(file, format)
+	if err != nil {
+		log.Printf("[track] analysis skipped name=%s err=%v", track.StorageFilename, err)
+		setAnalysisError(track, err)
+		return
+	}
+
+	peaks := audio.NewPeakSink(dec.SampleRate(), dec.Channels())
+	meter := audio.NewLoudnessSink(dec.SampleRate(), dec.Channels())
+	tempo := audio.NewTempoSink(dec.SampleRate(), dec.Channels())
+	key := audio.NewKeySink(dec.SampleRate(), dec.Channels())
+	silence := audio.NewSilenceSink(dec.SampleRate(), dec.Channels(), s.silenceThreshold())
+	if err := audio.Process(dec, peaks, meter, tempo, key, silence); err != nil {
+		log.Printf("[track] analysis decode name=%s err=%v", track.StorageFilename, err)
+		setAnalysisError(track, err)
+		return
+	}
+
+	waveform := peaks.Waveform(s.waveformResolutions())
+	if err := s.storeWaveform(ctx, track, waveform); err != nil {
+		log.Printf("[track] store waveform name=%s err=%v", track.StorageFilename, err)
+	}
+
+	// containers without length information fall back to the decoded sample count
+	if track.DurationMs == nil {
+		s.applyDuration(track, waveform.DurationMs)
+	}
+
+	applyLoudness(track, meter.Loudness())
+
+	if bpm, confidence, ok := tempo.Tempo(); ok {
+		track.Bpm, track.BpmConfidence = &bpm, &confidence
+	}
+
+	if k, confidence, ok := key.Key(); ok {
+		name := k.String()
+		track.Key, track.KeyConfidence = &name, &confidence
+	}
+
+	start, end, audible := silence.Bounds()
+	applyTrim(track, start, end, audible, gapless)
+}
+
+// setAnalysisError records why a track could not be decoded, cut to fit its column
+func setAnalysisError(track *schema.Track, err error) {
+	message := err.Error()
+	if len(message) > 255 {
+		message = message[:255]
+	}
+
+	track.AnalysisError = &message
+}
+
+// applyGapless stores the encoder delay and padding, files without gapless info keep them empty
+func applyGapless(track *schema.Track, g *audio.Gapless) {
+	if g == nil || !g.Tagged {
+		return
+	}
+
+	track.EncoderDelay, track.EncoderPadding = &g.Delay, &g.Padding
+	if g.SampleRate > 0 {
+		track.SampleRate = &g.SampleRate
+	}
+}
+
+// applyTrim stores the audible bounds found in the decoded stream on the timeline of the
+// track, which starts after the encoder and decoder delay of lossy files. Tracks without audible
+// content keep their whole length as bounds, so they count as analyzed and nothing is cut.
+func applyTrim(track *schema.Track, start, end time.Duration, audible bool, g *audio.Gapless) {
+	startMs, endMs := start.Milliseconds(), end.Milliseconds()
+	if g != nil && g.SampleRate > 0 {
+		offset := int64(g.Delay+g.DecoderDelay) * 1000 / int64(g.SampleRate)
+		startMs, endMs = max(startMs-offset, 0), max(endMs-offset, 0)
+	}
+
+	if track.DurationMs != nil {
+		endMs = min(endMs, *track.DurationMs)
+	}
+
+	if !audible || endMs <= startMs {
+		if track.DurationMs == nil {
+			return
+		}
+
+		startMs, endMs = 0, *track.DurationMs
+	}
+
+	track.TrimStartMs, track.TrimEndMs = &startMs, &endMs
+}
+
+func (s *trackService) silenceThreshold() float64 {
+	if s.cfg.Track.Silence.ThresholdDB >= 0 {
+		return defaultSilenceThreshold
+	}
+
+	return s.cfg.Track.Silence.ThresholdDB
+}
+
+// applyDuration stores the measured duration and reconciles it with the client supplied seconds
+func (s *trackService) applyDuration(track *schema.Track, ms int64) {
+	track.DurationMs = &ms
+
+	measured := int((ms + 500) / 1000)
+	if track.Duration == 0 {
+		track.Duration = measured
+		return
+	}
+
+	tolerance := s.cfg.Track.Duration.ToleranceSeconds
+	if tolerance <= 0 {
+		tolerance = defaultDurationTolerance
+	}
+
+	diff := track.Duration - measured
+	if diff >= -tolerance && diff <= tolerance {
+		return
+	}
+
+	log.Printf("[track] duration mismatch name=%s client=%ds measured=%dms trust_client=%t",
+		track.StorageFilename, track.Duration, ms, s.cfg.Track.Duration.TrustClient)
+
+	if !s.cfg.Track.Duration.TrustClient {
+		track.Duration = measured
+	}
+}
+
+// applyLoudness copies a measurement onto the track. Silent tracks have no integrated
+// loudness, so their gain is left empty.
+func applyLoudness(track *schema.Track, l *audio.Loudness) {
+	track.LoudnessRange = &l.Range
+	if !math.IsInf(l.TruePeak, 0) {
+		track.LoudnessTruePeak = &l.TruePeak
+		track.ReplayGainTrackPeak = &l.TruePeakLinear
+	}
+
+	if math.IsInf(l.Integrated, 0) {
+		return
+	}
+
+	gain := l.TrackGain()
+	track.LoudnessIntegrated = &l.Integrated
+	track.ReplayGainTrackGain = &gain
+}
+
+// refreshAlbumGain recomputes the ReplayGain album values shared by every analyzed track of an album
+func (s *trackService) refreshAlbumGain(userID uint64, album *string) {
+	if album == nil || *album == "" {
+		return
+	}
+
+	tracks, err := s.repo.ListAlbumTracks(userID, *album)
+	if err != nil {
+		log.Printf("[track] album gain list album=%q err=%v", *album, err)
+		return
+	}
+
+	var measured []audio.Loudness
+	var peak float64
+	for _, t := range tracks {
+		if t.LoudnessIntegrated == nil {
+			continue
+		}
+
+		duration := float64(max(t.Duration, 1))
+		if t.DurationMs != nil && *t.DurationMs > 0 {
+			duration = float64(*t.DurationMs) / 1000
+		}
+
+		measured = append(measured, audio.Loudness{
+			Integrated: *t.LoudnessIntegrated,
+			Duration:   duration,
+		})
+
+		if t.ReplayGainTrackPeak != nil {
+			peak = max(peak, *t.ReplayGainTrackPeak)
+		}
+	}
+
+	var gain, albumPeak *float64
+	if len(measured) > 0 {
+		g := audio.ReplayGainReference - audio.AlbumLoudness(measured)
+		gain, albumPeak = &g, &peak
+	}
+
+	if err := s.repo.UpdateAlbumGain(userID, *album, gain, albumPeak); err != nil {
+		log.Printf("[track] album gain update album=%q err=%v", *album, err)
+	}
+}
+
+func (s *trackService) waveformResolutions() []int {
+	if len(s.cfg.Track.Waveform.Resolutions) == 0 {
+		return defaultWaveformResolutions
+	}
+
+	return s.cfg.Track.Waveform.Resolutions
+}
+
+func (s *trackService) storeWaveform(ctx context.Context, track *schema.Track, waveform *audio.Waveform) error {
+	data, err := json.Marshal(waveform)
+	if err != nil {
+		return err
+	}
+
+	stem := strings.TrimSuffix(track.StorageFilename, filepath.Ext(track.StorageFilename))
+	filename := fmt.Sprintf("waveform/%s.json", stem)
+	if _, err := s.storage.Upload(ctx, filename, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	track.WaveformFilename = &filename
+	return nil
+}
+
+func (s *trackService) loadWaveform(ctx context.Context, filename string) (*audio.Waveform, error) {
+	r, err := s.storage.Open(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	waveform := new(audio.Waveform)
+	if err := json.NewDecoder(r).Decode(waveform); err != nil {
+		return nil, err
+	}
+
+	return waveform, nil
+}
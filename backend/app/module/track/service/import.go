@@ -0,0 +1,156 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"gorm.io/gorm"
+)
+
+// trackNumberPrefix matches numbering such as "01 ", "01 - " or "1." in front of file names
+var trackNumberPrefix = regexp.MustCompile(`^(?:\d{2,3}\s*[-._)]?\s+|\d{1,3}\s*[-._)]\s*)`)
+
+// ImportFile adds an audio file below root on the local filesystem as a track of userID
+func (s *trackService) ImportFile(ctx context.Context, root string, path string, userID uint64) (*response.TrackResponse, error) {
+	track, err := s.PrepareImport(ctx, root, path, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.SaveImports([]*schema.Track{track}); err != nil {
+		return nil, err
+	}
+
+	trackRes := response.FromTrackSchema(*track, s.storage)
+	return &trackRes, nil
+}
+
+// PrepareImport uploads and analyzes a local audio file without saving its track. Title, artist
+// and album come from the tags of the file, missing values from the path below root laid out
+// as artist/album/track.
+func (s *trackService) PrepareImport(ctx context.Context, root string, path string, userID uint64) (*schema.Track, error) {
+	format := audio.FormatOf("", path)
+	if format == audio.FormatUnknown {
+		return nil, fmt.Errorf("%w: %s", audio.ErrUnsupportedFormat, filepath.Ext(path))
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := audio.ReadTags(file)
+	if err != nil {
+		log.Printf("[track] import read tags name=%s err=%v", path, err)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	req := importRequest(root, path, tags)
+	track, err := s.storeTrack(ctx, req, userID, upload{
+		file:     file,
+		filename: filepath.Base(path),
+		mimeType: audio.MimeType(format),
+		size:     info.Size(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	track.SourcePath = &path
+	return track, nil
+}
+
+// SaveImports links prepared tracks to the library and inserts them in one transaction, then
+// refreshes the album gain of their albums. The stored files of the batch are removed when it
+// cannot be saved.
+func (s *trackService) SaveImports(tracks []*schema.Track) error {
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		library := s.library.WithTx(tx)
+		for _, t := range tracks {
+			if err := library.LinkTrack(t, t.AlbumArtist, nil); err != nil {
+				return err
+			}
+		}
+
+		return s.repo.WithTx(tx).CreateTracks(tracks)
+	})
+	if err != nil {
+		for _, t := range tracks {
+			s.discardTrack(t)
+		}
+		return err
+	}
+
+	type albumKey struct {
+		userID uint64
+		album  string
+	}
+
+	refreshed := make(map[albumKey]bool)
+	for _, t := range tracks {
+		if t.Album == nil || *t.Album == "" {
+			continue
+		}
+
+		key := albumKey{t.UserID, *t.Album}
+		if !refreshed[key] {
+			refreshed[key] = true
+			s.refreshAlbumGain(t.UserID, t.Album)
+		}
+	}
+
+	return nil
+}
+
+// DiscardImport removes the stored files of a prepared track that will not be saved
+func (s *trackService) DiscardImport(track *schema.Track) {
+	s.discardTrack(track)
+}
+
+// importRequest fills the track metadata of a local file from its tags, falling back to the
+// directories below root and to the file name
+func importRequest(root string, path string, tags audio.Tags) request.CreateTrackRequest {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	var artist, album string
+	if rel, err := filepath.Rel(root, filepath.Dir(path)); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		dirs := strings.Split(rel, string(filepath.Separator))
+		album = dirs[len(dirs)-1]
+		if len(dirs) > 1 {
+			artist = dirs[len(dirs)-2]
+		}
+	}
+
+	return request.CreateTrackRequest{
+		Title:  cmp.Or(tags.Title, strings.TrimSpace(trackNumberPrefix.ReplaceAllString(name, "")), name),
+		Artist: cmp.Or(tags.Artist, artist),
+		Album:  cmp.Or(tags.Album, album),
+	}
+}
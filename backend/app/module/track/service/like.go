@@ -0,0 +1,91 @@
+package service
+
+import (
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+	"github.com/gofiber/fiber/v2"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// GetTracksByIDs returns the existing tracks among ids in the order of ids, with the tags and
+// likes the user sees
+func (s *trackService) GetTracksByIDs(ids []uint64, userID uint64) ([]response.TrackResponse, error) {
+	schemaTracks, err := s.repo.FindTracksByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint64]*schema.Track, len(schemaTracks))
+	listed := make([]*schema.Track, 0, len(schemaTracks))
+	for i := range schemaTracks {
+		byID[schemaTracks[i].ID] = &schemaTracks[i]
+		listed = append(listed, &schemaTracks[i])
+	}
+
+	if err := s.loadTags(userID, listed...); err != nil {
+		return nil, err
+	}
+
+	res := make([]response.TrackResponse, 0, len(schemaTracks))
+	for _, id := range ids {
+		if track, ok := byID[id]; ok {
+			res = append(res, response.FromTrackSchema(*track, s.storage))
+		}
+	}
+
+	if err := s.loadLikes(userID, res); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadRatings(userID, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *trackService) LikeTrack(id uint64, userID uint64) error {
+	if err := s.findTrack(id); err != nil {
+		return err
+	}
+
+	return s.likes.CreateLike(userID, schema.LikeTrack, id)
+}
+
+// UnlikeTrack removes a like, tracks that are not liked are left as they are
+func (s *trackService) UnlikeTrack(id uint64, userID uint64) error {
+	return s.likes.DeleteLike(userID, schema.LikeTrack, id)
+}
+
+// loadLikes marks the tracks the user likes and counts the likes of the tracks they own
+func (s *trackService) loadLikes(userID uint64, tracks []response.TrackResponse) error {
+	stats, err := s.likes.LikeStats(userID, schema.LikeTrack, response.TrackIDs(tracks))
+	if err != nil {
+		return err
+	}
+
+	for i := range tracks {
+		stat := stats[tracks[i].ID]
+		response.SetLike(&tracks[i], userID, stat.Liked, stat.Count)
+	}
+
+	return nil
+}
+
+// findTrack reports a track that does not exist
+func (s *trackService) findTrack(id uint64) error {
+	tracks, err := s.repo.FindTracksByIDs([]uint64{id})
+	if err != nil {
+		return err
+	}
+
+	if len(tracks) == 0 {
+		return &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Track not found",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/request"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+	"github.com/gofiber/fiber/v2"
+
+	libraryService "git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+)
+
+// maxTagNumber is the largest track or disc number and total accepted, as in the request validation
+const maxTagNumber = 999
+
+// validateNumbers checks what the validation tags of the request cannot: numbers against their totals
+func validateNumbers(track *schema.Track) error {
+	if exceeds(track.TrackNumber, track.TrackTotal) {
+		return &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "Track number cannot exceed the track total",
+		}
+	}
+
+	if exceeds(track.DiscNumber, track.DiscTotal) {
+		return &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "Disc number cannot exceed the disc total",
+		}
+	}
+
+	return nil
+}
+
+func exceeds(n *int, total *int) bool {
+	return n != nil && total != nil && *n > *total
+}
+
+// setMetadata applies the fields set in m to the track, nil fields are left unchanged while empty
+// strings and zero numbers clear the field. The release date and ISRC are stored normalized.
+func setMetadata(track *schema.Track, m request.TrackMetadata) {
+	setNumber(&track.TrackNumber, m.TrackNumber)
+	setNumber(&track.TrackTotal, m.TrackTotal)
+	setNumber(&track.DiscNumber, m.DiscNumber)
+	setNumber(&track.DiscTotal, m.DiscTotal)
+
+	if m.ReleaseDate != nil {
+		track.ReleaseDate, track.Year = nil, nil
+		if date, err := audio.ParseReleaseDate(*m.ReleaseDate); err == nil {
+			releaseDate := date.String()
+			track.ReleaseDate, track.Year = &releaseDate, &date.Year
+		}
+	}
+
+	if m.ISRC != nil {
+		track.ISRC = nil
+		if isrc, err := audio.ParseISRC(*m.ISRC); err == nil {
+			track.ISRC = &isrc
+		}
+	}
+
+	setString(&track.Genre, m.Genre)
+	setString(&track.Composer, m.Composer)
+	setString(&track.Label, m.Label)
+	setString(&track.Comment, m.Comment)
+}
+
+func setNumber(field **int, v *int) {
+	if v == nil {
+		return
+	}
+
+	*field = nil
+	if *v > 0 {
+		n := *v
+		*field = &n
+	}
+}
+
+func setString(field **string, v *string) {
+	if v != nil {
+		*field = optional(*v)
+	}
+}
+
+// tagMetadata fills the fields missing from m with the tags of the file. Tag values the request
+// validation would reject are skipped or shortened.
+func tagMetadata(m request.TrackMetadata, tags audio.Tags) request.TrackMetadata {
+	if m.TrackNumber == nil && m.TrackTotal == nil {
+		m.TrackNumber, m.TrackTotal = tagNumber(tags.TrackNumber, tags.TrackTotal)
+	}
+	if m.DiscNumber == nil && m.DiscTotal == nil {
+		m.DiscNumber, m.DiscTotal = tagNumber(tags.DiscNumber, tags.DiscTotal)
+	}
+
+	releaseDate := tags.ReleaseDate
+	if releaseDate == "" && tags.Year > 0 {
+		releaseDate = strconv.Itoa(tags.Year)
+	}
+	m.ReleaseDate = cmp.Or(m.ReleaseDate, optional(releaseDate))
+
+	m.Genre = cmp.Or(m.Genre, optional(truncate(tags.Genre, 100)))
+	m.Composer = cmp.Or(m.Composer, optional(truncate(tags.Composer, 255)))
+	m.ISRC = cmp.Or(m.ISRC, optional(tags.ISRC))
+	m.Label = cmp.Or(m.Label, optional(truncate(tags.Label, 255)))
+	m.Comment = cmp.Or(m.Comment, optional(truncate(tags.Comment, 1000)))
+
+	return m
+}
+
+// tagNumber returns a track or disc number read from tags, zero values and totals smaller than
+// the number are dropped
+func tagNumber(n int, total int) (*int, *int) {
+	var number, count *int
+	if n > 0 && n <= maxTagNumber {
+		number = &n
+	}
+	if total > 0 && total <= maxTagNumber && (number == nil || n <= total) {
+		count = &total
+	}
+
+	return number, count
+}
+
+// requestCredits converts the credits of an update, nil when the request has none
+func requestCredits(credits []request.CreditRequest) []libraryService.Credit {
+	if credits == nil {
+		return nil
+	}
+
+	res := make([]libraryService.Credit, 0, len(credits))
+	for _, c := range credits {
+		res = append(res, libraryService.Credit{Artist: c.Artist, Role: c.Role})
+	}
+
+	return res
+}
+
+// trackCredits returns the stored credits of a track, nil for tracks linked before credits existed
+func trackCredits(track *schema.Track) []libraryService.Credit {
+	if len(track.Credits) == 0 {
+		return nil
+	}
+
+	res := make([]libraryService.Credit, 0, len(track.Credits))
+	for _, c := range track.Credits {
+		res = append(res, libraryService.Credit{Artist: c.Artist.Name, Role: c.Role})
+	}
+
+	return res
+}
+
+// optional returns nil for blank strings
+func optional(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+
+	return s
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+const (
+	defaultPreviewSeconds     = 30
+	defaultPreviewMaxSeconds  = 60
+	defaultPreviewSnapSeconds = 5
+	defaultPreviewsCached     = 20
+)
+
+// GetPreview returns a clip of length seconds from start seconds into the track. Clips are
+// cut without re-encoding and cached in storage, so repeated requests are served directly.
+// The range is snapped to whole steps so nearby requests share a cached clip.
+func (s *trackService) GetPreview(ctx context.Context, id uint64, start int, length int) (*Stream, error) {
+	existingTrack, err := s.repo.FindTrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSeconds := s.cfg.Track.Preview.MaxSeconds
+	if maxSeconds <= 0 {
+		maxSeconds = defaultPreviewMaxSeconds
+	}
+
+	if length == 0 {
+		length = min(max(s.cfg.Track.Preview.DefaultSeconds, 0), maxSeconds)
+		if length == 0 {
+			length = min(defaultPreviewSeconds, maxSeconds)
+		}
+	}
+
+	if start < 0 || length < 0 || length > maxSeconds {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid preview range, length must be between 1 and %d seconds", maxSeconds),
+		}
+	}
+
+	start, length = s.snapPreview(start, length, maxSeconds)
+	startMs, lengthMs := int64(start)*1000, int64(length)*1000
+	if existingTrack.DurationMs != nil && startMs >= *existingTrack.DurationMs {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Preview starts after the end of the track",
+		}
+	}
+
+	format := audio.FormatOf(existingTrack.MimeType, existingTrack.OriginalFilename)
+	if !audio.CanSlice(format) {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: "Preview is not supported for this audio format",
+		}
+	}
+
+	preview, err := s.repo.FindPreview(id, startMs, lengthMs)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if preview == nil {
+		key := fmt.Sprintf("preview:%d:%d:%d", id, startMs, lengthMs)
+		res, err, _ := s.analysis.Do(key, func() (any, error) {
+			return s.createPreview(ctx, existingTrack, format, startMs, lengthMs)
+		})
+		if err != nil {
+			return nil, err
+		}
+		preview = res.(*schema.TrackPreview)
+	}
+
+	return &Stream{
+		RedirectURL: s.storage.GetURL(preview.StorageFilename),
+		MimeType:    existingTrack.MimeType,
+	}, nil
+}
+
+// createPreview cuts a clip from the stored audio, offsets are relative to the start of a CUE segment
+func (s *trackService) createPreview(ctx context.Context, track *schema.Track, format audio.Format, startMs int64, lengthMs int64) (*schema.TrackPreview, error) {
+	file, err := storage.Download(ctx, s.storage, track.StorageFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	offset, end := segmentBounds(track)
+	from := offset + time.Duration(startMs)*time.Millisecond
+	to := from + time.Duration(lengthMs)*time.Millisecond
+	if end > 0 {
+		to = min(to, end)
+	}
+
+	var clip bytes.Buffer
+	if err := audio.Slice(&clip, file, format, from, to); err != nil {
+		return nil, err
+	}
+
+	preview := &schema.TrackPreview{
+		TrackID:         track.ID,
+		StartMs:         startMs,
+		LengthMs:        lengthMs,
+		StorageFilename: fmt.Sprintf("preview/%d_%ds_%ds%s", track.ID, startMs/1000, lengthMs/1000, filepath.Ext(track.StorageFilename)),
+	}
+
+	if _, err := s.storage.Upload(ctx, preview.StorageFilename, &clip); err != nil {
+		return nil, err
+	}
+
+	// another instance may have cached the same clip, the file is identical either way
+	if err := s.repo.CreatePreview(preview); err != nil {
+		log.Printf("[track] store preview %s err=%v", preview.StorageFilename, err)
+	}
+
+	s.prunePreviews(track.ID)
+
+	return preview, nil
+}
+
+// snapPreview rounds the start of a clip down and its length up to whole snap steps, without
+// making it longer than maxSeconds
+func (s *trackService) snapPreview(start int, length int, maxSeconds int) (int, int) {
+	step := s.cfg.Track.Preview.SnapSeconds
+	if step <= 0 {
+		step = defaultPreviewSnapSeconds
+	}
+
+	start -= start % step
+	if rem := length % step; rem != 0 {
+		length += step - rem
+	}
+
+	return start, min(length, maxSeconds)
+}
+
+// prunePreviews deletes the oldest cached previews of a track beyond the configured number to keep
+func (s *trackService) prunePreviews(trackID uint64) {
+	keep := s.cfg.Track.Preview.MaxCached
+	if keep == 0 {
+		keep = defaultPreviewsCached
+	}
+	if keep < 0 {
+		return
+	}
+
+	previews, err := s.repo.ListStalePreviews(trackID, keep)
+	if err != nil {
+		log.Printf("[track] list previews id=%d err=%v", trackID, err)
+		return
+	}
+
+	s.removePreviews(trackID, previews)
+}
+
+// deletePreviews removes the cached previews of a track and of its CUE segments
+func (s *trackService) deletePreviews(trackID uint64) {
+	previews, err := s.repo.ListPreviews(trackID)
+	if err != nil {
+		log.Printf("[track] list previews id=%d err=%v", trackID, err)
+		return
+	}
+
+	s.removePreviews(trackID, previews)
+}
+
+func (s *trackService) removePreviews(trackID uint64, previews []schema.TrackPreview) {
+	for _, p := range previews {
+		if err := s.storage.Delete(p.StorageFilename); err != nil {
+			log.Printf("[track] delete preview %s err=%v", p.StorageFilename, err)
+		}
+	}
+
+	if err := s.repo.DeletePreviews(previews); err != nil {
+		log.Printf("[track] delete previews id=%d err=%v", trackID, err)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+	"github.com/gofiber/fiber/v2"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// SetRating rates a track for the user, replacing their previous rating, and returns the ratings
+// of the track
+func (s *trackService) SetRating(id uint64, userID uint64, req request.RatingRequest) (*response.RatingResponse, error) {
+	if err := s.validStars(*req.Stars); err != nil {
+		return nil, err
+	}
+
+	if err := s.findTrack(id); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveRatings([]schema.Rating{{UserID: userID, TrackID: id, Stars: *req.Stars}}); err != nil {
+		return nil, err
+	}
+
+	return s.rating(id, userID)
+}
+
+// ClearRating removes the rating of the user and returns the ratings of the track
+func (s *trackService) ClearRating(id uint64, userID uint64) (*response.RatingResponse, error) {
+	if err := s.findTrack(id); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteRating(userID, id); err != nil {
+		return nil, err
+	}
+
+	return s.rating(id, userID)
+}
+
+func (s *trackService) rating(id uint64, userID uint64) (*response.RatingResponse, error) {
+	stats, err := s.repo.RatingStats(userID, []uint64{id})
+	if err != nil {
+		return nil, err
+	}
+
+	stat := stats[id]
+	res := response.FromRating(stat.Mine, stat.Average, stat.Count)
+	return &res, nil
+}
+
+// loadRatings adds the rating of the user and the average rating to the tracks
+func (s *trackService) loadRatings(userID uint64, tracks []response.TrackResponse) error {
+	stats, err := s.repo.RatingStats(userID, response.TrackIDs(tracks))
+	if err != nil {
+		return err
+	}
+
+	for i := range tracks {
+		stat := stats[tracks[i].ID]
+		tracks[i].Rating = response.FromRating(stat.Mine, stat.Average, stat.Count)
+	}
+
+	return nil
+}
+
+// validStars reports ratings that are not whole stars, or half stars when they are enabled
+func (s *trackService) validStars(stars float64) error {
+	step := 1.0
+	if s.cfg.Track.Ratings.HalfStars {
+		step = 0.5
+	}
+
+	if stars < 0 || stars > 5 || math.Mod(stars, step) != 0 {
+		return &uresponse.Error{
+			Code:    fiber.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("Invalid rating %v, ratings go from 0 to 5 in steps of %v", stars, step),
+		}
+	}
+
+	return nil
+}
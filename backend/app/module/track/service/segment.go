@@ -0,0 +1,213 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+	"github.com/gofiber/fiber/v2"
+
+	settingRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/setting/repository"
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// Stream is the audio of a track. Whole files are served from storage through RedirectURL,
+// CUE segments and trimmed tracks are cut on the fly and streamed through Body.
+type Stream struct {
+	RedirectURL string
+	MimeType    string
+	Body        io.ReadCloser
+}
+
+func (s *trackService) StreamTrack(ctx context.Context, id uint64) (*Stream, error) {
+	existingTrack, err := s.repo.FindTrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	format := audio.FormatOf(existingTrack.MimeType, existingTrack.OriginalFilename)
+	if existingTrack.IsSegment() {
+		if !audio.CanSlice(format) {
+			return nil, &uresponse.Error{
+				Code:    fiber.StatusUnprocessableEntity,
+				Message: "Segment streaming is not supported for this audio format",
+			}
+		}
+
+		start, end := segmentBounds(existingTrack)
+		return s.sliceStream(ctx, existingTrack, format, start, end)
+	}
+
+	if start, end, ok := s.streamTrim(existingTrack); ok && audio.CanSlice(format) {
+		return s.sliceStream(ctx, existingTrack, format, start, end)
+	}
+
+	return &Stream{
+		RedirectURL: s.storage.GetURL(existingTrack.StorageFilename),
+		MimeType:    existingTrack.MimeType,
+	}, nil
+}
+
+// streamTrim returns the silence trims of a track when the stream endpoint applies them
+func (s *trackService) streamTrim(track *schema.Track) (start, end time.Duration, ok bool) {
+	if track.TrimStartMs == nil || track.TrimEndMs == nil || !s.trimStream() {
+		return 0, 0, false
+	}
+
+	start = time.Duration(*track.TrimStartMs) * time.Millisecond
+	end = time.Duration(*track.TrimEndMs) * time.Millisecond
+
+	// nothing to cut when the track has no leading or trailing silence
+	if start == 0 && (track.DurationMs == nil || *track.TrimEndMs >= *track.DurationMs) {
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
+
+// sliceStream cuts start to end out of the stored file while it is being sent
+func (s *trackService) sliceStream(ctx context.Context, track *schema.Track, format audio.Format, start, end time.Duration) (*Stream, error) {
+	file, err := storage.Download(ctx, s.storage, track.StorageFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer file.Close()
+
+		err := audio.Slice(pw, file, format, start, end)
+		if err != nil {
+			log.Printf("[track] stream slice id=%d err=%v", track.ID, err)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return &Stream{
+		MimeType: track.MimeType,
+		Body:     pr,
+	}, nil
+}
+
+// readCueSheet parses the CUE sheet uploaded with a track and returns it with its UTF-8 text
+func readCueSheet(fileHeader *multipart.FileHeader) (*audio.CueSheet, string, error) {
+	data, err := readFileHeader(fileHeader)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sheet, err := audio.ParseCue(data)
+	if err != nil {
+		return nil, "", &uresponse.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: err.Error(),
+		}
+	}
+
+	return sheet, audio.CueText(data), nil
+}
+
+// segmentTracks builds one virtual track per CUE entry. Entries starting after the end
+// of the parent file are dropped.
+func segmentTracks(parent *schema.Track, sheet *audio.CueSheet) []schema.Track {
+	album := parent.Album
+	if sheet.Title != "" {
+		album = &sheet.Title
+	}
+
+	total := len(sheet.Tracks)
+	segments := make([]schema.Track, 0, len(sheet.Tracks))
+	for _, t := range sheet.Tracks {
+		startMs := t.Start.Milliseconds()
+		if parent.DurationMs != nil && startMs >= *parent.DurationMs {
+			log.Printf("[track] cue track %d starts after the end of %s", t.Number, parent.StorageFilename)
+			continue
+		}
+
+		segment := schema.Track{
+			UserID:           parent.UserID,
+			Title:            cmp.Or(t.Title, fmt.Sprintf("Track %02d", t.Number)),
+			Artist:           cmp.Or(t.Performer, sheet.Performer, parent.Artist),
+			Album:            album,
+			StorageFilename:  parent.StorageFilename,
+			OriginalFilename: parent.OriginalFilename,
+			FileSize:         parent.FileSize,
+			MimeType:         parent.MimeType,
+			ParentID:         &parent.ID,
+			SegmentStartMs:   &startMs,
+		}
+		segment.TrackNumber, segment.TrackTotal = &t.Number, &total
+		segment.DiscNumber, segment.DiscTotal = parent.DiscNumber, parent.DiscTotal
+		segment.ReleaseDate, segment.Year = parent.ReleaseDate, parent.Year
+		segment.Genre, segment.Label = parent.Genre, parent.Label
+		segment.Composer = optional(t.Songwriter)
+		if isrc, err := audio.ParseISRC(t.ISRC); err == nil {
+			segment.ISRC = &isrc
+		}
+
+		var endMs int64
+		if t.End > 0 {
+			endMs = t.End.Milliseconds()
+			segment.SegmentEndMs = &endMs
+		} else if parent.DurationMs != nil {
+			endMs = *parent.DurationMs
+		}
+
+		if endMs > 0 {
+			durationMs := endMs - startMs
+			segment.DurationMs = &durationMs
+			segment.Duration = int((durationMs + 500) / 1000)
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+// segmentBounds returns the offsets of a CUE segment inside its parent file, end is zero
+// for the last segment
+func segmentBounds(track *schema.Track) (start, end time.Duration) {
+	if track.SegmentStartMs != nil {
+		start = time.Duration(*track.SegmentStartMs) * time.Millisecond
+	}
+	if track.SegmentEndMs != nil {
+		end = time.Duration(*track.SegmentEndMs) * time.Millisecond
+	}
+
+	return start, end
+}
+
+// errSegment rejects operations on the audio of a CUE segment, which belongs to its parent
+var errSegment = &uresponse.Error{
+	Code:    fiber.StatusUnprocessableEntity,
+	Message: "Audio of a CUE track is managed by its parent track",
+}
+
+// errCueParent rejects replacing the file of a CUE parent, the offsets of its segments only
+// hold for the file they were cut from
+var errCueParent = &uresponse.Error{
+	Code:    fiber.StatusConflict,
+	Message: "The file of a track split by a CUE sheet cannot be replaced, upload the new file as a new track",
+}
+
+// trimStream reports whether the stream endpoint cuts silence trims. The value set with the
+// trim-stream admin command wins over track.silence.trim_stream of the config file.
+func (s *trackService) trimStream() bool {
+	value, found, err := s.settings.FindSetting(settingRepository.TrimStream)
+	if err != nil {
+		log.Printf("[track] read setting %s err=%v", settingRepository.TrimStream, err)
+	}
+	if err != nil || !found {
+		return s.cfg.Track.Silence.TrimStream
+	}
+
+	return value == "true"
+}
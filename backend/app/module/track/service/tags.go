@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/helpers"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+)
+
+// shouldWriteTags resolves the configured tag write-back against a per-request override
+func (s *trackService) shouldWriteTags(override *bool) bool {
+	if override != nil {
+		return *override
+	}
+
+	return s.cfg.Track.Tags.WriteBack
+}
+
+// writeTags rewrites the title, artist, album and cover tags of the stored audio file. The tagged
+// copy is uploaded under a new name and swapped in before the old object is deleted, so a failure
+// at any step leaves the track playing from its previous file. CUE segments are skipped, their
+// metadata does not describe the whole file.
+func (s *trackService) writeTags(ctx context.Context, track *schema.Track) error {
+	if track.IsSegment() {
+		return nil
+	}
+
+	start := time.Now()
+	file, err := storage.Download(ctx, s.storage, track.StorageFilename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	format, err := audio.Sniff(file)
+	if err != nil {
+		return err
+	}
+	if !audio.CanWriteTags(format) {
+		log.Printf("[track] tags id=%d format=%s not supported", track.ID, format)
+		return nil
+	}
+
+	tags := audio.Tags{
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Album:       helpers.Value(track.Album),
+		ReleaseDate: helpers.Value(track.ReleaseDate),
+		TrackNumber: helpers.Value(track.TrackNumber),
+		TrackTotal:  helpers.Value(track.TrackTotal),
+		DiscNumber:  helpers.Value(track.DiscNumber),
+		DiscTotal:   helpers.Value(track.DiscTotal),
+		Genre:       helpers.Value(track.Genre),
+		Composer:    helpers.Value(track.Composer),
+		ISRC:        helpers.Value(track.ISRC),
+		Label:       helpers.Value(track.Label),
+		Comment:     helpers.Value(track.Comment),
+	}
+	if tags.Picture, err = s.largestArtwork(ctx, track.Artworks); err != nil {
+		log.Printf("[track] tags id=%d artwork err=%v", track.ID, err)
+	}
+
+	ext := filepath.Ext(track.StorageFilename)
+	tmp, err := storage.NewTempFile("tags-*" + ext)
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+
+	if err := audio.WriteTags(tmp, file, format, tags); err != nil {
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), helpers.Slug(track.Title), ext)
+	if _, err := s.storage.Upload(ctx, filename, tmp); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateTrackFile(track.ID, filename, size); err != nil {
+		if err := s.storage.Delete(filename); err != nil {
+			log.Printf("[track] delete %s err=%v", filename, err)
+		}
+		return err
+	}
+
+	if err := s.storage.Delete(track.StorageFilename); err != nil {
+		log.Printf("[track] delete %s err=%v", track.StorageFilename, err)
+	}
+
+	log.Printf("[track] tags written id=%d name=%s size=%d->%d dur=%s", track.ID, filename, track.FileSize, size, time.Since(start))
+	track.StorageFilename, track.FileSize = filename, size
+
+	return nil
+}
+
+// largestArtwork loads the biggest rendered artwork of a track, nil when it has none
+func (s *trackService) largestArtwork(ctx context.Context, artworks []schema.TrackArtwork) (*audio.Picture, error) {
+	var largest *schema.TrackArtwork
+	for i := range artworks {
+		if largest == nil || artworks[i].Size > largest.Size {
+			largest = &artworks[i]
+		}
+	}
+	if largest == nil {
+		return nil, nil
+	}
+
+	src, err := s.storage.Open(ctx, largest.StorageFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &audio.Picture{MIMEType: "image/jpeg", Data: data}, nil
+}
@@ -1,87 +1,377 @@
 package service
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/hook"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/request"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
 	"git.dev.siap.id/kukuhkkh/app-music/utils/helpers"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/imaging"
 	"git.dev.siap.id/kukuhkkh/app-music/utils/paginator"
 	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/throttle"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+
+	libraryService "git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+	likeRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/like/repository"
+	playlistService "git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/service"
+	settingRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/setting/repository"
+	tagService "git.dev.siap.id/kukuhkkh/app-music/app/module/tag/service"
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+const (
+	artworkSourceEmbedded = "embedded"
+	artworkSourceUpload   = "upload"
 )
 
+var (
+	defaultArtworkSizes   = []int{64, 300, 1000}
+	defaultArtworkQuality = 85
+)
+
+// upload is an audio file entering the library, sent to the API or read from disk
+type upload struct {
+	file     io.ReadSeeker
+	filename string
+	mimeType string
+	size     int64
+	// limited uploads hold an upload slot of their user while the file is sent to storage
+	limited bool
+}
+
 type trackService struct {
-	repo    repository.TrackRepository
-	storage storage.Storage
+	repo      repository.TrackRepository
+	storage   storage.Storage
+	cfg       *config.Config
+	hooks     *hook.Chain
+	uploads   *throttle.Limiter
+	library   libraryService.LibraryService
+	tags      tagService.TagService
+	playlists playlistService.PlaylistService
+	likes     likeRepository.LikeRepository
+	settings  settingRepository.SettingRepository
+	analysis  singleflight.Group
 }
 
 type TrackService interface {
-	GetPaginatedTracks(search string, p *paginator.Pagination) (tracks []response.TrackResponse, pagination *paginator.Pagination, err error)
-	GetTrackByID(id uint64) (track *response.TrackResponse, err error)
+	GetPaginatedTracks(req request.TrackPaginationRequest, userID uint64, p *paginator.Pagination) (tracks []response.TrackResponse, pagination *paginator.Pagination, err error)
+	GetTrackByID(id uint64, userID uint64) (track *response.TrackResponse, err error)
 	CreateTrack(ctx context.Context, req request.CreateTrackRequest, userID uint64, fileHeader *multipart.FileHeader) (track *response.TrackResponse, err error)
-	UpdateTrack(id uint64, req request.UpdateTrackRequest, userID uint64) (track *response.TrackResponse, err error)
+	ImportFile(ctx context.Context, root string, path string, userID uint64) (track *response.TrackResponse, err error)
+	PrepareImport(ctx context.Context, root string, path string, userID uint64) (track *schema.Track, err error)
+	SaveImports(tracks []*schema.Track) (err error)
+	DiscardImport(track *schema.Track)
+	UpdateTrack(ctx context.Context, id uint64, req request.UpdateTrackRequest, userID uint64) (track *response.TrackResponse, err error)
 	DeleteTrack(id uint64, userID uint64) (err error)
+	ReplaceArtwork(ctx context.Context, id uint64, userID uint64, fileHeader *multipart.FileHeader) (track *response.TrackResponse, err error)
+	ReplaceFile(ctx context.Context, id uint64, userID uint64, req request.ReplaceFileRequest, fileHeader *multipart.FileHeader) (track *response.TrackResponse, err error)
+	GetVersions(id uint64, userID uint64) (versions []response.TrackVersionResponse, err error)
+	RollbackVersion(ctx context.Context, id uint64, versionID uint64, userID uint64) (track *response.TrackResponse, err error)
+	GetWaveform(ctx context.Context, id uint64, resolution int) (waveform *audio.Waveform, err error)
+	AnalyzeTrack(ctx context.Context, id uint64) (track *schema.Track, err error)
+	StreamTrack(ctx context.Context, id uint64) (stream *Stream, err error)
+	GetPreview(ctx context.Context, id uint64, start int, length int) (stream *Stream, err error)
+	GetTracksByIDs(ids []uint64, userID uint64) (tracks []response.TrackResponse, err error)
+	LikeTrack(id uint64, userID uint64) (err error)
+	UnlikeTrack(id uint64, userID uint64) (err error)
+	SetRating(id uint64, userID uint64, req request.RatingRequest) (rating *response.RatingResponse, err error)
+	ClearRating(id uint64, userID uint64) (rating *response.RatingResponse, err error)
 }
 
-func NewTrackService(repo repository.TrackRepository, storage storage.Storage) TrackService {
+func NewTrackService(repo repository.TrackRepository, storage storage.Storage, cfg *config.Config, hooks *hook.Chain, library libraryService.LibraryService, tags tagService.TagService, playlists playlistService.PlaylistService, likes likeRepository.LikeRepository, settings settingRepository.SettingRepository) TrackService {
 	return &trackService{
-		repo:    repo,
-		storage: storage,
+		repo:      repo,
+		storage:   storage,
+		cfg:       cfg,
+		hooks:     hooks,
+		uploads:   newUploadLimiter(cfg),
+		library:   library,
+		tags:      tags,
+		playlists: playlists,
+		likes:     likes,
+		settings:  settings,
 	}
 }
 
-func (s *trackService) GetPaginatedTracks(search string, p *paginator.Pagination) (tracks []response.TrackResponse, pagination *paginator.Pagination, err error) {
-	schemaTracks, p, err := s.repo.PaginateTracks(search, p)
+func (s *trackService) GetPaginatedTracks(req request.TrackPaginationRequest, userID uint64, p *paginator.Pagination) (tracks []response.TrackResponse, pagination *paginator.Pagination, err error) {
+	filter := repository.TrackFilter{
+		Search:      req.Search,
+		BpmMin:      req.BpmMin,
+		BpmMax:      req.BpmMax,
+		Year:        req.Year,
+		Genre:       strings.TrimSpace(req.Genre),
+		RatingMin:   req.RatingMin,
+		RatingMax:   req.RatingMax,
+		MyRatingMin: req.MyRatingMin,
+		MyRatingMax: req.MyRatingMax,
+		Rater:       userID,
+	}
+
+	// keys are stored in a single notation, e.g. "Eb minor" is matched as "D#m"
+	for _, name := range strings.Split(req.Key, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+
+		key, err := audio.ParseKey(name)
+		if err != nil {
+			return nil, p, &uresponse.Error{
+				Code:    fiber.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid key %q", name),
+			}
+		}
+		filter.Keys = append(filter.Keys, key.String())
+	}
+
+	if req.Tags != "" {
+		if req.TagsMode != "" && req.TagsMode != "and" && req.TagsMode != "or" {
+			return nil, p, &uresponse.Error{
+				Code:    fiber.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid tags_mode %q", req.TagsMode),
+			}
+		}
+
+		filter.Tags = tagService.Names(strings.Split(req.Tags, ","))
+		filter.AllTags = req.TagsMode == "and"
+		filter.TagOwner = s.tags.Owner(userID)
+	}
+
+	if req.Sort != "" {
+		filter.Sort, filter.Desc = strings.CutPrefix(req.Sort, "-")
+		if _, ok := repository.TrackSortColumns[filter.Sort]; !ok {
+			return nil, p, &uresponse.Error{
+				Code:    fiber.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid sort %q", req.Sort),
+			}
+		}
+	}
+
+	schemaTracks, p, err := s.repo.PaginateTracks(filter, p)
 	if err != nil {
 		return nil, p, err
 	}
 
-	return response.FromTrackListSchema(schemaTracks, s.storage), p, nil
+	listed := make([]*schema.Track, 0, len(schemaTracks))
+	for i := range schemaTracks {
+		listed = append(listed, &schemaTracks[i])
+	}
+
+	if err := s.loadTags(userID, listed...); err != nil {
+		return nil, p, err
+	}
+
+	res := response.FromTrackListSchema(schemaTracks, s.storage)
+	if err := s.loadLikes(userID, res); err != nil {
+		return nil, p, err
+	}
+
+	if err := s.loadRatings(userID, res); err != nil {
+		return nil, p, err
+	}
+
+	return res, p, nil
 }
 
-func (s *trackService) GetTrackByID(id uint64) (track *response.TrackResponse, err error) {
+func (s *trackService) GetTrackByID(id uint64, userID uint64) (track *response.TrackResponse, err error) {
 	schemaTrack, err := s.repo.FindTrackByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	res := response.FromTrackSchema(*schemaTrack, s.storage.GetURL(schemaTrack.StorageFilename))
-	return &res, nil
+	if err := s.loadTags(userID, schemaTrack); err != nil {
+		return nil, err
+	}
+
+	res := []response.TrackResponse{response.FromTrackSchema(*schemaTrack, s.storage)}
+	if err := s.loadLikes(userID, res); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadRatings(userID, res); err != nil {
+		return nil, err
+	}
+
+	return &res[0], nil
+}
+
+// loadTags attaches the tags the user sees to the tracks
+func (s *trackService) loadTags(userID uint64, tracks ...*schema.Track) error {
+	ids := make([]uint64, 0, len(tracks))
+	for _, track := range tracks {
+		ids = append(ids, track.ID)
+	}
+
+	tags, err := s.tags.ListTrackTags(userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, track := range tracks {
+		track.Tags = tags[track.ID]
+	}
+
+	return nil
 }
 
 func (s *trackService) CreateTrack(ctx context.Context, req request.CreateTrackRequest, userID uint64, fileHeader *multipart.FileHeader) (track *response.TrackResponse, err error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return s.createTrack(ctx, req, userID, upload{
+		file:     file,
+		filename: fileHeader.Filename,
+		mimeType: fileHeader.Header.Get("Content-Type"),
+		size:     fileHeader.Size,
+		limited:  true,
+	})
+}
+
+// createTrack stores a new audio file and creates its track, whichever way the file arrived
+func (s *trackService) createTrack(ctx context.Context, req request.CreateTrackRequest, userID uint64, src upload) (track *response.TrackResponse, err error) {
 	start := time.Now()
 	log.Printf("[track] create start user=%d title=%q size=%d ct=%q",
-		userID, req.Title, fileHeader.Size, fileHeader.Header.Get("Content-Type"))
+		userID, req.Title, src.size, src.mimeType)
 
-	file, err := fileHeader.Open()
+	var requested schema.Track
+	setMetadata(&requested, req.TrackMetadata)
+	if err := validateNumbers(&requested); err != nil {
+		return nil, err
+	}
+
+	var sheet *audio.CueSheet
+	var cueText string
+	if req.CueSheet != nil {
+		if sheet, cueText, err = readCueSheet(req.CueSheet); err != nil {
+			return nil, err
+		}
+	}
+
+	newTrack, err := s.storeTrack(ctx, req, userID, src)
 	if err != nil {
 		return nil, err
 	}
+	if sheet != nil {
+		newTrack.CueSheet = &cueText
+	}
 
-	defer file.Close()
+	var res *schema.Track
+	err = s.repo.Transaction(func(tx *gorm.DB) error {
+		library, repo := s.library.WithTx(tx), s.repo.WithTx(tx)
+		if err := library.LinkTrack(newTrack, newTrack.AlbumArtist, nil); err != nil {
+			return err
+		}
+
+		var err error
+		if res, err = repo.CreateTrack(newTrack); err != nil || sheet == nil {
+			return err
+		}
+
+		segments := segmentTracks(res, sheet)
+		for i := range segments {
+			// segments stay on the album of the first primary artist of the file
+			if err := library.LinkTrack(&segments[i], cmp.Or(req.AlbumArtist, sheet.Performer, res.Credits[0].Artist.Name), nil); err != nil {
+				return err
+			}
+		}
+		if err := repo.CreateSegments(segments); err != nil {
+			return err
+		}
+		log.Printf("[track] cue sheet id=%d segments=%d", res.ID, len(segments))
+
+		return nil
+	})
+	if err != nil {
+		s.discardTrack(newTrack)
+		return nil, err
+	}
+
+	s.refreshAlbumGain(res.UserID, res.Album)
+
+	trackRes := response.FromTrackSchema(*res, s.storage)
+	log.Printf("[track] create success id=%d total_dur=%s", res.ID, time.Since(start))
+
+	return &trackRes, nil
+}
+
+// storeTrack uploads the audio file and its artwork and analyzes it. The returned track is not
+// saved yet, its stored files are removed with discardTrack when it cannot be.
+func (s *trackService) storeTrack(ctx context.Context, req request.CreateTrackRequest, userID uint64, src upload) (*schema.Track, error) {
+	start := time.Now()
+
+	ingest := &hook.Ingest{
+		UserID:   userID,
+		Filename: src.filename,
+		MimeType: src.mimeType,
+		Size:     src.size,
+		File:     src.file,
+		Title:    req.Title,
+		Artist:   req.Artist,
+		Album:    req.Album,
+	}
+	if err := s.hooks.BeforeStore(ctx, ingest); err != nil {
+		return nil, err
+	}
+	req.Title, req.Artist, req.Album = ingest.Title, ingest.Artist, ingest.Album
+
+	var audioFile io.ReadSeeker = src.file
+	ext := filepath.Ext(src.filename)
+	mimeType := src.mimeType
+	fileSize := src.size
+
+	if compressed := s.compressWAV(src, req.EncodeFLAC); compressed != nil {
+		defer compressed.Close()
+
+		info, err := compressed.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		audioFile, ext, mimeType, fileSize = compressed, ".flac", mimeTypeFLAC, info.Size()
+	}
 
-	ext := filepath.Ext(fileHeader.Filename)
 	storageFilename := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), helpers.Slug(req.Title), ext)
 
 	// Hard timeout agar tidak menggantung sampai Traefik timeout
-	uploadCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	uploadCtx, cancel := s.uploadContext(ctx, fileSize)
 	defer cancel()
 
+	release, err := s.holdUpload(ctx, userID, src)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Printf("[track] upload to storage start name=%s", storageFilename)
-	if _, err := s.storage.Upload(uploadCtx, storageFilename, file); err != nil {
+	_, err = s.storage.Upload(uploadCtx, storageFilename, s.shapeUpload(uploadCtx, audioFile))
+	release()
+	if err != nil {
 		log.Printf("[track] upload failed err=%v dur=%s", err, time.Since(start))
 		return nil, err
 	}
 	log.Printf("[track] upload to storage done dur=%s", time.Since(start))
 
+	artworks := s.ingestArtwork(uploadCtx, audioFile, req)
+
 	newTrack := &schema.Track{
 		UserID:           userID,
 		Title:            req.Title,
@@ -89,65 +379,386 @@ func (s *trackService) CreateTrack(ctx context.Context, req request.CreateTrackR
 		Album:            &req.Album,
 		Duration:         req.Duration,
 		StorageFilename:  storageFilename,
-		OriginalFilename: fileHeader.Filename,
-		FileSize:         fileHeader.Size,
-		MimeType:         fileHeader.Header.Get("Content-Type"),
+		OriginalFilename: src.filename,
+		FileSize:         fileSize,
+		MimeType:         mimeType,
+		Artworks:         artworks,
 	}
 
-	res, err := s.repo.CreateTrack(newTrack)
-	if err != nil {
+	if _, err := audioFile.Seek(0, io.SeekStart); err == nil {
+		analysisCtx, cancel := analysisContext(ctx)
+		s.analyzeAudio(analysisCtx, audioFile, newTrack)
+		cancel()
+		log.Printf("[track] analysis done dur=%s", time.Since(start))
+	}
+
+	ingest.File, ingest.MimeType, ingest.Size, ingest.Track = audioFile, mimeType, fileSize, newTrack
+	if err := s.hooks.AfterStore(ctx, ingest); err != nil {
+		s.discardTrack(newTrack)
 		return nil, err
 	}
 
-	trackRes := response.FromTrackSchema(*res, s.storage.GetURL(res.StorageFilename))
-	log.Printf("[track] create success id=%d total_dur=%s", res.ID, time.Since(start))
+	newTrack.Title, newTrack.Artist, newTrack.Album = ingest.Title, ingest.Artist, &ingest.Album
+	newTrack.IngestData = ingest.Data
 
-	return &trackRes, nil
+	var tags audio.Tags
+	if _, err := audioFile.Seek(0, io.SeekStart); err == nil {
+		if tags, err = audio.ReadTags(audioFile); err != nil {
+			log.Printf("[track] read tags name=%s err=%v", src.filename, err)
+		}
+	}
+
+	setMetadata(newTrack, tagMetadata(req.TrackMetadata, tags))
+	newTrack.AlbumArtist = cmp.Or(req.AlbumArtist, tags.AlbumArtist)
+
+	return newTrack, nil
 }
 
-func (s *trackService) UpdateTrack(id uint64, req request.UpdateTrackRequest, userID uint64) (track *response.TrackResponse, err error) {
-	// Check if track exists and user is owner
-	existingTrack, err := s.repo.FindTrackByID(id)
+// discardTrack removes the stored files of a track that was never saved
+func (s *trackService) discardTrack(track *schema.Track) {
+	if err := s.storage.Delete(track.StorageFilename); err != nil {
+		log.Printf("[track] delete %s err=%v", track.StorageFilename, err)
+	}
+
+	s.deleteArtworkFiles(track.Artworks)
+	if track.WaveformFilename != nil {
+		if err := s.storage.Delete(*track.WaveformFilename); err != nil {
+			log.Printf("[track] delete waveform %s err=%v", *track.WaveformFilename, err)
+		}
+	}
+}
+
+func (s *trackService) UpdateTrack(ctx context.Context, id uint64, req request.UpdateTrackRequest, userID uint64) (track *response.TrackResponse, err error) {
+	existingTrack, err := s.findOwnedTrack(id, userID, "update")
 	if err != nil {
 		return nil, err
 	}
 
-	if existingTrack.UserID != userID {
-		return nil, fmt.Errorf("you don't have permission to update this track")
+	previousAlbum := existingTrack.Album
+
+	// omitted fields keep their value
+	title := cmp.Or(helpers.Value(req.Title), existingTrack.Title)
+	artist := existingTrack.Artist
+	if req.Artist != nil {
+		artist = *req.Artist
+	}
+	album := helpers.Value(previousAlbum)
+	if req.Album != nil {
+		album = *req.Album
 	}
 
+	if existingTrack.Title != title || existingTrack.Artist != artist || helpers.Value(previousAlbum) != album {
+		ingest := &hook.Ingest{
+			UserID:   userID,
+			Filename: existingTrack.OriginalFilename,
+			Title:    title,
+			Artist:   artist,
+			Album:    album,
+			Track:    existingTrack,
+		}
+		if err := s.hooks.CheckMetadata(ctx, ingest); err != nil {
+			return nil, err
+		}
+		title, artist, album = ingest.Title, ingest.Artist, ingest.Album
+	}
+	albumChanged := helpers.Value(previousAlbum) != album
+
+	// credits are parsed again when the strings they come from change, otherwise they are kept
+	credits := requestCredits(req.Credits)
+	reparse := existingTrack.Artist != artist || existingTrack.Title != title ||
+		(req.Composer != nil && helpers.Value(existingTrack.Composer) != strings.TrimSpace(*req.Composer))
+	if credits == nil && !reparse {
+		credits = trackCredits(existingTrack)
+	}
+	relink := reparse || req.Credits != nil || albumChanged || req.AlbumArtist != ""
+
 	// Update fields
-	existingTrack.Title = req.Title
-	existingTrack.Artist = req.Artist
-	existingTrack.Album = &req.Album
+	existingTrack.Title = title
+	existingTrack.Artist = artist
+	if albumChanged {
+		existingTrack.Album = &album
+	}
+	setMetadata(existingTrack, req.TrackMetadata)
+	if err := validateNumbers(existingTrack); err != nil {
+		return nil, err
+	}
 
-	res, err := s.repo.UpdateTrack(id, existingTrack)
+	// the fields and the tag metadata, which may clear columns, are saved together
+	res := existingTrack
+	err = s.repo.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if _, err := repo.UpdateTrack(id, res); err != nil {
+			return err
+		}
+
+		return repo.UpdateTrackMetadata(id, res)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	trackRes := response.FromTrackSchema(*res, s.storage.GetURL(res.StorageFilename))
-	return &trackRes, nil
+	if relink {
+		if err := s.library.RelinkTrack(res, req.AlbumArtist, credits); err != nil {
+			return nil, err
+		}
+	}
+
+	if albumChanged {
+		s.refreshAlbumGain(userID, previousAlbum)
+		s.refreshAlbumGain(userID, res.Album)
+	}
+
+	if s.shouldWriteTags(req.WriteTags) {
+		if err := s.writeTags(ctx, res); err != nil {
+			log.Printf("[track] write tags id=%d err=%v", id, err)
+		}
+	}
+
+	if err := s.loadTags(userID, res); err != nil {
+		return nil, err
+	}
+
+	trackRes := []response.TrackResponse{response.FromTrackSchema(*res, s.storage)}
+	if err := s.loadLikes(userID, trackRes); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadRatings(userID, trackRes); err != nil {
+		return nil, err
+	}
+
+	return &trackRes[0], nil
 }
 
 func (s *trackService) DeleteTrack(id uint64, userID uint64) (err error) {
-	// Check if track exists and user is owner
-	existingTrack, err := s.repo.FindTrackByID(id)
+	existingTrack, err := s.findOwnedTrack(id, userID, "delete")
+	if err != nil {
+		return err
+	}
+
+	// only the albums and artists the deleted tracks were linked to can become unused
+	albumIDs, artistIDs := libraryService.LinkedIDs(*existingTrack)
+	if existingTrack.CueSheet != nil {
+		segments, err := s.repo.ListSegments(id)
+		if err != nil {
+			return err
+		}
+		albumIDs, artistIDs = libraryService.LinkedIDs(append(segments, *existingTrack)...)
+	}
+
+	s.deletePreviews(id)
+	s.deleteVersions(id)
+
+	// CUE segments only remove their own record, the audio belongs to the parent
+	if !existingTrack.IsSegment() {
+		// Delete file from storage
+		err = s.storage.Delete(existingTrack.StorageFilename)
+		if err != nil {
+			// Log the error but continue to delete the DB record if the file is already gone
+			fmt.Printf("Warning: failed to delete file from storage: %v\n", err)
+		}
+
+		s.deleteArtworkFiles(existingTrack.Artworks)
+		if existingTrack.WaveformFilename != nil {
+			if err := s.storage.Delete(*existingTrack.WaveformFilename); err != nil {
+				log.Printf("[track] delete waveform %s err=%v", *existingTrack.WaveformFilename, err)
+			}
+		}
+	}
+
+	// the track, and the segments of a CUE parent, leave every playlist and lose their likes and
+	// ratings together with their records, a failed delete keeps all of them
+	err = s.repo.Transaction(func(tx *gorm.DB) error {
+		if err := s.playlists.WithTx(tx).RemoveTrack(id); err != nil {
+			return err
+		}
+
+		if err := s.likes.WithTx(tx).DeleteTrackLikes(id); err != nil {
+			return err
+		}
+
+		repo := s.repo.WithTx(tx)
+		if err := repo.DeleteTrackRatings(id); err != nil {
+			return err
+		}
+
+		if existingTrack.CueSheet != nil {
+			if err := repo.DeleteSegments(id); err != nil {
+				return err
+			}
+		}
+
+		// Delete DB Record
+		return repo.DeleteTrack(id)
+	})
 	if err != nil {
 		return err
 	}
 
-	if existingTrack.UserID != userID {
-		return fmt.Errorf("you don't have permission to delete this track")
+	s.refreshAlbumGain(existingTrack.UserID, existingTrack.Album)
+	s.library.Prune(albumIDs, artistIDs)
+	return nil
+}
+
+func (s *trackService) ReplaceArtwork(ctx context.Context, id uint64, userID uint64, fileHeader *multipart.FileHeader) (track *response.TrackResponse, err error) {
+	existingTrack, err := s.findOwnedTrack(id, userID, "update")
+	if err != nil {
+		return nil, err
 	}
 
-	// Delete file from storage
-	err = s.storage.Delete(existingTrack.StorageFilename)
+	data, err := readFileHeader(fileHeader)
 	if err != nil {
-		// Log the error but continue to delete the DB record if the file is already gone
-		fmt.Printf("Warning: failed to delete file from storage: %v\n", err)
+		return nil, err
+	}
+
+	// CUE segments show the artwork of their parent
+	target := id
+	if existingTrack.IsSegment() {
+		target = *existingTrack.ParentID
 	}
 
-	// Delete DB Record
-	return s.repo.DeleteTrack(id)
+	artworks, err := s.storeArtwork(ctx, existingTrack.Title, data, artworkSourceUpload)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := s.repo.ReplaceArtworks(target, artworks)
+	if err != nil {
+		s.deleteArtworkFiles(artworks)
+		return nil, err
+	}
+
+	s.deleteArtworkFiles(old)
+
+	tagged := existingTrack
+	if existingTrack.Parent != nil {
+		existingTrack.Parent.Artworks = artworks
+		tagged = existingTrack.Parent
+	} else {
+		existingTrack.Artworks = artworks
+	}
+
+	if s.shouldWriteTags(nil) {
+		if err := s.writeTags(ctx, tagged); err != nil {
+			log.Printf("[track] write tags id=%d err=%v", tagged.ID, err)
+		}
+		if tagged != existingTrack {
+			existingTrack.StorageFilename, existingTrack.FileSize = tagged.StorageFilename, tagged.FileSize
+		}
+	}
+	trackRes := response.FromTrackSchema(*existingTrack, s.storage)
+	return &trackRes, nil
+}
+
+// findOwnedTrack loads a track and checks that userID owns it, action names the
+// operation in the permission error
+func (s *trackService) findOwnedTrack(id uint64, userID uint64, action string) (*schema.Track, error) {
+	track, err := s.repo.FindTrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if track.UserID != userID {
+		return nil, fmt.Errorf("you don't have permission to %s this track", action)
+	}
+
+	return track, nil
+}
+
+// ingestArtwork renders the artwork of a new track from the picture embedded in the audio file,
+// falling back to the uploaded image. Failures are logged and never block the upload.
+func (s *trackService) ingestArtwork(ctx context.Context, file io.ReadSeeker, req request.CreateTrackRequest) []schema.TrackArtwork {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		log.Printf("[track] artwork seek err=%v", err)
+		return nil
+	}
+
+	pic, err := audio.ExtractPicture(file)
+	if err != nil {
+		log.Printf("[track] artwork read tags err=%v", err)
+	}
+
+	if pic != nil {
+		artworks, err := s.storeArtwork(ctx, req.Title, pic.Data, artworkSourceEmbedded)
+		if err == nil {
+			return artworks
+		}
+		log.Printf("[track] embedded artwork err=%v", err)
+	}
+
+	if req.Artwork == nil {
+		return nil
+	}
+
+	data, err := readFileHeader(req.Artwork)
+	if err != nil {
+		log.Printf("[track] uploaded artwork read err=%v", err)
+		return nil
+	}
+
+	artworks, err := s.storeArtwork(ctx, req.Title, data, artworkSourceUpload)
+	if err != nil {
+		log.Printf("[track] uploaded artwork err=%v", err)
+		return nil
+	}
+
+	return artworks
+}
+
+// storeArtwork renders the configured square sizes of an image and uploads them to storage
+func (s *trackService) storeArtwork(ctx context.Context, title string, data []byte, source string) ([]schema.TrackArtwork, error) {
+	sizes := s.cfg.Track.Artwork.Sizes
+	if len(sizes) == 0 {
+		sizes = defaultArtworkSizes
+	}
+
+	quality := s.cfg.Track.Artwork.Quality
+	if quality <= 0 || quality > 100 {
+		quality = defaultArtworkQuality
+	}
+
+	variants, err := imaging.SquareVariants(data, sizes, quality)
+	if err != nil {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "Invalid artwork image",
+		}
+	}
+
+	prefix := fmt.Sprintf("artwork/%d_%s", time.Now().UnixNano(), helpers.Slug(title))
+	artworks := make([]schema.TrackArtwork, 0, len(variants))
+	for _, v := range variants {
+		filename := fmt.Sprintf("%s_%d.jpg", prefix, v.Size)
+		if _, err := s.storage.Upload(ctx, filename, bytes.NewReader(v.Data)); err != nil {
+			s.deleteArtworkFiles(artworks)
+			return nil, err
+		}
+
+		artworks = append(artworks, schema.TrackArtwork{
+			Size:            v.Size,
+			Source:          source,
+			StorageFilename: filename,
+		})
+	}
+
+	return artworks, nil
+}
+
+func (s *trackService) deleteArtworkFiles(artworks []schema.TrackArtwork) {
+	for _, a := range artworks {
+		if err := s.storage.Delete(a.StorageFilename); err != nil {
+			log.Printf("[track] delete artwork %s err=%v", a.StorageFilename, err)
+		}
+	}
+}
+
+func readFileHeader(fileHeader *multipart.FileHeader) ([]byte, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return io.ReadAll(file)
 }
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+)
+
+const mimeTypeFLAC = "audio/flac"
+
+// compressWAV losslessly encodes a WAV upload to FLAC and checks the round trip sample by
+// sample. override, when set, replaces the configured default. It returns nil, leaving the
+// upload as it is, for other formats, when disabled or when encoding fails. The upload is then
+// rewound, an encoder that gave up after the header must not cut the stored original.
+func (s *trackService) compressWAV(src upload, override *bool) (compressed *storage.TempFile) {
+	enabled := s.cfg.Track.Flac.EncodeWAV
+	if override != nil {
+		enabled = *override
+	}
+
+	if !enabled || audio.FormatOf(src.mimeType, src.filename) != audio.FormatWAV {
+		return nil
+	}
+
+	defer func() {
+		if compressed != nil {
+			return
+		}
+		if _, err := src.file.Seek(0, io.SeekStart); err != nil {
+			log.Printf("[track] rewind %s err=%v", src.filename, err)
+		}
+	}()
+
+	start := time.Now()
+	tmp, err := storage.NewTempFile("ingest-*.flac")
+	if err != nil {
+		log.Printf("[track] flac temp file err=%v", err)
+		return nil
+	}
+
+	if err := audio.EncodeFLAC(tmp, src.file); err != nil {
+		log.Printf("[track] flac encode name=%s err=%v", src.filename, err)
+		_ = tmp.Close()
+		return nil
+	}
+
+	if err := audio.VerifyFLAC(tmp, src.file); err != nil {
+		log.Printf("[track] flac verify name=%s err=%v", src.filename, err)
+		_ = tmp.Close()
+		return nil
+	}
+
+	size, err := tmp.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = tmp.Close()
+		return nil
+	}
+
+	log.Printf("[track] flac encoded name=%s size=%d->%d dur=%s", src.filename, src.size, size, time.Since(start))
+	return tmp
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"testing"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/request"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+)
+
+// memStorage keeps stored files in memory
+type memStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (m *memStorage) Upload(_ context.Context, filename string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = map[string][]byte{}
+	}
+	m.files[filename] = data
+
+	return filename, nil
+}
+
+func (m *memStorage) Open(_ context.Context, filename string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[filename]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memStorage) Delete(filename string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.files, filename)
+	return nil
+}
+
+func (m *memStorage) GetURL(filename string) string {
+	return "/" + filename
+}
+
+// floatWAV builds a mono 32-bit float WAV of a short ramp
+func floatWAV(frames int) []byte {
+	data := make([]byte, frames*4)
+	for i := range frames {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(i%100)/100))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(4+8+16+8+len(data)))
+	b.WriteString("WAVEfmt ")
+	_ = binary.Write(&b, binary.LittleEndian, []any{
+		uint32(16), uint16(3), uint16(1), uint32(8000), uint32(8000 * 4), uint16(4), uint16(32),
+	})
+	b.WriteString("data")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+
+	return b.Bytes()
+}
+
+func TestStoreTrackUncompressedWAV(t *testing.T) {
+	wav := floatWAV(8000)
+
+	cfg := &config.Config{}
+	cfg.Track.Flac.EncodeWAV = true
+	store := &memStorage{}
+	s := &trackService{cfg: cfg, storage: store}
+
+	track, err := s.storeTrack(context.Background(), request.CreateTrackRequest{Title: "Float"}, 1, upload{
+		file:     bytes.NewReader(wav),
+		filename: "float.wav",
+		mimeType: "audio/wav",
+		size:     int64(len(wav)),
+	})
+	if err != nil {
+		t.Fatalf("storeTrack() err = %v", err)
+	}
+
+	if track.MimeType != "audio/wav" {
+		t.Errorf("storeTrack() mime type = %q, want audio/wav", track.MimeType)
+	}
+	if got := store.files[track.StorageFilename]; !bytes.Equal(got, wav) {
+		t.Errorf("stored %d bytes, want the %d bytes of the upload", len(got), len(wav))
+	}
+}
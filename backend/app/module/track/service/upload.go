@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/throttle"
+	"github.com/gofiber/fiber/v2"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+const (
+	uploadTimeout           = 2 * time.Minute
+	defaultUploadRetryAfter = 10 * time.Second
+)
+
+var uploadBusyMessages = map[error]string{
+	throttle.ErrUserBusy:  "Too many uploads in progress for this user, try again later",
+	throttle.ErrQueueFull: "Too many uploads in progress, try again later",
+	throttle.ErrTimeout:   "Timed out waiting for an upload slot, try again later",
+}
+
+// newUploadLimiter limits concurrent uploads per track.upload, its state is published as the
+// "uploads" expvar
+func newUploadLimiter(cfg *config.Config) *throttle.Limiter {
+	conf := cfg.Track.Upload
+
+	return throttle.NewLimiter("uploads", conf.MaxConcurrent, conf.MaxQueue, conf.PerUser,
+		time.Duration(conf.QueueTimeoutSeconds)*time.Second)
+}
+
+// acquireUpload takes an upload slot for userID, uploads over the limits are refused with 429
+func (s *trackService) acquireUpload(ctx context.Context, userID uint64) (release func(), err error) {
+	release, err = s.uploads.Acquire(ctx, userID)
+	for busy, message := range uploadBusyMessages {
+		if errors.Is(err, busy) {
+			log.Printf("[track] upload refused user=%d: %v", userID, err)
+
+			retryAfter := defaultUploadRetryAfter
+			if s.cfg.Track.Upload.RetryAfterSeconds > 0 {
+				retryAfter = time.Duration(s.cfg.Track.Upload.RetryAfterSeconds) * time.Second
+			}
+
+			return nil, &uresponse.Error{
+				Code:       fiber.StatusTooManyRequests,
+				Message:    message,
+				RetryAfter: retryAfter,
+			}
+		}
+	}
+
+	return release, err
+}
+
+// holdUpload takes an upload slot for a limited upload, it is released once the file is sent
+// to storage so that encoding and analysis do not hold it. Other uploads need no slot.
+func (s *trackService) holdUpload(ctx context.Context, userID uint64, src upload) (release func(), err error) {
+	if !src.limited {
+		return func() {}, nil
+	}
+
+	return s.acquireUpload(ctx, userID)
+}
+
+// shapeUpload limits the rate an audio file is sent to storage to track.upload.bandwidth_kbps
+func (s *trackService) shapeUpload(ctx context.Context, r io.Reader) io.Reader {
+	return throttle.Reader(ctx, r, s.cfg.Track.Upload.BandwidthKBps*1024)
+}
+
+// uploadContext bounds storing a file of size bytes, leaving room for the bandwidth limit
+func (s *trackService) uploadContext(ctx context.Context, size int64) (context.Context, context.CancelFunc) {
+	timeout := uploadTimeout
+	if rate := s.cfg.Track.Upload.BandwidthKBps * 1024; rate > 0 {
+		timeout += time.Duration(size/rate) * time.Second
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/hook"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/request"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	"github.com/gofiber/fiber/v2"
+
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+// secondUploadHook stores another upload of the same user from the first AfterStore it sees,
+// once the first file is stored and analyzed but its track is not done yet
+type secondUploadHook struct {
+	store func() error
+	done  bool
+	err   error
+}
+
+func (h *secondUploadHook) Name() string { return "second-upload" }
+
+func (h *secondUploadHook) BeforeStore(context.Context, *hook.Ingest) error { return nil }
+
+func (h *secondUploadHook) AfterStore(context.Context, *hook.Ingest) error {
+	if !h.done {
+		h.done = true
+		h.err = h.store()
+	}
+
+	return nil
+}
+
+func TestUploadSlotReleasedAfterTransfer(t *testing.T) {
+	wav := floatWAV(800)
+	newUpload := func(limited bool) upload {
+		return upload{file: bytes.NewReader(wav), filename: "a.wav", mimeType: "audio/wav", size: int64(len(wav)), limited: limited}
+	}
+
+	cfg := &config.Config{}
+	cfg.Track.Upload.PerUser = 1
+	cfg.Track.Hooks.Enabled = []string{"second-upload"}
+
+	second := &secondUploadHook{}
+	s := &trackService{cfg: cfg, storage: &memStorage{}, uploads: newUploadLimiter(cfg)}
+	s.hooks = hook.NewChain(cfg, []hook.IngestHook{second})
+
+	ctx := context.Background()
+	second.store = func() error {
+		_, err := s.storeTrack(ctx, request.CreateTrackRequest{Title: "Second"}, 1, newUpload(true))
+		return err
+	}
+
+	if _, err := s.storeTrack(ctx, request.CreateTrackRequest{Title: "First"}, 1, newUpload(true)); err != nil {
+		t.Fatalf("storeTrack() first upload err = %v", err)
+	}
+	if !second.done || second.err != nil {
+		t.Fatalf("second upload ran %v, err = %v, want it admitted while the first is finishing", second.done, second.err)
+	}
+
+	// the slot is held while the file is sent
+	release, err := s.holdUpload(ctx, 1, newUpload(true))
+	if err != nil {
+		t.Fatalf("holdUpload() err = %v", err)
+	}
+	defer release()
+
+	var refused *uresponse.Error
+	if _, err := s.storeTrack(ctx, request.CreateTrackRequest{Title: "Refused"}, 1, newUpload(true)); !errors.As(err, &refused) || refused.Code != fiber.StatusTooManyRequests {
+		t.Errorf("storeTrack() during a transfer of the same user err = %v, want 429", err)
+	}
+	if _, err := s.storeTrack(ctx, request.CreateTrackRequest{Title: "Import"}, 1, newUpload(false)); err != nil {
+		t.Errorf("storeTrack() of an import during a transfer err = %v", err)
+	}
+}
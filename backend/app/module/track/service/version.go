@@ -0,0 +1,410 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/hook"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/request"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/response"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/helpers"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	libraryService "git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+	uresponse "git.dev.siap.id/kukuhkkh/app-music/utils/response"
+)
+
+const defaultVersionsKept = 10
+
+// ReplaceFile uploads a new audio file for an existing track. The previous file is kept as a
+// version, metadata and analysis are derived again from the new file and the track keeps its ID.
+// Metadata the new file has no tags for keeps its current value.
+func (s *trackService) ReplaceFile(ctx context.Context, id uint64, userID uint64, req request.ReplaceFileRequest, fileHeader *multipart.FileHeader) (*response.TrackResponse, error) {
+	existingTrack, err := s.findOwnedTrack(id, userID, "update")
+	if err != nil {
+		return nil, err
+	}
+
+	if existingTrack.IsSegment() {
+		return nil, errSegment
+	}
+
+	if existingTrack.CueSheet != nil {
+		return nil, errCueParent
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	src := upload{
+		file:     file,
+		filename: fileHeader.Filename,
+		mimeType: fileHeader.Header.Get("Content-Type"),
+		size:     fileHeader.Size,
+		limited:  true,
+	}
+
+	updated := *existingTrack
+	tags, err := audio.ReadTags(file)
+	if err != nil {
+		log.Printf("[track] read tags name=%s err=%v", src.filename, err)
+	}
+	applyFileTags(&updated, tags)
+
+	ingest := &hook.Ingest{
+		UserID:   userID,
+		Filename: src.filename,
+		MimeType: src.mimeType,
+		Size:     src.size,
+		File:     src.file,
+		Title:    updated.Title,
+		Artist:   updated.Artist,
+		Album:    helpers.Value(updated.Album),
+		Track:    existingTrack,
+	}
+	if err := s.hooks.BeforeStore(ctx, ingest); err != nil {
+		return nil, err
+	}
+
+	var audioFile io.ReadSeeker = file
+	ext := filepath.Ext(src.filename)
+	mimeType := src.mimeType
+	fileSize := src.size
+
+	if compressed := s.compressWAV(src, req.EncodeFLAC); compressed != nil {
+		defer compressed.Close()
+
+		info, err := compressed.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		audioFile, ext, mimeType, fileSize = compressed, ".flac", mimeTypeFLAC, info.Size()
+	}
+
+	storageFilename := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), helpers.Slug(existingTrack.Title), ext)
+
+	uploadCtx, cancel := s.uploadContext(ctx, fileSize)
+	defer cancel()
+
+	release, err := s.holdUpload(ctx, userID, src)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.storage.Upload(uploadCtx, storageFilename, s.shapeUpload(uploadCtx, audioFile))
+	release()
+	if err != nil {
+		return nil, err
+	}
+
+	updated.StorageFilename = storageFilename
+	updated.OriginalFilename = fileHeader.Filename
+	updated.FileSize = fileSize
+	updated.MimeType = mimeType
+	s.analyzeFile(ctx, &updated, audioFile)
+
+	ingest.File, ingest.MimeType, ingest.Size, ingest.Track = audioFile, mimeType, fileSize, &updated
+	if err := s.hooks.AfterStore(ctx, ingest); err != nil {
+		s.discardFile(&updated)
+		return nil, err
+	}
+
+	updated.Title, updated.Artist, updated.Album = ingest.Title, ingest.Artist, optional(ingest.Album)
+	updated.IngestData = ingest.Data
+
+	if err := s.swapFile(ctx, existingTrack, &updated, audioFile, nil); err != nil {
+		s.discardFile(&updated)
+		return nil, err
+	}
+
+	if err := s.relinkFile(existingTrack, &updated, tags.AlbumArtist); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[track] file replaced id=%d name=%s previous=%s", id, storageFilename, existingTrack.StorageFilename)
+
+	trackRes := response.FromTrackSchema(updated, s.storage)
+	return &trackRes, nil
+}
+
+// GetVersions lists the previous audio files of a track, newest first
+func (s *trackService) GetVersions(id uint64, userID uint64) ([]response.TrackVersionResponse, error) {
+	existingTrack, err := s.findOwnedTrack(id, userID, "view")
+	if err != nil {
+		return nil, err
+	}
+
+	if existingTrack.IsSegment() {
+		return nil, errSegment
+	}
+
+	versions, err := s.repo.ListVersions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.FromTrackVersionListSchema(versions, s.storage), nil
+}
+
+// RollbackVersion makes a previous file current again, the file it replaces becomes a version
+func (s *trackService) RollbackVersion(ctx context.Context, id uint64, versionID uint64, userID uint64) (*response.TrackResponse, error) {
+	existingTrack, err := s.findOwnedTrack(id, userID, "update")
+	if err != nil {
+		return nil, err
+	}
+
+	if existingTrack.IsSegment() {
+		return nil, errSegment
+	}
+
+	if existingTrack.CueSheet != nil {
+		return nil, errCueParent
+	}
+
+	version, err := s.repo.FindVersion(id, versionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &uresponse.Error{
+			Code:    fiber.StatusNotFound,
+			Message: "Track version not found",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := storage.Download(ctx, s.storage, version.StorageFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tags, err := audio.ReadTags(file)
+	if err != nil {
+		log.Printf("[track] read tags name=%s err=%v", version.StorageFilename, err)
+	}
+
+	updated := *existingTrack
+	applyFileTags(&updated, tags)
+	updated.StorageFilename = version.StorageFilename
+	updated.OriginalFilename = version.OriginalFilename
+	updated.FileSize = version.FileSize
+	updated.MimeType = version.MimeType
+	s.analyzeFile(ctx, &updated, file)
+
+	if err := s.swapFile(ctx, existingTrack, &updated, file, version); err != nil {
+		return nil, err
+	}
+
+	if err := s.relinkFile(existingTrack, &updated, tags.AlbumArtist); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[track] file rolled back id=%d version=%d name=%s", id, versionID, version.StorageFilename)
+
+	trackRes := response.FromTrackSchema(updated, s.storage)
+	return &trackRes, nil
+}
+
+// discardFile removes a replacement file and the waveform analyzed from it when the track is not
+// saved with them, the artwork still belongs to the current file
+func (s *trackService) discardFile(track *schema.Track) {
+	if err := s.storage.Delete(track.StorageFilename); err != nil {
+		log.Printf("[track] delete %s err=%v", track.StorageFilename, err)
+	}
+
+	if track.WaveformFilename != nil {
+		if err := s.storage.Delete(*track.WaveformFilename); err != nil {
+			log.Printf("[track] delete waveform %s err=%v", *track.WaveformFilename, err)
+		}
+	}
+}
+
+// analyzeFile derives the analysis of a track from its new audio file. Artifacts derived from the
+// previous file are cleared, nothing is kept from it when the new file cannot be analyzed.
+// Analysis gets its own deadline, decoding a long file outlasts the upload timeout.
+func (s *trackService) analyzeFile(ctx context.Context, track *schema.Track, file io.ReadSeeker) {
+	resetAnalysis(track)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	ctx, cancel := analysisContext(ctx)
+	defer cancel()
+
+	s.analyzeAudio(ctx, file, track)
+}
+
+// swapFile persists the analyzed new file of updated, archiving the file of current as a version.
+// Previews and waveforms of the previous file are removed.
+func (s *trackService) swapFile(ctx context.Context, current *schema.Track, updated *schema.Track, file io.ReadSeeker, restored *schema.TrackVersion) error {
+	archived := &schema.TrackVersion{
+		TrackID:          current.ID,
+		StorageFilename:  current.StorageFilename,
+		OriginalFilename: current.OriginalFilename,
+		FileSize:         current.FileSize,
+		MimeType:         current.MimeType,
+	}
+
+	if err := s.repo.SwapTrackFile(updated, archived, restored); err != nil {
+		if updated.WaveformFilename != nil {
+			if err := s.storage.Delete(*updated.WaveformFilename); err != nil {
+				log.Printf("[track] delete waveform %s err=%v", *updated.WaveformFilename, err)
+			}
+		}
+		return err
+	}
+
+	if old := current.WaveformFilename; old != nil && (updated.WaveformFilename == nil || *old != *updated.WaveformFilename) {
+		if err := s.storage.Delete(*old); err != nil {
+			log.Printf("[track] delete waveform %s err=%v", *old, err)
+		}
+	}
+
+	s.deletePreviews(current.ID)
+	s.refreshArtwork(ctx, updated, file)
+	s.pruneVersions(current.ID)
+	s.refreshAlbumGain(updated.UserID, updated.Album)
+	if helpers.Value(current.Album) != helpers.Value(updated.Album) {
+		s.refreshAlbumGain(current.UserID, current.Album)
+	}
+
+	return nil
+}
+
+// applyFileTags copies the metadata found in the tags of a new audio file onto the track, fields
+// the file has no tag for keep their value
+func applyFileTags(track *schema.Track, tags audio.Tags) {
+	track.Title = cmp.Or(strings.TrimSpace(tags.Title), track.Title)
+	track.Artist = cmp.Or(strings.TrimSpace(tags.Artist), track.Artist)
+	if album := optional(tags.Album); album != nil {
+		track.Album = album
+	}
+
+	setMetadata(track, tagMetadata(request.TrackMetadata{}, tags))
+}
+
+// relinkFile links a track to its artist, album and credits again when the tags of its new file
+// changed the strings they come from. Credits are only parsed again when the artist, title or
+// composer changed, otherwise the stored credits are kept.
+func (s *trackService) relinkFile(current *schema.Track, updated *schema.Track, albumArtist string) error {
+	reparse := current.Artist != updated.Artist || current.Title != updated.Title ||
+		helpers.Value(current.Composer) != helpers.Value(updated.Composer)
+	if !reparse && helpers.Value(current.Album) == helpers.Value(updated.Album) && albumArtist == "" {
+		return nil
+	}
+
+	var credits []libraryService.Credit
+	if !reparse {
+		credits = trackCredits(current)
+	}
+
+	return s.library.RelinkTrack(updated, albumArtist, credits)
+}
+
+// resetAnalysis clears every value derived from the audio file of a track
+func resetAnalysis(track *schema.Track) {
+	track.Duration, track.DurationMs = 0, nil
+	track.WaveformFilename, track.AnalysisError = nil, nil
+	track.LoudnessIntegrated, track.LoudnessTruePeak, track.LoudnessRange = nil, nil, nil
+	track.ReplayGainTrackGain, track.ReplayGainTrackPeak = nil, nil
+	track.Bpm, track.BpmConfidence = nil, nil
+	track.Key, track.KeyConfidence = nil, nil
+	track.SampleRate, track.EncoderDelay, track.EncoderPadding = nil, nil, nil
+	track.TrimStartMs, track.TrimEndMs = nil, nil
+}
+
+// refreshArtwork replaces embedded artwork with the picture of a new audio file. Uploaded
+// covers are kept, the owner picked them over the embedded picture.
+func (s *trackService) refreshArtwork(ctx context.Context, track *schema.Track, file io.ReadSeeker) {
+	for _, a := range track.Artworks {
+		if a.Source != artworkSourceEmbedded {
+			return
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	pic, err := audio.ExtractPicture(file)
+	if err != nil || pic == nil {
+		return
+	}
+
+	artworks, err := s.storeArtwork(ctx, track.Title, pic.Data, artworkSourceEmbedded)
+	if err != nil {
+		log.Printf("[track] embedded artwork id=%d err=%v", track.ID, err)
+		return
+	}
+
+	old, err := s.repo.ReplaceArtworks(track.ID, artworks)
+	if err != nil {
+		log.Printf("[track] replace artwork id=%d err=%v", track.ID, err)
+		s.deleteArtworkFiles(artworks)
+		return
+	}
+
+	s.deleteArtworkFiles(old)
+	track.Artworks = artworks
+}
+
+// pruneVersions deletes the oldest versions of a track beyond the configured number to keep
+func (s *trackService) pruneVersions(trackID uint64) {
+	keep := s.cfg.Track.Versions.Keep
+	if keep == 0 {
+		keep = defaultVersionsKept
+	}
+	if keep < 0 {
+		return
+	}
+
+	versions, err := s.repo.ListVersions(trackID)
+	if err != nil {
+		log.Printf("[track] list versions id=%d err=%v", trackID, err)
+		return
+	}
+
+	if len(versions) > keep {
+		s.removeVersions(versions[keep:])
+	}
+}
+
+// deleteVersions removes every previous file of a track
+func (s *trackService) deleteVersions(trackID uint64) {
+	versions, err := s.repo.ListVersions(trackID)
+	if err != nil {
+		log.Printf("[track] list versions id=%d err=%v", trackID, err)
+		return
+	}
+
+	s.removeVersions(versions)
+}
+
+func (s *trackService) removeVersions(versions []schema.TrackVersion) {
+	for _, v := range versions {
+		if err := s.storage.Delete(v.StorageFilename); err != nil {
+			log.Printf("[track] delete version %s err=%v", v.StorageFilename, err)
+		}
+	}
+
+	if err := s.repo.DeleteVersions(versions); err != nil {
+		log.Printf("[track] delete versions err=%v", err)
+	}
+}
@@ -2,7 +2,9 @@ package track
 
 import (
 	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
+	idempotency "git.dev.siap.id/kukuhkkh/app-music/app/module/idempotency/repository"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/controller"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/hook"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/service"
 	"git.dev.siap.id/kukuhkkh/app-music/utils/storage"
@@ -11,14 +13,23 @@ import (
 )
 
 type TrackRouter struct {
-	App        fiber.Router
-	Controller *controller.Controller
+	App         fiber.Router
+	Controller  *controller.Controller
+	Idempotency idempotency.IdempotencyRepository
 }
 
 var NewTrackModule = fx.Options(
 	// register repository of track module
 	fx.Provide(repository.NewTrackRepository),
 
+	// register ingest hooks, track.hooks.enabled picks which run and in which order
+	fx.Provide(
+		hook.AsIngestHook(hook.NewClamAV),
+		hook.AsIngestHook(hook.NewMaxDuration),
+		hook.AsIngestHook(hook.NewWordFilter),
+	),
+	fx.Provide(fx.Annotate(hook.NewChain, fx.ParamTags(``, hook.GroupTag))),
+
 	// register service of track module
 	fx.Provide(service.NewTrackService),
 
@@ -32,23 +43,36 @@ var NewTrackModule = fx.Options(
 	fx.Provide(storage.NewStorage),
 )
 
-func NewTrackRouter(fiber *fiber.App, controller *controller.Controller) *TrackRouter {
+func NewTrackRouter(fiber *fiber.App, controller *controller.Controller, idempotency idempotency.IdempotencyRepository) *TrackRouter {
 	return &TrackRouter{
-		App:        fiber,
-		Controller: controller,
+		App:         fiber,
+		Controller:  controller,
+		Idempotency: idempotency,
 	}
 }
 
 func (_i *TrackRouter) RegisterTrackRoutes() {
 	// define controllers
 	trackController := _i.Controller.Track
+	idempotent := middleware.Idempotent(_i.Idempotency)
 
 	// define routes
 	_i.App.Route("/music", func(router fiber.Router) {
 		router.Get("", middleware.Protected(), trackController.GetTracks)
 		router.Get("/:id", middleware.Protected(), trackController.GetTrackByID)
-		router.Put("/:id", middleware.Protected(), trackController.Update)
-		router.Delete("/:id", middleware.Protected(), trackController.Delete)
-		router.Post("", middleware.Protected(), trackController.Create)
+		router.Get("/:id/waveform", middleware.Protected(), trackController.GetWaveform)
+		router.Get("/:id/stream", middleware.Protected(), trackController.Stream)
+		router.Get("/:id/preview", middleware.Protected(), trackController.Preview)
+		router.Post("/:id/like", middleware.Protected(), trackController.Like)
+		router.Delete("/:id/like", middleware.Protected(), trackController.Unlike)
+		router.Put("/:id/rating", middleware.Protected(), trackController.SetRating)
+		router.Delete("/:id/rating", middleware.Protected(), trackController.ClearRating)
+		router.Put("/:id", middleware.Protected(), idempotent, trackController.Update)
+		router.Put("/:id/artwork", middleware.Protected(), trackController.UpdateArtwork)
+		router.Put("/:id/file", middleware.Protected(), trackController.UpdateFile)
+		router.Get("/:id/versions", middleware.Protected(), trackController.GetVersions)
+		router.Post("/:id/versions/:versionId/rollback", middleware.Protected(), trackController.RollbackVersion)
+		router.Delete("/:id", middleware.Protected(), idempotent, trackController.Delete)
+		router.Post("", middleware.Protected(), idempotent, trackController.Create)
 	})
 }
@@ -3,6 +3,10 @@ package router
 import (
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/auth"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/dashboard"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track"
 	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
 	"github.com/gofiber/fiber/v2"
@@ -15,6 +19,10 @@ type Router struct {
 	AuthRouter      *auth.AuthRouter
 	TrackRouter     *track.TrackRouter
 	DashboardRouter *dashboard.DashboardRouter
+	LibraryRouter   *library.LibraryRouter
+	TagRouter       *tag.TagRouter
+	PlaylistRouter  *playlist.PlaylistRouter
+	LikeRouter      *like.LikeRouter
 }
 
 func NewRouter(
@@ -23,6 +31,10 @@ func NewRouter(
 	authRouter *auth.AuthRouter,
 	trackRouter *track.TrackRouter,
 	dashboardRouter *dashboard.DashboardRouter,
+	libraryRouter *library.LibraryRouter,
+	tagRouter *tag.TagRouter,
+	playlistRouter *playlist.PlaylistRouter,
+	likeRouter *like.LikeRouter,
 ) *Router {
 	return &Router{
 		App:             fiber,
@@ -30,6 +42,10 @@ func NewRouter(
 		AuthRouter:      authRouter,
 		TrackRouter:     trackRouter,
 		DashboardRouter: dashboardRouter,
+		LibraryRouter:   libraryRouter,
+		TagRouter:       tagRouter,
+		PlaylistRouter:  playlistRouter,
+		LikeRouter:      likeRouter,
 	}
 }
 
@@ -48,4 +64,8 @@ func (r *Router) Register() {
 	r.AuthRouter.RegisterAuthRoutes()
 	r.DashboardRouter.RegisterDashboardRoutes()
 	r.TrackRouter.RegisterTrackRoutes()
+	r.LibraryRouter.RegisterLibraryRoutes()
+	r.TagRouter.RegisterTagRoutes()
+	r.PlaylistRouter.RegisterPlaylistRoutes()
+	r.LikeRouter.RegisterLikeRoutes()
 }
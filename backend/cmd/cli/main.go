@@ -0,0 +1,49 @@
+package main
+
+import (
+	"go.uber.org/fx"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/ingest"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/setting"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/cli"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	_ "go.uber.org/automaxprocs"
+)
+
+// Admin commands for maintaining the music library, e.g.
+//
+//	go run ./cmd/cli backfill-loudness
+func main() {
+	fx.New(
+		// config
+		fx.Provide(config.NewConfig),
+		// logging
+		fx.Provide(bootstrap.NewLogger),
+		// database
+		fx.Provide(database.NewDatabase),
+
+		// provide modules
+		track.NewTrackModule,
+		library.NewLibraryModule,
+		tag.NewTagModule,
+		playlist.NewPlaylistModule,
+		like.NewLikeModule,
+		ingest.NewIngestModule,
+		setting.NewSettingModule,
+
+		// commands
+		fx.Provide(cli.NewCLI),
+
+		// run the requested command
+		fx.Invoke(cli.Start),
+
+		fx.NopLogger,
+	).Run()
+}
@@ -6,6 +6,13 @@ import (
 	"git.dev.siap.id/kukuhkkh/app-music/app/middleware"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/auth"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/dashboard"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/idempotency"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/ingest"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/library"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/like"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/playlist"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/setting"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/tag"
 	"git.dev.siap.id/kukuhkkh/app-music/app/module/track"
 	"git.dev.siap.id/kukuhkkh/app-music/app/router"
 	_ "git.dev.siap.id/kukuhkkh/app-music/docs"
@@ -49,11 +56,24 @@ func main() {
 		// provide modules
 		auth.NewAuthModule,
 		track.NewTrackModule,
+		library.NewLibraryModule,
+		tag.NewTagModule,
+		playlist.NewPlaylistModule,
+		like.NewLikeModule,
 		dashboard.NewDashboardModule,
+		ingest.NewIngestModule,
+		setting.NewSettingModule,
+		idempotency.NewIdempotencyModule,
 
 		// start aplication
 		fx.Invoke(bootstrap.Start),
 
+		// watch folder, after the database is connected
+		fx.Invoke(ingest.StartWatcher),
+
+		// smart playlist refresh, after the database is connected
+		fx.Invoke(playlist.StartRefresher),
+
 		// define logger
 		fx.WithLogger(fxzerolog.Init()),
 	).Run()
@@ -73,7 +73,24 @@ func (_db *Database) MigrateModels() {
 func Models() []interface{} {
 	return []interface{}{
 		schema.User{},
+		schema.Artist{},
+		schema.Album{},
 		schema.Track{},
+		schema.TrackArtwork{},
+		schema.TrackCredit{},
+		schema.Tag{},
+		schema.Playlist{},
+		schema.PlaylistCover{},
+		schema.PlaylistEntry{},
+		schema.PlaylistMember{},
+		schema.PlaylistActivity{},
+		schema.Like{},
+		schema.Rating{},
+		schema.TrackPreview{},
+		schema.TrackVersion{},
+		schema.IngestFailure{},
+		schema.IdempotencyKey{},
+		schema.Setting{},
 	}
 }
 
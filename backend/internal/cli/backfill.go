@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+
+	libraryService "git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+)
+
+func (c *CLI) backfillDuration(ctx context.Context, args []string) error {
+	return c.backfill(ctx, "backfill-duration", "duration_ms", args, func(t *schema.Track) bool {
+		return t.DurationMs != nil
+	})
+}
+
+// backfillLoudness selects on the loudness range, which silent tracks get too while their
+// integrated loudness stays empty
+func (c *CLI) backfillLoudness(ctx context.Context, args []string) error {
+	return c.backfill(ctx, "backfill-loudness", "loudness_range", args, func(t *schema.Track) bool {
+		return t.LoudnessRange != nil
+	})
+}
+
+func (c *CLI) backfillTempo(ctx context.Context, args []string) error {
+	return c.backfill(ctx, "backfill-tempo", "bpm", args, func(t *schema.Track) bool {
+		return t.Bpm != nil
+	})
+}
+
+func (c *CLI) backfillTrim(ctx context.Context, args []string) error {
+	return c.backfill(ctx, "backfill-trim", "trim_end_ms", args, func(t *schema.Track) bool {
+		return t.TrimEndMs != nil
+	})
+}
+
+// backfill re-analyzes every track whose column is still empty, in ID order and batches. Tracks
+// that cannot be decoded, or still lack the value afterwards, get their failure recorded so the
+// next run skips them instead of reporting them again.
+func (c *CLI) backfill(ctx context.Context, name, column string, args []string, measured func(t *schema.Track) bool) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	batch := fs.Int("batch", 100, "number of tracks loaded per query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var afterID uint64
+	var analyzed, failed int
+
+	for {
+		tracks, err := c.TrackRepo.ListTracksMissing(column, afterID, *batch)
+		if err != nil {
+			return err
+		}
+
+		if len(tracks) == 0 {
+			break
+		}
+
+		for _, t := range tracks {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			afterID = t.ID
+			track, err := c.TrackService.AnalyzeTrack(ctx, t.ID)
+			if err == nil && !measured(track) {
+				message := column + " could not be measured"
+				if err = c.TrackRepo.UpdateAnalysisError(t.ID, &message); err == nil {
+					err = errors.New(message)
+				}
+			}
+
+			if err != nil {
+				c.Log.Warn().Err(err).Uint64("track_id", t.ID).Msg("Analysis failed")
+				failed++
+				continue
+			}
+
+			analyzed++
+			c.Log.Info().Uint64("track_id", t.ID).Str("title", t.Title).Msg("Analyzed")
+		}
+	}
+
+	fmt.Printf("%s: %d analyzed, %d failed\n", name, analyzed, failed)
+	return nil
+}
+
+// backfillLibrary links tracks stored before artists, albums and credits had their own records
+func (c *CLI) backfillLibrary(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backfill-library", flag.ContinueOnError)
+	batch := fs.Int("batch", 100, "number of tracks loaded per query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var afterID uint64
+	var linked, failed int
+	var albumIDs, artistIDs []uint64
+
+	for {
+		tracks, err := c.LibraryRepo.ListUnlinkedTracks(afterID, *batch)
+		if err != nil {
+			return err
+		}
+
+		if len(tracks) == 0 {
+			break
+		}
+
+		// links replaced by the migration may leave albums and artists unused
+		albums, artists := libraryService.LinkedIDs(tracks...)
+		albumIDs, artistIDs = append(albumIDs, albums...), append(artistIDs, artists...)
+
+		for _, t := range tracks {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			afterID = t.ID
+			if err := c.Library.MigrateTrack(&t); err != nil {
+				c.Log.Warn().Err(err).Uint64("track_id", t.ID).Msg("Linking failed")
+				failed++
+				continue
+			}
+
+			linked++
+		}
+	}
+
+	c.Library.Prune(albumIDs, artistIDs)
+
+	fmt.Printf("backfill-library: %d linked, %d failed\n", linked, failed)
+	return nil
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/service"
+	"git.dev.siap.id/kukuhkkh/app-music/internal/bootstrap/database"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+	"github.com/rs/zerolog"
+	"go.uber.org/fx"
+
+	ingestRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/ingest/repository"
+	libraryRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/library/repository"
+	libraryService "git.dev.siap.id/kukuhkkh/app-music/app/module/library/service"
+	playlistService "git.dev.siap.id/kukuhkkh/app-music/app/module/playlist/service"
+	settingRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/setting/repository"
+)
+
+// Command is a single admin task runnable from the command line
+type Command struct {
+	Description string
+	Run         func(ctx context.Context, args []string) error
+}
+
+// CLI holds the dependencies and registered commands of the admin binary
+type CLI struct {
+	Log          zerolog.Logger
+	Config       *config.Config
+	DB           *database.Database
+	TrackRepo    repository.TrackRepository
+	TrackService service.TrackService
+	IngestRepo   ingestRepository.IngestRepository
+	LibraryRepo  libraryRepository.LibraryRepository
+	Library      libraryService.LibraryService
+	Playlists    playlistService.PlaylistService
+	Settings     settingRepository.SettingRepository
+
+	commands map[string]Command
+}
+
+func NewCLI(
+	log zerolog.Logger,
+	cfg *config.Config,
+	db *database.Database,
+	trackRepo repository.TrackRepository,
+	trackService service.TrackService,
+	ingestRepo ingestRepository.IngestRepository,
+	libraryRepo libraryRepository.LibraryRepository,
+	library libraryService.LibraryService,
+	playlists playlistService.PlaylistService,
+	settings settingRepository.SettingRepository,
+) *CLI {
+	c := &CLI{
+		Log:          log,
+		Config:       cfg,
+		DB:           db,
+		TrackRepo:    trackRepo,
+		TrackService: trackService,
+		IngestRepo:   ingestRepo,
+		LibraryRepo:  libraryRepo,
+		Library:      library,
+		Playlists:    playlists,
+		Settings:     settings,
+	}
+
+	c.commands = map[string]Command{
+		"backfill-duration": {
+			Description: "Compute the exact duration of tracks uploaded before server side measurement",
+			Run:         c.backfillDuration,
+		},
+		"backfill-trim": {
+			Description: "Detect silence trims and gapless metadata of tracks that have not been analyzed yet",
+			Run:         c.backfillTrim,
+		},
+		"trim-stream": {
+			Description: "Show or switch whether the stream endpoint cuts silence trims: on, off or default (config file)",
+			Run:         c.trimStream,
+		},
+		"backfill-loudness": {
+			Description: "Analyze loudness and ReplayGain of tracks that have not been measured yet",
+			Run:         c.backfillLoudness,
+		},
+		"backfill-tempo": {
+			Description: "Detect BPM and musical key of tracks that have not been analyzed yet",
+			Run:         c.backfillTempo,
+		},
+		"backfill-library": {
+			Description: "Link the artist and album names of existing tracks to artist and album records",
+			Run:         c.backfillLibrary,
+		},
+		"refresh-playlists": {
+			Description: "Store the tracks currently matching the rules of every materialized smart playlist",
+			Run:         c.refreshPlaylists,
+		},
+		"export-ratings": {
+			Description: "Write the track ratings of one or every user to a CSV file",
+			Run:         c.exportRatings,
+		},
+		"import-ratings": {
+			Description: "Rate tracks from a CSV file written by export-ratings, matching tracks by ID, ISRC, path or title",
+			Run:         c.importRatings,
+		},
+		"import": {
+			Description: "Import every audio file below a directory, resuming an interrupted run",
+			Run:         c.importLibrary,
+		},
+	}
+
+	return c
+}
+
+// Start runs the command named by the first argument in the background and stops the app when it returns
+func Start(lifecycle fx.Lifecycle, shutdowner fx.Shutdowner, c *CLI) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if len(os.Args) < 2 {
+				c.usage()
+				return fmt.Errorf("missing command")
+			}
+
+			cmd, ok := c.commands[os.Args[1]]
+			if !ok {
+				c.usage()
+				return fmt.Errorf("unknown command %q", os.Args[1])
+			}
+
+			c.DB.ConnectDatabase()
+
+			go func() {
+				defer close(done)
+
+				code := 0
+				if err := cmd.Run(ctx, os.Args[2:]); err != nil {
+					c.Log.Error().Err(err).Msgf("Command %s failed", os.Args[1])
+					code = 1
+				}
+
+				_ = shutdowner.Shutdown(fx.ExitCode(code))
+			}()
+
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			// let an interrupted command finish its current batch before the database goes away
+			cancel()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+			}
+
+			c.DB.ShutdownDatabase()
+
+			return nil
+		},
+	})
+}
+
+func (c *CLI) usage() {
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("Usage: cli <command> [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, name := range names {
+		fmt.Printf("  %-20s %s\n", name, c.commands[name].Description)
+	}
+}
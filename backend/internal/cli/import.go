@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/audio"
+
+	ingestService "git.dev.siap.id/kukuhkkh/app-music/app/module/ingest/service"
+)
+
+// importReport counts the outcome of an import run, it is shared by the import workers
+type importReport struct {
+	mu          sync.Mutex
+	imported    int
+	bytes       int64
+	skipped     int
+	unsupported int
+	albums      map[string]bool
+	artists     map[string]bool
+	failures    []string
+}
+
+func (r *importReport) add(t *schema.Track) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.imported++
+	r.bytes += t.FileSize
+	r.artists[t.Artist] = true
+	if t.Album != nil && *t.Album != "" {
+		r.albums[t.Artist+"\x00"+*t.Album] = true
+	}
+}
+
+func (r *importReport) fail(path string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures = append(r.failures, fmt.Sprintf("%s: %v", path, err))
+}
+
+func (r *importReport) print(root string, elapsed time.Duration) {
+	fmt.Printf("import %s: %d imported (%.1f MB), %d already imported, %d not audio, %d failed in %s\n",
+		root, r.imported, float64(r.bytes)/(1<<20), r.skipped, r.unsupported, len(r.failures), elapsed.Round(time.Second))
+	fmt.Printf("  %d albums by %d artists\n", len(r.albums), len(r.artists))
+
+	if len(r.failures) == 0 {
+		return
+	}
+
+	sort.Strings(r.failures)
+	fmt.Println("failed:")
+	for _, f := range r.failures {
+		fmt.Println("  " + f)
+	}
+}
+
+// importLibrary walks a directory tree and imports every audio file as a track of one user.
+// Files are uploaded and analyzed concurrently and their tracks inserted in batches. Files
+// already imported from the same path are skipped, so an interrupted run resumes where it stopped.
+func (c *CLI) importLibrary(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	userID := flags.Uint64("user", 0, "ID of the user owning the imported tracks")
+	workers := flags.Int("workers", 4, "number of files uploaded and analyzed at the same time")
+	batch := flags.Int("batch", 50, "number of tracks inserted per query")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 || *userID == 0 {
+		return fmt.Errorf("usage: import -user <id> [-workers n] [-batch n] <dir>")
+	}
+
+	root, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	existing, err := c.TrackRepo.ListSourcePaths(*userID, root)
+	if err != nil {
+		return err
+	}
+
+	done := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		done[p] = true
+	}
+
+	report := &importReport{albums: map[string]bool{}, artists: map[string]bool{}}
+	start := time.Now()
+
+	paths := make(chan string)
+	go func() {
+		defer close(paths)
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				c.Log.Warn().Err(err).Str("path", path).Msg("Cannot read")
+				report.fail(path, err)
+				return nil
+			}
+
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if strings.HasPrefix(d.Name(), ".") || audio.FormatOf("", path) == audio.FormatUnknown {
+				report.unsupported++
+				return nil
+			}
+
+			if done[path] {
+				report.skipped++
+				return nil
+			}
+
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			c.Log.Error().Err(err).Msg("Walk failed")
+		}
+	}()
+
+	prepared := make(chan *schema.Track)
+	var wg sync.WaitGroup
+	for range max(*workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for path := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				track, err := c.TrackService.PrepareImport(ctx, root, path, *userID)
+				if err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+
+					c.Log.Warn().Err(err).Str("path", path).Msg("Import failed")
+					report.fail(path, err)
+					c.recordImportFailure(path, err)
+					continue
+				}
+
+				prepared <- track
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(prepared)
+	}()
+
+	// prepared tracks are saved even when interrupted, so their uploads are not orphaned
+	pending := make([]*schema.Track, 0, *batch)
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+
+		if err := c.TrackService.SaveImports(pending); err != nil {
+			c.Log.Error().Err(err).Int("tracks", len(pending)).Msg("Saving batch failed")
+			for _, t := range pending {
+				report.fail(*t.SourcePath, err)
+				c.recordImportFailure(*t.SourcePath, err)
+			}
+		} else {
+			for _, t := range pending {
+				report.add(t)
+				c.Log.Info().Uint64("track_id", t.ID).Str("path", *t.SourcePath).Msg("Imported")
+			}
+		}
+
+		pending = make([]*schema.Track, 0, *batch)
+	}
+
+	for track := range prepared {
+		pending = append(pending, track)
+		if len(pending) >= *batch {
+			flush()
+		}
+	}
+	flush()
+
+	report.print(root, time.Since(start))
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("import interrupted, run it again to resume: %w", ctx.Err())
+	}
+
+	return nil
+}
+
+func (c *CLI) recordImportFailure(path string, cause error) {
+	failure := &schema.IngestFailure{
+		Source: ingestService.IngestSourceImport,
+		Path:   path,
+		Error:  cause.Error(),
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		failure.FileSize = info.Size()
+		modifiedAt := info.ModTime().Truncate(time.Second)
+		failure.FileModifiedAt = &modifiedAt
+	}
+
+	if err := c.IngestRepo.CreateFailure(failure); err != nil {
+		c.Log.Warn().Err(err).Str("path", path).Msg("Recording failure failed")
+	}
+}
@@ -0,0 +1,16 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+)
+
+func (c *CLI) refreshPlaylists(ctx context.Context, args []string) error {
+	refreshed, failed, err := c.Playlists.RefreshSmartPlaylists(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("refresh-playlists: %d refreshed, %d failed\n", refreshed, failed)
+	return nil
+}
@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/helpers"
+)
+
+// ratingColumns is the header of a ratings file. Imports find the columns by name, so files
+// with extra or reordered columns are accepted.
+var ratingColumns = []string{"user_id", "track_id", "isrc", "artist", "title", "source_path", "stars", "rated_at"}
+
+// ratingMatch keys tracks and rows the same way, a row rates the track with its key
+type ratingMatch struct {
+	track func(t repository.TrackRef) string
+	row   func(row map[string]string) string
+}
+
+// ratingMatches are the ways import-ratings finds the track of a row
+var ratingMatches = map[string]ratingMatch{
+	"id": {
+		track: func(t repository.TrackRef) string { return strconv.FormatUint(t.ID, 10) },
+		row:   func(row map[string]string) string { return row["track_id"] },
+	},
+	"isrc": {
+		track: func(t repository.TrackRef) string { return strings.ToUpper(helpers.Value(t.ISRC)) },
+		row:   func(row map[string]string) string { return strings.ToUpper(row["isrc"]) },
+	},
+	"path": {
+		track: func(t repository.TrackRef) string { return helpers.Value(t.SourcePath) },
+		row:   func(row map[string]string) string { return row["source_path"] },
+	},
+	"title": {
+		track: func(t repository.TrackRef) string { return titleKey(t.Artist, t.Title) },
+		row:   func(row map[string]string) string { return titleKey(row["artist"], row["title"]) },
+	},
+}
+
+// exportRatings writes the ratings of one or every user as CSV, to a file or to stdout with "-"
+func (c *CLI) exportRatings(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export-ratings", flag.ContinueOnError)
+	userID := flags.Uint64("user", 0, "only export the ratings of this user")
+	batch := flags.Int("batch", 500, "number of ratings loaded per query")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: export-ratings [-user id] [-batch n] <file.csv|->")
+	}
+
+	out := os.Stdout
+	if name := flags.Arg(0); name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := csv.NewWriter(out)
+	if err := w.Write(ratingColumns); err != nil {
+		return err
+	}
+
+	var afterID uint64
+	var exported int
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		ratings, err := c.TrackRepo.ListRatings(*userID, afterID, *batch)
+		if err != nil {
+			return err
+		}
+
+		if len(ratings) == 0 {
+			break
+		}
+
+		for _, r := range ratings {
+			afterID = r.ID
+			err := w.Write([]string{
+				strconv.FormatUint(r.UserID, 10),
+				strconv.FormatUint(r.TrackID, 10),
+				helpers.Value(r.Track.ISRC),
+				r.Track.Artist,
+				r.Track.Title,
+				helpers.Value(r.Track.SourcePath),
+				strconv.FormatFloat(r.Stars, 'f', -1, 64),
+				r.UpdatedAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				return err
+			}
+			exported++
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "export-ratings: %d exported\n", exported)
+	return nil
+}
+
+// importRatings reads a file written by export-ratings, e.g. of another library, and rates the
+// matching tracks. When several tracks share a key the oldest is rated. Ratings already present
+// are replaced, rows whose track cannot be found are reported and skipped.
+func (c *CLI) importRatings(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import-ratings", flag.ContinueOnError)
+	userID := flags.Uint64("user", 0, "rate as this user instead of the user_id column")
+	match := flags.String("match", "id", "how rows find their track: id, isrc, path (source path) or title (artist and title)")
+	batch := flags.Int("batch", 500, "number of ratings saved per query")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	matcher, ok := ratingMatches[*match]
+	if flags.NArg() != 1 || !ok {
+		return fmt.Errorf("usage: import-ratings [-user id] [-match id|isrc|path|title] [-batch n] <file.csv|->")
+	}
+
+	in := os.Stdin
+	if name := flags.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	refs, err := c.TrackRepo.ListTrackRefs()
+	if err != nil {
+		return err
+	}
+
+	tracks := make(map[string]uint64, len(refs))
+	for _, ref := range refs {
+		if k := matcher.track(ref); k != "" {
+			if _, dup := tracks[k]; !dup {
+				tracks[k] = ref.ID
+			}
+		}
+	}
+
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	if !slices.Contains(header, "stars") {
+		return fmt.Errorf("missing stars column")
+	}
+	if !slices.Contains(header, "user_id") && *userID == 0 {
+		return fmt.Errorf("missing user_id column, pass -user to rate as one user")
+	}
+
+	step := 1.0
+	if c.Config.Track.Ratings.HalfStars {
+		step = 0.5
+	}
+
+	// users caches whether the user ids of the rows exist, rows of unknown users are invalid
+	// instead of failing the batch they are saved with
+	users := make(map[uint64]bool)
+
+	var pending []schema.Rating
+	var imported, unmatched, invalid int
+	var failures []string
+
+	save := func() error {
+		if err := c.TrackRepo.SaveRatings(pending); err != nil {
+			return fmt.Errorf("save ratings: %w", err)
+		}
+		imported += len(pending)
+		pending = pending[:0]
+		return nil
+	}
+
+	for line := 2; ; line++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		row := make(map[string]string, len(header))
+		for i, value := range record {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(value)
+			}
+		}
+
+		rating, err := ratingRow(row, *userID, step)
+		if err != nil {
+			invalid++
+			failures = append(failures, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+
+		exists, checked := users[rating.UserID]
+		if !checked {
+			if exists, err = c.TrackRepo.UserExists(rating.UserID); err != nil {
+				return err
+			}
+			users[rating.UserID] = exists
+		}
+		if !exists {
+			invalid++
+			failures = append(failures, fmt.Sprintf("line %d: no user with id %d", line, rating.UserID))
+			continue
+		}
+
+		k := matcher.row(row)
+		trackID, ok := tracks[k]
+		if k == "" || !ok {
+			unmatched++
+			failures = append(failures, fmt.Sprintf("line %d: no track with %s %q", line, *match, k))
+			continue
+		}
+
+		rating.TrackID = trackID
+		pending = append(pending, rating)
+
+		if len(pending) >= *batch {
+			if err := save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("import-ratings: %d imported, %d without track, %d invalid\n", imported, unmatched, invalid)
+	for _, f := range failures {
+		fmt.Println("  " + f)
+	}
+
+	return nil
+}
+
+// ratingRow reads the user, stars and rating date of a row, the track is matched by the caller.
+// Stars must be a multiple of step, 1 or 0.5 when half stars are enabled.
+func ratingRow(row map[string]string, userID uint64, step float64) (schema.Rating, error) {
+	rating := schema.Rating{UserID: userID}
+
+	if rating.UserID == 0 {
+		id, err := strconv.ParseUint(row["user_id"], 10, 64)
+		if err != nil || id == 0 {
+			return rating, fmt.Errorf("invalid user_id %q", row["user_id"])
+		}
+		rating.UserID = id
+	}
+
+	stars, err := strconv.ParseFloat(row["stars"], 64)
+	if err != nil || stars < 0 || stars > 5 || math.Mod(stars, step) != 0 {
+		return rating, fmt.Errorf("invalid stars %q", row["stars"])
+	}
+	rating.Stars = stars
+
+	if ratedAt := row["rated_at"]; ratedAt != "" {
+		t, err := time.Parse(time.RFC3339, ratedAt)
+		if err != nil {
+			return rating, fmt.Errorf("invalid rated_at %q", ratedAt)
+		}
+		rating.CreatedAt, rating.UpdatedAt = t, t
+	}
+
+	return rating, nil
+}
+
+// titleKey matches tracks by artist and title regardless of case and surrounding whitespace
+func titleKey(artist string, title string) string {
+	if title = strings.TrimSpace(title); title == "" {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(artist) + "\x00" + title)
+}
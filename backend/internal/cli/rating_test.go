@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"git.dev.siap.id/kukuhkkh/app-music/app/database/schema"
+	"git.dev.siap.id/kukuhkkh/app-music/app/module/track/repository"
+	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
+)
+
+func TestRatingRow(t *testing.T) {
+	ratedAt := time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		row    map[string]string
+		userID uint64
+		step   float64
+		want   schema.Rating
+		err    string
+	}{
+		{
+			name: "whole star",
+			row:  map[string]string{"user_id": "3", "stars": "4"},
+			step: 1,
+			want: schema.Rating{UserID: 3, Stars: 4},
+		},
+		{
+			name: "half star when enabled",
+			row:  map[string]string{"user_id": "3", "stars": "3.5"},
+			step: 0.5,
+			want: schema.Rating{UserID: 3, Stars: 3.5},
+		},
+		{
+			name: "half star when disabled",
+			row:  map[string]string{"user_id": "3", "stars": "3.5"},
+			step: 1,
+			err:  `invalid stars "3.5"`,
+		},
+		{
+			name:   "user flag replaces the column",
+			row:    map[string]string{"user_id": "3", "stars": "0"},
+			userID: 9,
+			step:   1,
+			want:   schema.Rating{UserID: 9},
+		},
+		{
+			name: "rating date",
+			row:  map[string]string{"user_id": "3", "stars": "5", "rated_at": "2026-05-01T08:30:00Z"},
+			step: 1,
+			want: schema.Rating{UserID: 3, Stars: 5, Base: schema.Base{CreatedAt: ratedAt, UpdatedAt: ratedAt}},
+		},
+		{
+			name: "missing user",
+			row:  map[string]string{"stars": "2"},
+			step: 1,
+			err:  `invalid user_id ""`,
+		},
+		{
+			name: "user zero",
+			row:  map[string]string{"user_id": "0", "stars": "2"},
+			step: 1,
+			err:  `invalid user_id "0"`,
+		},
+		{
+			name: "more than five stars",
+			row:  map[string]string{"user_id": "3", "stars": "5.5"},
+			step: 0.5,
+			err:  `invalid stars "5.5"`,
+		},
+		{
+			name: "negative stars",
+			row:  map[string]string{"user_id": "3", "stars": "-1"},
+			step: 1,
+			err:  `invalid stars "-1"`,
+		},
+		{
+			name: "quarter star",
+			row:  map[string]string{"user_id": "3", "stars": "2.25"},
+			step: 0.5,
+			err:  `invalid stars "2.25"`,
+		},
+		{
+			name: "bad rating date",
+			row:  map[string]string{"user_id": "3", "stars": "1", "rated_at": "yesterday"},
+			step: 1,
+			err:  `invalid rated_at "yesterday"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ratingRow(tt.row, tt.userID, tt.step)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ratingRow() err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ratingRow() err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ratingRow() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// ratingRepo keeps the tracks, users and saved ratings of import-ratings in memory, other
+// methods of the embedded interface are not used
+type ratingRepo struct {
+	repository.TrackRepository
+
+	refs  []repository.TrackRef
+	users []uint64
+	saved []schema.Rating
+}
+
+func (r *ratingRepo) ListTrackRefs() ([]repository.TrackRef, error) {
+	return r.refs, nil
+}
+
+func (r *ratingRepo) UserExists(id uint64) (bool, error) {
+	for _, u := range r.users {
+		if u == id {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *ratingRepo) SaveRatings(ratings []schema.Rating) error {
+	r.saved = append(r.saved, ratings...)
+	return nil
+}
+
+func TestImportRatings(t *testing.T) {
+	isrc := "USABC2600001"
+	path := "/music/b.flac"
+	refs := []repository.TrackRef{
+		{ID: 1, Title: "First", Artist: "A", ISRC: &isrc},
+		{ID: 2, Title: "Second", Artist: "B", SourcePath: &path},
+		{ID: 3, Title: "First", Artist: "A"},
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		csv  string
+		want []schema.Rating
+		err  bool
+	}{
+		{
+			name: "by id",
+			args: []string{"-match", "id"},
+			csv:  "user_id,track_id,stars\n1,1,4\n1,2,3\n1,99,5\n",
+			want: []schema.Rating{{UserID: 1, TrackID: 1, Stars: 4}, {UserID: 1, TrackID: 2, Stars: 3}},
+		},
+		{
+			name: "by isrc ignoring case and reordered columns",
+			args: []string{"-match", "isrc"},
+			csv:  "stars,isrc,user_id\n2,usabc2600001,1\n",
+			want: []schema.Rating{{UserID: 1, TrackID: 1, Stars: 2}},
+		},
+		{
+			name: "by source path",
+			args: []string{"-match", "path"},
+			csv:  "user_id,source_path,stars\n1,/music/b.flac,1\n",
+			want: []schema.Rating{{UserID: 1, TrackID: 2, Stars: 1}},
+		},
+		{
+			name: "by title rates the oldest track",
+			args: []string{"-match", "title"},
+			csv:  "user_id,artist,title,stars\n1, a ,FIRST,5\n",
+			want: []schema.Rating{{UserID: 1, TrackID: 1, Stars: 5}},
+		},
+		{
+			name: "unknown users and half stars are skipped",
+			args: []string{"-match", "id"},
+			csv:  "user_id,track_id,stars\n1,1,4\n7,1,4\n1,2,3.5\n",
+			want: []schema.Rating{{UserID: 1, TrackID: 1, Stars: 4}},
+		},
+		{
+			name: "user flag without user column",
+			args: []string{"-user", "2", "-match", "id"},
+			csv:  "track_id,stars\n3,1\n",
+			want: []schema.Rating{{UserID: 2, TrackID: 3, Stars: 1}},
+		},
+		{
+			name: "missing stars column",
+			args: []string{"-match", "id"},
+			csv:  "user_id,track_id\n1,1\n",
+			err:  true,
+		},
+		{
+			name: "missing user column",
+			args: []string{"-match", "id"},
+			csv:  "track_id,stars\n1,1\n",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "ratings.csv")
+			if err := os.WriteFile(file, []byte(tt.csv), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			repo := &ratingRepo{refs: refs, users: []uint64{1, 2}}
+			c := &CLI{Config: &config.Config{}, TrackRepo: repo}
+
+			err := c.importRatings(context.Background(), append(tt.args, file))
+			if tt.err {
+				if err == nil {
+					t.Fatal("importRatings() succeeded, want an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("importRatings() err = %v", err)
+			}
+			if !reflect.DeepEqual(repo.saved, tt.want) {
+				t.Errorf("importRatings() saved %+v, want %+v", repo.saved, tt.want)
+			}
+		})
+	}
+}
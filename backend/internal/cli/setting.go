@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	settingRepository "git.dev.siap.id/kukuhkkh/app-music/app/module/setting/repository"
+)
+
+// trimStream switches cutting silence trims on the stream endpoint without editing the config
+// file or restarting the server. "default" goes back to track.silence.trim_stream.
+func (c *CLI) trimStream(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: trim-stream [on|off|default]")
+	}
+
+	if len(args) == 1 {
+		var err error
+		switch args[0] {
+		case "on":
+			err = c.Settings.SaveSetting(settingRepository.TrimStream, "true")
+		case "off":
+			err = c.Settings.SaveSetting(settingRepository.TrimStream, "false")
+		case "default":
+			err = c.Settings.DeleteSetting(settingRepository.TrimStream)
+		default:
+			return fmt.Errorf("usage: trim-stream [on|off|default]")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	value, found, err := c.Settings.FindSetting(settingRepository.TrimStream)
+	if err != nil {
+		return err
+	}
+
+	enabled, source := c.Config.Track.Silence.TrimStream, "config"
+	if found {
+		enabled, source = value == "true", "setting"
+	}
+
+	state := "off"
+	if enabled {
+		state = "on"
+	}
+
+	fmt.Printf("trim-stream: %s (%s)\n", state, source)
+	return nil
+}
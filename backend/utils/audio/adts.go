@@ -0,0 +1,94 @@
+package audio
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+var adtsSampleRates = [16]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// ADTSFrame is a parsed ADTS AAC frame header
+type ADTSFrame struct {
+	SampleRate int
+	Channels   int
+	Size       int // bytes including the header
+	Samples    int // samples per channel
+}
+
+// ParseADTSFrame decodes the 7 byte header at the start of h
+func ParseADTSFrame(h []byte) (ADTSFrame, bool) {
+	var f ADTSFrame
+	if len(h) < 7 || h[0] != 0xFF || h[1]&0xF6 != 0xF0 {
+		return f, false
+	}
+
+	rateIdx := (h[2] >> 2) & 0x0F
+	if rateIdx > 12 {
+		return f, false
+	}
+
+	f.SampleRate = adtsSampleRates[rateIdx]
+	f.Channels = int(h[2]&0x01)<<2 | int(h[3]>>6)
+	f.Size = int(h[3]&0x03)<<11 | int(h[4])<<3 | int(h[5]>>5)
+	f.Samples = (int(h[6]&0x03) + 1) * 1024
+
+	headerSize := 7
+	if h[1]&0x01 == 0 {
+		headerSize = 9 // CRC present
+	}
+
+	if f.Size < headerSize {
+		return f, false
+	}
+
+	return f, true
+}
+
+// WalkADTSFrames calls fn with the offset and header of every ADTS frame, starting after an
+// ID3v2 tag. It stops at the first invalid header, at the end of the stream or when fn returns false.
+func WalkADTSFrames(r io.ReadSeeker, fn func(offset int64, f ADTSFrame) bool) error {
+	offset, err := SkipID3v2(r)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		h, err := br.Peek(7)
+		if err != nil {
+			return nil
+		}
+
+		f, ok := ParseADTSFrame(h)
+		if !ok || !fn(offset, f) {
+			return nil
+		}
+
+		n, _ := br.Discard(f.Size)
+		if n < f.Size {
+			return nil
+		}
+		offset += int64(n)
+	}
+}
+
+func adtsDuration(r io.ReadSeeker) (time.Duration, error) {
+	var samples int64
+	var rate int
+
+	err := WalkADTSFrames(r, func(_ int64, f ADTSFrame) bool {
+		rate = f.SampleRate
+		samples += int64(f.Samples)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if rate == 0 {
+		return 0, ErrNoDuration
+	}
+
+	return samplesToDuration(samples, rate), nil
+}
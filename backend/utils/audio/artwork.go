@@ -0,0 +1,34 @@
+package audio
+
+import (
+	"errors"
+	"io"
+
+	"github.com/dhowden/tag"
+)
+
+// Picture is an image embedded in the tags of an audio file
+type Picture struct {
+	MIMEType string
+	Data     []byte
+}
+
+// ExtractPicture returns the cover image stored in the tags of r (ID3 APIC, FLAC PICTURE,
+// MP4 covr or Vorbis METADATA_BLOCK_PICTURE). It returns nil when the file carries none.
+func ExtractPicture(r io.ReadSeeker) (*Picture, error) {
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		if errors.Is(err, tag.ErrNoTagsFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	p := m.Picture()
+	if p == nil || len(p.Data) == 0 {
+		return nil, nil
+	}
+
+	return &Picture{MIMEType: p.MIMEType, Data: p.Data}, nil
+}
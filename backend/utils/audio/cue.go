@@ -0,0 +1,205 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidCue is returned for CUE sheets without playable tracks
+var ErrInvalidCue = errors.New("invalid CUE sheet")
+
+// cueFramesPerSecond is the CD frame rate used by INDEX timestamps
+const cueFramesPerSecond = 75
+
+// CueSheet is a parsed single-file CUE sheet
+type CueSheet struct {
+	Title     string
+	Performer string
+	File      string
+	Tracks    []CueTrack
+}
+
+// CueTrack is one TRACK entry. End is zero for the last track, which plays to the end of the file.
+type CueTrack struct {
+	Number     int
+	Title      string
+	Performer  string
+	Songwriter string
+	ISRC       string
+	Start      time.Duration
+	End        time.Duration
+}
+
+// ParseCue reads a CUE sheet. Sheets that are not valid UTF-8 are decoded as Latin-1,
+// which is what most rippers write. Only sheets referencing a single FILE are supported.
+func ParseCue(data []byte) (*CueSheet, error) {
+	sheet := &CueSheet{}
+	var current *CueTrack
+
+	scanner := bufio.NewScanner(strings.NewReader(CueText(data)))
+	for line := 1; scanner.Scan(); line++ {
+		fields := splitCueLine(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		keyword := strings.ToUpper(fields[0])
+		arg := ""
+		if len(fields) > 1 {
+			arg = fields[1]
+		}
+
+		switch keyword {
+		case "FILE":
+			if sheet.File != "" && sheet.File != arg {
+				return nil, fmt.Errorf("%w: multiple FILE entries are not supported", ErrInvalidCue)
+			}
+			sheet.File = arg
+
+		case "TRACK":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: bad track number", ErrInvalidCue, line)
+			}
+
+			sheet.Tracks = append(sheet.Tracks, CueTrack{Number: n, Start: -1})
+			current = &sheet.Tracks[len(sheet.Tracks)-1]
+
+		case "TITLE", "PERFORMER", "SONGWRITER":
+			setCueField(sheet, current, keyword, arg)
+
+		case "ISRC":
+			if current != nil {
+				current.ISRC = arg
+			}
+
+		case "INDEX":
+			if current == nil || len(fields) < 3 || arg != "01" {
+				continue
+			}
+
+			ts, err := parseCueTime(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCue, line, err)
+			}
+			current.Start = ts
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return sheet, sheet.resolve()
+}
+
+// CueText returns the sheet as UTF-8 without byte order mark
+func CueText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+
+	return string(runes)
+}
+
+func setCueField(sheet *CueSheet, current *CueTrack, keyword, value string) {
+	switch {
+	case current == nil && keyword == "TITLE":
+		sheet.Title = value
+	case current == nil && keyword == "PERFORMER":
+		sheet.Performer = value
+	case current == nil:
+	case keyword == "TITLE":
+		current.Title = value
+	case keyword == "PERFORMER":
+		current.Performer = value
+	case keyword == "SONGWRITER":
+		current.Songwriter = value
+	}
+}
+
+// resolve drops tracks without INDEX 01 and ends every track where the next one starts
+func (c *CueSheet) resolve() error {
+	tracks := c.Tracks[:0]
+	for _, t := range c.Tracks {
+		if t.Start >= 0 {
+			tracks = append(tracks, t)
+		}
+	}
+	c.Tracks = tracks
+
+	if len(c.Tracks) == 0 {
+		return fmt.Errorf("%w: no tracks with INDEX 01", ErrInvalidCue)
+	}
+
+	for i := range c.Tracks[:len(c.Tracks)-1] {
+		c.Tracks[i].End = c.Tracks[i+1].Start
+		if c.Tracks[i].End <= c.Tracks[i].Start {
+			return fmt.Errorf("%w: track %d does not start after track %d", ErrInvalidCue, c.Tracks[i+1].Number, c.Tracks[i].Number)
+		}
+	}
+
+	return nil
+}
+
+// parseCueTime converts mm:ss:ff, where ff counts 1/75 second frames
+func parseCueTime(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+
+	var v [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("bad timestamp %q", s)
+		}
+		v[i] = n
+	}
+
+	frames := (v[0]*60+v[1])*cueFramesPerSecond + v[2]
+	return time.Duration(frames) * time.Second / cueFramesPerSecond, nil
+}
+
+// splitCueLine splits a line into whitespace separated fields, keeping quoted strings together
+func splitCueLine(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	inQuote, inField := false, false
+
+	for _, r := range strings.TrimSpace(line) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inField = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if inField {
+		fields = append(fields, cur.String())
+	}
+
+	return fields
+}
@@ -0,0 +1,136 @@
+package audio
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCue(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *CueSheet
+		err  error
+	}{
+		{
+			name: "album",
+			data: `REM GENRE Rock
+PERFORMER "The Band"
+TITLE "Live Album"
+FILE "album.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Intro"
+    PERFORMER "The Band"
+    ISRC USABC9900001
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Second Song"
+    SONGWRITER "Someone"
+    INDEX 00 03:58:60
+    INDEX 01 04:00:30
+  TRACK 03 AUDIO
+    TITLE "Outro"
+    INDEX 01 10:01:00
+`,
+			want: &CueSheet{
+				Title:     "Live Album",
+				Performer: "The Band",
+				File:      "album.flac",
+				Tracks: []CueTrack{
+					{Number: 1, Title: "Intro", Performer: "The Band", ISRC: "USABC9900001", Start: 0, End: 4*time.Minute + 400*time.Millisecond},
+					{Number: 2, Title: "Second Song", Songwriter: "Someone", Start: 4*time.Minute + 400*time.Millisecond, End: 10*time.Minute + time.Second},
+					{Number: 3, Title: "Outro", Start: 10*time.Minute + time.Second},
+				},
+			},
+		},
+		{
+			name: "byte order mark and lower case keywords",
+			data: "\xEF\xBB\xBFfile \"a.wav\" WAVE\ntrack 1 audio\ntitle \"Ünïcode\"\nindex 01 00:00:00\n",
+			want: &CueSheet{File: "a.wav", Tracks: []CueTrack{{Number: 1, Title: "Ünïcode"}}},
+		},
+		{
+			name: "latin-1",
+			data: "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nTITLE \"Caf\xe9\"\nINDEX 01 00:00:00\n",
+			want: &CueSheet{File: "a.wav", Tracks: []CueTrack{{Number: 1, Title: "Café"}}},
+		},
+		{
+			name: "tracks without index 01 are dropped",
+			data: "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 00 00:00:00\nTRACK 02 AUDIO\nINDEX 01 00:02:00\n",
+			want: &CueSheet{File: "a.wav", Tracks: []CueTrack{{Number: 2, Start: 2 * time.Second}}},
+		},
+		{
+			name: "multiple files",
+			data: "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nFILE \"b.wav\" WAVE\nTRACK 02 AUDIO\nINDEX 01 00:00:00\n",
+			err:  ErrInvalidCue,
+		},
+		{
+			name: "no tracks",
+			data: "FILE \"a.wav\" WAVE\n",
+			err:  ErrInvalidCue,
+		},
+		{
+			name: "tracks out of order",
+			data: "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 01:00:00\nTRACK 02 AUDIO\nINDEX 01 00:30:00\n",
+			err:  ErrInvalidCue,
+		},
+		{
+			name: "bad timestamp",
+			data: "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00\n",
+			err:  ErrInvalidCue,
+		},
+		{
+			name: "bad track number",
+			data: "FILE \"a.wav\" WAVE\nTRACK one AUDIO\nINDEX 01 00:00:00\n",
+			err:  ErrInvalidCue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCue([]byte(tt.data))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("ParseCue() err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseCue() err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCueTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{in: "00:00:00", want: 0, ok: true},
+		{in: "01:02:03", want: time.Minute + 2*time.Second + 40*time.Millisecond, ok: true},
+		{in: "00:00:74", want: 74 * time.Second / 75, ok: true},
+		{in: "99:59:74", want: 99*time.Minute + 59*time.Second + 74*time.Second/75, ok: true},
+		{in: "00:00"},
+		{in: "00:-1:00"},
+		{in: "aa:00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseCueTime(tt.in)
+			if (err == nil) != tt.ok {
+				t.Fatalf("parseCueTime(%q) err = %v, want ok %v", tt.in, err, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("parseCueTime(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/jfreymuth/oggvorbis"
+	"github.com/mewkiz/flac"
+)
+
+// ErrUnsupportedFormat is returned when no decoder exists for a container
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// Decoder streams interleaved PCM samples normalized to [-1, 1]
+type Decoder interface {
+	SampleRate() int
+	Channels() int
+	// Read fills buf with whole sample frames and returns the number of samples written
+	Read(buf []float32) (int, error)
+}
+
+// NewDecoder opens a decoder for r. The format is sniffed from the stream when f is unknown.
+func NewDecoder(r io.ReadSeeker, f Format) (Decoder, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if f == FormatUnknown {
+		var err error
+		if f, err = Sniff(r); err != nil {
+			return nil, err
+		}
+	}
+
+	switch f {
+	case FormatWAV:
+		return newWAVDecoder(r)
+	case FormatMP3:
+		d, err := mp3.NewDecoder(r)
+		if err != nil {
+			return nil, err
+		}
+		return &mp3Decoder{d: d}, nil
+	case FormatFLAC:
+		s, err := flac.New(r)
+		if err != nil {
+			return nil, err
+		}
+		return &flacDecoder{s: s, scale: 1 / float32(int64(1)<<(s.Info.BitsPerSample-1))}, nil
+	case FormatOGG:
+		d, err := oggvorbis.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, f)
+}
+
+// Sink consumes decoded interleaved sample blocks
+type Sink interface {
+	Write(block []float32)
+}
+
+// Process decodes the whole stream and hands every block to each sink in order
+func Process(dec Decoder, sinks ...Sink) error {
+	buf := make([]float32, 4096*dec.Channels())
+
+	for {
+		n, err := dec.Read(buf)
+		if n > 0 {
+			for _, s := range sinks {
+				s.Write(buf[:n])
+			}
+		}
+
+		if err == io.EOF || (err == nil && n == 0) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type wavDecoder struct {
+	info  *WAVInfo
+	r     io.Reader
+	width int
+	buf   []byte
+}
+
+func newWAVDecoder(r io.ReadSeeker) (*wavDecoder, error) {
+	info, err := ParseWAV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(info.DataOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return &wavDecoder{
+		info:  info,
+		r:     io.LimitReader(r, info.DataSize),
+		width: info.BlockAlign / info.Channels,
+	}, nil
+}
+
+func (d *wavDecoder) SampleRate() int { return d.info.SampleRate }
+func (d *wavDecoder) Channels() int   { return d.info.Channels }
+
+func (d *wavDecoder) Read(out []float32) (int, error) {
+	frames := len(out) / d.info.Channels
+	need := frames * d.info.BlockAlign
+	if cap(d.buf) < need {
+		d.buf = make([]byte, need)
+	}
+
+	n, err := io.ReadFull(d.r, d.buf[:need])
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+
+	n -= n % d.info.BlockAlign
+	if n == 0 && err == nil {
+		err = io.EOF
+	}
+
+	samples := n / d.width
+	for i := 0; i < samples; i++ {
+		out[i] = d.sample(d.buf[i*d.width:])
+	}
+
+	return samples, err
+}
+
+func (d *wavDecoder) sample(b []byte) float32 {
+	if d.info.IsFloat() {
+		if d.width == 8 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+
+	switch d.width {
+	case 1:
+		// 8-bit WAV is unsigned
+		return float32(int(b[0])-128) / 128
+	case 2:
+		return float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 3:
+		v := int32(b[0])<<8 | int32(b[1])<<16 | int32(b[2])<<24
+		return float32(v>>8) / (1 << 23)
+	default:
+		return float32(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// mp3Decoder adapts go-mp3, which always yields 16-bit little endian stereo
+type mp3Decoder struct {
+	d   *mp3.Decoder
+	buf []byte
+}
+
+func (d *mp3Decoder) SampleRate() int { return d.d.SampleRate() }
+func (d *mp3Decoder) Channels() int   { return 2 }
+
+func (d *mp3Decoder) Read(out []float32) (int, error) {
+	need := (len(out) / 2) * 4
+	if cap(d.buf) < need {
+		d.buf = make([]byte, need)
+	}
+
+	n, err := io.ReadFull(d.d, d.buf[:need])
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+
+	n -= n % 4
+	if n == 0 && err == nil {
+		err = io.EOF
+	}
+
+	samples := n / 2
+	for i := 0; i < samples; i++ {
+		out[i] = float32(int16(binary.LittleEndian.Uint16(d.buf[i*2:]))) / (1 << 15)
+	}
+
+	return samples, err
+}
+
+type flacDecoder struct {
+	s       *flac.Stream
+	scale   float32
+	pending []float32
+}
+
+func (d *flacDecoder) SampleRate() int { return int(d.s.Info.SampleRate) }
+func (d *flacDecoder) Channels() int   { return int(d.s.Info.NChannels) }
+
+func (d *flacDecoder) Read(out []float32) (int, error) {
+	for len(d.pending) == 0 {
+		frame, err := d.s.ParseNext()
+		if err != nil {
+			return 0, err
+		}
+
+		channels := len(frame.Subframes)
+		samples := frame.Subframes[0].NSamples
+		d.pending = d.pending[:0]
+		for i := 0; i < samples; i++ {
+			for ch := 0; ch < channels; ch++ {
+				d.pending = append(d.pending, float32(frame.Subframes[ch].Samples[i])*d.scale)
+			}
+		}
+	}
+
+	n := copy(out, d.pending)
+	d.pending = d.pending[n:]
+
+	return n, nil
+}
@@ -0,0 +1,323 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mewkiz/flac"
+)
+
+// ErrNoDuration is returned when the container does not reveal its length
+var ErrNoDuration = errors.New("duration not available")
+
+// Duration computes the exact playing time from container data without decoding audio:
+// Xing/VBRI/LAME headers or a frame scan for MP3, STREAMINFO for FLAC, the data chunk for WAV,
+// the last granule position for Ogg, mvhd/mdhd atoms for MP4 and a frame scan for ADTS AAC.
+func Duration(r io.ReadSeeker, f Format) (time.Duration, error) {
+	if f == FormatUnknown {
+		var err error
+		if f, err = Sniff(r); err != nil {
+			return 0, err
+		}
+	}
+
+	switch f {
+	case FormatMP3:
+		info, err := ParseMP3(r)
+		if err != nil {
+			return 0, err
+		}
+		return samplesToDuration(info.Samples(), info.SampleRate), nil
+
+	case FormatFLAC:
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		s, err := flac.New(r)
+		if err != nil {
+			return 0, err
+		}
+		if s.Info.NSamples == 0 {
+			return 0, ErrNoDuration
+		}
+		return samplesToDuration(int64(s.Info.NSamples), int(s.Info.SampleRate)), nil
+
+	case FormatWAV:
+		info, err := ParseWAV(r)
+		if err != nil {
+			return 0, err
+		}
+		return samplesToDuration(info.Frames(), info.SampleRate), nil
+
+	case FormatOGG:
+		return oggDuration(r)
+
+	case FormatMP4:
+		return mp4Duration(r)
+
+	case FormatAAC:
+		return adtsDuration(r)
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrUnsupportedFormat, f)
+}
+
+func samplesToDuration(samples int64, rate int) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(samples * int64(time.Second) / int64(rate))
+}
+
+// oggDuration reads the codec rate from the identification header and the granule
+// position of the last page. Opus granules run at 48 kHz and include the pre-skip.
+func oggDuration(r io.ReadSeeker) (time.Duration, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	head := make([]byte, 28+255+64)
+	n, _ := io.ReadFull(r, head)
+	head = head[:n]
+	if len(head) < 27 || !bytes.HasPrefix(head, []byte("OggS")) {
+		return 0, fmt.Errorf("invalid Ogg file")
+	}
+
+	segments := int(head[26])
+	payload := 27 + segments
+	if len(head) < payload+20 {
+		return 0, fmt.Errorf("invalid Ogg file")
+	}
+
+	packet := head[payload:]
+	var rate int
+	var preSkip int64
+
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		rate = int(binary.LittleEndian.Uint32(packet[12:]))
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		rate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(packet[10:]))
+	case bytes.HasPrefix(packet, []byte("\x7fFLAC")) && len(packet) >= 17+14:
+		// Ogg FLAC mapping: 9 byte prefix, "fLaC", block header, then STREAMINFO
+		si := packet[17:]
+		rate = int(binary.BigEndian.Uint32(si[10:]) >> 12)
+	default:
+		return 0, fmt.Errorf("%w: unknown Ogg codec", ErrUnsupportedFormat)
+	}
+
+	granule, err := lastOggGranule(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return samplesToDuration(max(granule-preSkip, 0), rate), nil
+}
+
+// lastOggGranule scans the tail of the stream backwards for the last page with a granule position
+func lastOggGranule(r io.ReadSeeker) (int64, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	for window := int64(64 * 1024); ; window *= 4 {
+		start := max(size-window, 0)
+		if _, err := r.Seek(start, io.SeekStart); err != nil {
+			return 0, err
+		}
+
+		buf := make([]byte, size-start)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, err
+		}
+
+		for i := bytes.LastIndex(buf, []byte("OggS")); i >= 0; i = bytes.LastIndex(buf[:i], []byte("OggS")) {
+			if i+14 > len(buf) {
+				continue
+			}
+
+			granule := int64(binary.LittleEndian.Uint64(buf[i+6:]))
+			if granule >= 0 {
+				return granule, nil
+			}
+		}
+
+		if start == 0 {
+			return 0, ErrNoDuration
+		}
+	}
+}
+
+// mp4Duration prefers the media header of the sound track over the movie header
+func mp4Duration(r io.ReadSeeker) (time.Duration, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	moov, err := findAtom(r, 0, size, "moov")
+	if err != nil {
+		return 0, err
+	}
+
+	var movie time.Duration
+	children, err := listAtoms(r, moov.body, moov.end)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range children {
+		switch a.kind {
+		case "mvhd":
+			if movie, err = readMediaHeader(r, a); err != nil {
+				return 0, err
+			}
+		case "trak":
+			if d, ok := soundTrackDuration(r, a); ok {
+				return d, nil
+			}
+		}
+	}
+
+	if movie == 0 {
+		return 0, ErrNoDuration
+	}
+
+	return movie, nil
+}
+
+type atom struct {
+	kind  string
+	start int64
+	body  int64
+	end   int64
+}
+
+// listAtoms returns the atoms between start and end
+func listAtoms(r io.ReadSeeker, start, end int64) ([]atom, error) {
+	var atoms []atom
+
+	for pos := start; pos+8 <= end; {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		var h [16]byte
+		if _, err := io.ReadFull(r, h[:8]); err != nil {
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(h[:4]))
+		body := pos + 8
+		switch size {
+		case 0:
+			size = end - pos
+		case 1:
+			if _, err := io.ReadFull(r, h[8:16]); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(h[8:16]))
+			body += 8
+		}
+
+		if size < body-pos || pos+size > end {
+			return nil, fmt.Errorf("invalid MP4 atom %q", h[4:8])
+		}
+
+		atoms = append(atoms, atom{kind: string(h[4:8]), start: pos, body: body, end: pos + size})
+		pos += size
+	}
+
+	return atoms, nil
+}
+
+func findAtom(r io.ReadSeeker, start, end int64, kind string) (atom, error) {
+	atoms, err := listAtoms(r, start, end)
+	if err != nil {
+		return atom{}, err
+	}
+
+	for _, a := range atoms {
+		if a.kind == kind {
+			return a, nil
+		}
+	}
+
+	return atom{}, fmt.Errorf("MP4 atom %q not found", kind)
+}
+
+// soundTrackDuration reads mdhd of a trak whose handler is "soun"
+func soundTrackDuration(r io.ReadSeeker, trak atom) (time.Duration, bool) {
+	mdhd, ok := soundMediaHeader(r, trak)
+	if !ok {
+		return 0, false
+	}
+
+	d, err := readMediaHeader(r, mdhd)
+	return d, err == nil && d > 0
+}
+
+// soundMediaHeader returns the mdhd atom of a trak whose handler is "soun"
+func soundMediaHeader(r io.ReadSeeker, trak atom) (atom, bool) {
+	mdia, err := findAtom(r, trak.body, trak.end, "mdia")
+	if err != nil {
+		return atom{}, false
+	}
+
+	hdlr, err := findAtom(r, mdia.body, mdia.end, "hdlr")
+	if err != nil {
+		return atom{}, false
+	}
+
+	// version/flags (4) + pre_defined (4) + handler_type (4)
+	var h [12]byte
+	if _, err := r.Seek(hdlr.body, io.SeekStart); err != nil {
+		return atom{}, false
+	}
+	if _, err := io.ReadFull(r, h[:]); err != nil || string(h[8:12]) != "soun" {
+		return atom{}, false
+	}
+
+	mdhd, err := findAtom(r, mdia.body, mdia.end, "mdhd")
+	return mdhd, err == nil
+}
+
+// readMediaHeader decodes the duration of mvhd/mdhd
+func readMediaHeader(r io.ReadSeeker, a atom) (time.Duration, error) {
+	timescale, duration, err := mediaHeader(r, a)
+	if err != nil {
+		return 0, err
+	}
+
+	if timescale == 0 || duration == 0 || duration == 0xFFFFFFFF {
+		return 0, ErrNoDuration
+	}
+
+	return time.Duration(duration * uint64(time.Second) / uint64(timescale)), nil
+}
+
+// mediaHeader decodes timescale and duration of mvhd/mdhd in version 0 or 1 layout
+func mediaHeader(r io.ReadSeeker, a atom) (timescale uint32, duration uint64, err error) {
+	if _, err := r.Seek(a.body, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	// version 0 bodies are 24 bytes, version 1 bodies 36
+	var h [32]byte
+	if _, err := io.ReadFull(r, h[:min(32, a.end-a.body)]); err != nil {
+		return 0, 0, err
+	}
+
+	if h[0] == 1 {
+		return binary.BigEndian.Uint32(h[20:]), binary.BigEndian.Uint64(h[24:]), nil
+	}
+
+	return binary.BigEndian.Uint32(h[12:]), uint64(binary.BigEndian.Uint32(h[16:])), nil
+}
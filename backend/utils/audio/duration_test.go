@@ -0,0 +1,219 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// mp3Header is an MPEG-1 layer III frame header at 128 kbit/s, 44.1 kHz, stereo: 417 byte frames
+// of 1152 samples
+var mp3Header = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+const mp3FrameSize = 417
+
+// testMP3 returns count silent frames, the first holding info when it is not nil
+func testMP3(count int, info []byte) []byte {
+	var b bytes.Buffer
+	for i := range count {
+		frame := make([]byte, mp3FrameSize)
+		copy(frame, mp3Header)
+		if i == 0 && info != nil {
+			copy(frame[36:], info)
+		}
+		b.Write(frame)
+	}
+
+	return b.Bytes()
+}
+
+// xingHeader declares frames audio frames with the encoder delay and padding of a LAME tag
+func xingHeader(frames uint32, delay int, padding int) []byte {
+	b := []byte("Xing")
+	b = binary.BigEndian.AppendUint32(b, 0x1)
+	b = binary.BigEndian.AppendUint32(b, frames)
+
+	lame := make([]byte, 24)
+	copy(lame, "LAME3.100")
+	lame[21] = byte(delay >> 4)
+	lame[22] = byte(delay&0x0F)<<4 | byte(padding>>8)
+	lame[23] = byte(padding)
+
+	return append(b, lame...)
+}
+
+// id3Tag is an empty ID3v2.4 tag with size bytes of padding
+func id3Tag(size int) []byte {
+	h := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}
+	putSyncsafe(h[6:], size)
+
+	return append(h, make([]byte, size)...)
+}
+
+// testFLAC is a FLAC stream with only a STREAMINFO block
+func testFLAC(rate int, channels int, bits int, samples uint64) []byte {
+	si := make([]byte, 34)
+	binary.BigEndian.PutUint16(si[0:], 4096)
+	binary.BigEndian.PutUint16(si[2:], 4096)
+	binary.BigEndian.PutUint64(si[10:], uint64(rate)<<44|uint64(channels-1)<<41|uint64(bits-1)<<36|samples)
+
+	b := append([]byte("fLaC"), 0x80, 0, 0, 34)
+	return append(b, si...)
+}
+
+// testOgg lays the identification header out on the first page and ends the stream on a page
+// at granule
+func testOgg(head []byte, granule uint64) []byte {
+	first := paginateOgg([][]byte{head}, 1, 0)[0]
+	first.flags, first.granule = oggFirst, 0
+
+	last := paginateOgg([][]byte{make([]byte, 64)}, 1, 1)[0]
+	last.granule = granule
+
+	return append(first.bytes(), last.bytes()...)
+}
+
+func vorbisHead(rate uint32) []byte {
+	b := append([]byte("\x01vorbis"), 0, 0, 0, 0, 2)
+	b = binary.LittleEndian.AppendUint32(b, rate)
+
+	return append(b, make([]byte, 16)...)
+}
+
+func opusHead(preSkip uint16) []byte {
+	b := append([]byte("OpusHead"), 1, 2)
+	b = binary.LittleEndian.AppendUint16(b, preSkip)
+	b = binary.LittleEndian.AppendUint32(b, 48000)
+
+	return append(b, 0, 0, 0)
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		format Format
+		want   time.Duration
+		err    error
+	}{
+		{
+			name:   "mp3 frame scan",
+			data:   testMP3(100, nil),
+			format: FormatMP3,
+			want:   samplesToDuration(100*1152, 44100),
+		},
+		{
+			name:   "mp3 after id3v2 tag",
+			data:   append(id3Tag(1000), testMP3(50, nil)...),
+			format: FormatMP3,
+			want:   samplesToDuration(50*1152, 44100),
+		},
+		{
+			name:   "mp3 xing header with encoder delay",
+			data:   testMP3(2, xingHeader(1000, 576, 1000)),
+			format: FormatMP3,
+			want:   samplesToDuration(1000*1152-576-1000, 44100),
+		},
+		{
+			name:   "mp3 without frames",
+			data:   make([]byte, 2048),
+			format: FormatMP3,
+			err:    ErrInvalidMP3,
+		},
+		{
+			name:   "flac streaminfo",
+			data:   testFLAC(44100, 2, 16, 441000),
+			format: FormatFLAC,
+			want:   10 * time.Second,
+		},
+		{
+			name:   "flac without sample count",
+			data:   testFLAC(44100, 2, 16, 0),
+			format: FormatFLAC,
+			err:    ErrNoDuration,
+		},
+		{
+			name:   "ogg vorbis",
+			data:   testOgg(vorbisHead(44100), 441000),
+			format: FormatOGG,
+			want:   10 * time.Second,
+		},
+		{
+			name:   "ogg opus with pre-skip",
+			data:   testOgg(opusHead(312), 480000+312),
+			format: FormatOGG,
+			want:   10 * time.Second,
+		},
+		{
+			name:   "ogg unknown codec",
+			data:   testOgg(append([]byte("\x80theora"), make([]byte, 32)...), 1000),
+			format: FormatOGG,
+			err:    ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Duration(bytes.NewReader(tt.data), tt.format)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Duration() err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Duration() err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Duration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMP3Frame(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   MP3Frame
+		ok     bool
+	}{
+		{
+			name:   "mpeg1 layer iii",
+			header: mp3Header,
+			want:   MP3Frame{Version: 1, Layer: 3, Bitrate: 128, SampleRate: 44100, Channels: 2, Size: 417, Samples: 1152},
+			ok:     true,
+		},
+		{
+			name:   "padded mono",
+			header: []byte{0xFF, 0xFB, 0x92, 0xC0},
+			want:   MP3Frame{Version: 1, Layer: 3, Bitrate: 128, SampleRate: 44100, Channels: 1, Size: 418, Samples: 1152},
+			ok:     true,
+		},
+		{
+			name:   "mpeg2 layer iii",
+			header: []byte{0xFF, 0xF3, 0x80, 0x00},
+			want:   MP3Frame{Version: 2, Layer: 3, Bitrate: 64, SampleRate: 22050, Channels: 2, Size: 208, Samples: 576},
+			ok:     true,
+		},
+		{name: "no sync", header: []byte{0x49, 0x44, 0x33, 0x04}},
+		{name: "free bitrate", header: []byte{0xFF, 0xFB, 0x00, 0x00}},
+		{name: "reserved sample rate", header: []byte{0xFF, 0xFB, 0x9C, 0x00}},
+		{name: "short", header: []byte{0xFF, 0xFB}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseMP3Frame(tt.header)
+			if ok != tt.ok {
+				t.Fatalf("ParseMP3Frame() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("ParseMP3Frame() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// ErrInvalidFLAC is returned for streams without a FLAC signature and STREAMINFO
+var ErrInvalidFLAC = errors.New("invalid FLAC file")
+
+// FLACInfo locates the metadata and audio frames of a native FLAC stream
+type FLACInfo struct {
+	// StreamInfo is the raw 34 byte STREAMINFO block body
+	StreamInfo    []byte
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	BlockSize     int
+	TotalSamples  uint64
+	// MetadataOffset is the position of the first metadata block header, after "fLaC"
+	MetadataOffset int64
+	// AudioOffset is the position of the first audio frame
+	AudioOffset int64
+}
+
+// ParseFLAC reads the signature and metadata block headers, skipping a leading ID3v2 tag
+func ParseFLAC(r io.ReadSeeker) (*FLACInfo, error) {
+	start, err := SkipID3v2(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig [4]byte
+	if _, err := io.ReadFull(r, sig[:]); err != nil || string(sig[:]) != "fLaC" {
+		return nil, ErrInvalidFLAC
+	}
+
+	info := &FLACInfo{MetadataOffset: start + 4}
+	pos := info.MetadataOffset
+
+	for {
+		var h [4]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return nil, ErrInvalidFLAC
+		}
+
+		last := h[0]&0x80 != 0
+		kind := h[0] & 0x7F
+		length := int64(h[1])<<16 | int64(h[2])<<8 | int64(h[3])
+		pos += 4
+
+		if kind == 0 {
+			if length != 34 {
+				return nil, ErrInvalidFLAC
+			}
+
+			info.StreamInfo = make([]byte, 34)
+			if _, err := io.ReadFull(r, info.StreamInfo); err != nil {
+				return nil, ErrInvalidFLAC
+			}
+			info.decodeStreamInfo()
+		} else if _, err := r.Seek(length, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+
+		pos += length
+		if last {
+			break
+		}
+	}
+
+	if info.StreamInfo == nil {
+		return nil, ErrInvalidFLAC
+	}
+
+	info.AudioOffset = pos
+	return info, nil
+}
+
+func (f *FLACInfo) decodeStreamInfo() {
+	si := f.StreamInfo
+	f.BlockSize = int(binary.BigEndian.Uint16(si[0:]))
+	packed := binary.BigEndian.Uint64(si[10:])
+	f.SampleRate = int(packed >> 44)
+	f.Channels = int((packed>>41)&0x07) + 1
+	f.BitsPerSample = int((packed>>36)&0x1F) + 1
+	f.TotalSamples = packed & 0x0F_FFFF_FFFF
+}
+
+// FLACFrame is the position of an audio frame within the stream
+type FLACFrame struct {
+	Offset    int64
+	Sample    uint64 // first sample of the frame
+	BlockSize int
+}
+
+var flacBlockSizes = [16]int{0, 192, 576, 1152, 2304, 4608, 0, 0, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768}
+
+// parseFLACFrameHeader validates a frame header, including its CRC-8, and returns the sample
+// number and block size it describes
+func (f *FLACInfo) parseFLACFrameHeader(b []byte) (sample uint64, blockSize int, ok bool) {
+	if len(b) < 6 || b[0] != 0xFF || (b[1] != 0xF8 && b[1] != 0xF9) {
+		return 0, 0, false
+	}
+
+	variable := b[1] == 0xF9
+	bsCode := b[2] >> 4
+	rateCode := b[2] & 0x0F
+	channels := b[3] >> 4
+	sizeCode := (b[3] >> 1) & 0x07
+
+	if bsCode == 0 || rateCode == 15 || channels > 10 || sizeCode == 3 || b[3]&0x01 != 0 {
+		return 0, 0, false
+	}
+
+	// UTF-8 style coded frame or sample number
+	ones := bits.LeadingZeros8(^b[4])
+	if ones == 1 || ones > 7 {
+		return 0, 0, false
+	}
+
+	n := max(ones, 1)
+	pos := 4 + n
+	if len(b) < pos+4 {
+		return 0, 0, false
+	}
+
+	number := uint64(b[4])
+	if n > 1 {
+		number = uint64(b[4] & (0xFF >> (ones + 1)))
+		for _, c := range b[5:pos] {
+			if c&0xC0 != 0x80 {
+				return 0, 0, false
+			}
+			number = number<<6 | uint64(c&0x3F)
+		}
+	}
+
+	blockSize = flacBlockSizes[bsCode]
+	switch bsCode {
+	case 6:
+		blockSize = int(b[pos]) + 1
+		pos++
+	case 7:
+		blockSize = int(binary.BigEndian.Uint16(b[pos:])) + 1
+		pos += 2
+	}
+
+	switch rateCode {
+	case 12:
+		pos++
+	case 13, 14:
+		pos += 2
+	}
+
+	if len(b) <= pos || crc8(b[:pos]) != b[pos] {
+		return 0, 0, false
+	}
+
+	if variable {
+		return number, blockSize, true
+	}
+
+	return number * uint64(f.BlockSize), blockSize, true
+}
+
+// crc8 is the FLAC frame header checksum, polynomial x^8 + x^2 + x + 1
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+// WalkFLACFrames calls fn for every audio frame in stream order until fn returns false.
+// Frames are found by their sync code and accepted only when the header checksum holds
+// and the sample number continues the previous frame.
+func (f *FLACInfo) WalkFLACFrames(r io.ReadSeeker, fn func(FLACFrame) bool) error {
+	if _, err := r.Seek(f.AudioOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, 1<<20)
+	pos := f.AudioOffset
+	first := true
+	var expect uint64
+
+	for {
+		h, err := br.Peek(16)
+		if len(h) < 6 {
+			if err == io.EOF || err == nil {
+				return nil
+			}
+			return err
+		}
+
+		if sample, bs, ok := f.parseFLACFrameHeader(h); ok && (first || sample == expect) {
+			if !fn(FLACFrame{Offset: pos, Sample: sample, BlockSize: bs}) {
+				return nil
+			}
+
+			first = false
+			expect = sample + uint64(bs)
+		}
+
+		// jump to the next candidate sync byte
+		if _, err := br.Discard(1); err != nil {
+			return nil
+		}
+		pos++
+
+		for {
+			buffered, _ := br.Peek(max(br.Buffered(), 1))
+			if len(buffered) == 0 {
+				return nil
+			}
+
+			i := bytes.IndexByte(buffered, 0xFF)
+			if i >= 0 {
+				_, _ = br.Discard(i)
+				pos += int64(i)
+				break
+			}
+
+			n, _ := br.Discard(len(buffered))
+			pos += int64(n)
+		}
+	}
+}
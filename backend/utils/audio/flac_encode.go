@@ -0,0 +1,210 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// flacBlockSize is the number of samples per channel in every encoded frame
+const flacBlockSize = 4096
+
+// ErrNotLossless is returned for WAV files FLAC cannot hold losslessly, such as float samples
+var ErrNotLossless = errors.New("WAV layout cannot be encoded to FLAC losslessly")
+
+// EncodeFLAC losslessly encodes the integer PCM WAV in r to FLAC. w must be seekable so the
+// STREAMINFO block can be completed with the sample count and MD5 signature.
+func EncodeFLAC(w io.WriteSeeker, r io.ReadSeeker) error {
+	pcm, err := newWAVIntReader(r)
+	if err != nil {
+		return err
+	}
+
+	info := &meta.StreamInfo{
+		BlockSizeMin:  flacBlockSize,
+		BlockSizeMax:  flacBlockSize,
+		SampleRate:    uint32(pcm.info.SampleRate),
+		NChannels:     uint8(pcm.info.Channels),
+		BitsPerSample: uint8(pcm.width * 8),
+	}
+
+	enc, err := flac.NewEncoder(noCloseWriter{w}, info)
+	if err != nil {
+		return err
+	}
+
+	channels := make([][]int32, pcm.info.Channels)
+	for c := range channels {
+		channels[c] = make([]int32, flacBlockSize)
+	}
+
+	for {
+		n, err := pcm.read(channels)
+		if n > 0 {
+			f := &frame.Frame{
+				Header: frame.Header{
+					HasFixedBlockSize: true,
+					BlockSize:         uint16(n),
+					SampleRate:        info.SampleRate,
+					Channels:          frame.Channels(pcm.info.Channels - 1),
+					BitsPerSample:     info.BitsPerSample,
+				},
+				Subframes: make([]*frame.Subframe, len(channels)),
+			}
+			for c := range channels {
+				f.Subframes[c] = &frame.Subframe{
+					SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+					Samples:   append([]int32(nil), channels[c][:n]...),
+					NSamples:  n,
+				}
+			}
+
+			if werr := enc.WriteFrame(f); werr != nil {
+				return werr
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.Close()
+}
+
+// noCloseWriter keeps the encoder from closing the destination, which stays owned by the caller
+type noCloseWriter struct {
+	io.WriteSeeker
+}
+
+// VerifyFLAC decodes the FLAC stream in f and compares every sample with the WAV source
+func VerifyFLAC(f io.ReadSeeker, wav io.ReadSeeker) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	stream, err := flac.New(f)
+	if err != nil {
+		return err
+	}
+
+	pcm, err := newWAVIntReader(wav)
+	if err != nil {
+		return err
+	}
+
+	if int(stream.Info.NChannels) != pcm.info.Channels || int(stream.Info.SampleRate) != pcm.info.SampleRate {
+		return fmt.Errorf("FLAC verification: stream layout differs from source")
+	}
+
+	channels := make([][]int32, pcm.info.Channels)
+	var position int64
+	for {
+		decoded, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		n := int(decoded.BlockSize)
+		for c := range channels {
+			channels[c] = append(channels[c][:0], make([]int32, n)...)
+		}
+
+		read, err := pcm.read(channels)
+		if read != n {
+			return fmt.Errorf("FLAC verification: source ended at sample %d: %v", position+int64(read), err)
+		}
+
+		for c, sub := range decoded.Subframes {
+			for i, v := range sub.Samples {
+				if v != channels[c][i] {
+					return fmt.Errorf("FLAC verification: sample %d of channel %d differs", position+int64(i), c)
+				}
+			}
+		}
+		position += int64(n)
+	}
+
+	if position != pcm.info.Frames() {
+		return fmt.Errorf("FLAC verification: decoded %d of %d samples", position, pcm.info.Frames())
+	}
+
+	return nil
+}
+
+// wavIntReader reads integer PCM samples of a WAV file split by channel
+type wavIntReader struct {
+	info  *WAVInfo
+	r     io.Reader
+	width int
+	buf   []byte
+}
+
+func newWAVIntReader(r io.ReadSeeker) (*wavIntReader, error) {
+	info, err := ParseWAV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	width := info.BlockAlign / info.Channels
+	if info.IsFloat() || width < 1 || width > 3 || info.Channels > 8 {
+		return nil, ErrNotLossless
+	}
+
+	if _, err := r.Seek(info.DataOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return &wavIntReader{
+		info:  info,
+		r:     io.LimitReader(r, info.Frames()*int64(info.BlockAlign)),
+		width: width,
+	}, nil
+}
+
+// read fills every channel slice up to its length and returns the number of samples per
+// channel, with io.EOF once the data chunk is exhausted
+func (w *wavIntReader) read(channels [][]int32) (int, error) {
+	need := len(channels[0]) * w.info.BlockAlign
+	if cap(w.buf) < need {
+		w.buf = make([]byte, need)
+	}
+
+	n, err := io.ReadFull(w.r, w.buf[:need])
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	frames := n / w.info.BlockAlign
+	for i := 0; i < frames; i++ {
+		for c := range channels {
+			b := w.buf[i*w.info.BlockAlign+c*w.width:]
+			switch w.width {
+			case 1:
+				// 8-bit WAV is unsigned
+				channels[c][i] = int32(b[0]) - 128
+			case 2:
+				channels[c][i] = int32(int16(binary.LittleEndian.Uint16(b)))
+			default:
+				channels[c][i] = (int32(b[0])<<8 | int32(b[1])<<16 | int32(b[2])<<24) >> 8
+			}
+		}
+	}
+
+	if frames == 0 && err == nil {
+		err = io.EOF
+	}
+
+	return frames, err
+}
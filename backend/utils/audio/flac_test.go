@@ -0,0 +1,91 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseFLAC(t *testing.T) {
+	stream := testFLAC(48000, 2, 24, 96000)
+
+	// a padding block before STREAMINFO, which is then the last block
+	padded := append([]byte("fLaC"), 0x01, 0, 0, 8)
+	padded = append(padded, make([]byte, 8)...)
+	padded = append(padded, stream[4:]...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     FLACInfo
+		metadata int64
+		audio    int64
+		err      error
+	}{
+		{
+			name:     "streaminfo",
+			data:     stream,
+			want:     FLACInfo{SampleRate: 48000, Channels: 2, BitsPerSample: 24, BlockSize: 4096, TotalSamples: 96000},
+			metadata: 4,
+			audio:    4 + 4 + 34,
+		},
+		{
+			name:     "after id3v2 tag",
+			data:     append(id3Tag(100), stream...),
+			want:     FLACInfo{SampleRate: 48000, Channels: 2, BitsPerSample: 24, BlockSize: 4096, TotalSamples: 96000},
+			metadata: 110 + 4,
+			audio:    110 + 4 + 4 + 34,
+		},
+		{
+			name:     "other blocks first",
+			data:     padded,
+			want:     FLACInfo{SampleRate: 48000, Channels: 2, BitsPerSample: 24, BlockSize: 4096, TotalSamples: 96000},
+			metadata: 4,
+			audio:    4 + 4 + 8 + 4 + 34,
+		},
+		{
+			name: "no signature",
+			data: append([]byte("RIFF"), stream[4:]...),
+			err:  ErrInvalidFLAC,
+		},
+		{
+			name: "no streaminfo",
+			data: append([]byte("fLaC"), 0x81, 0, 0, 0),
+			err:  ErrInvalidFLAC,
+		},
+		{
+			name: "bad streaminfo length",
+			data: append([]byte("fLaC"), 0x80, 0, 0, 33),
+			err:  ErrInvalidFLAC,
+		},
+		{
+			name: "truncated",
+			data: stream[:20],
+			err:  ErrInvalidFLAC,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFLAC(bytes.NewReader(tt.data))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("ParseFLAC() err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseFLAC() err = %v", err)
+			}
+			if got.SampleRate != tt.want.SampleRate || got.Channels != tt.want.Channels ||
+				got.BitsPerSample != tt.want.BitsPerSample || got.BlockSize != tt.want.BlockSize ||
+				got.TotalSamples != tt.want.TotalSamples {
+				t.Errorf("ParseFLAC() = %+v, want %+v", got, tt.want)
+			}
+			if got.MetadataOffset != tt.metadata || got.AudioOffset != tt.audio {
+				t.Errorf("ParseFLAC() offsets = %d, %d, want %d, %d", got.MetadataOffset, got.AudioOffset, tt.metadata, tt.audio)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format identifies an audio container
+type Format string
+
+const (
+	FormatUnknown Format = ""
+	FormatMP3     Format = "mp3"
+	FormatWAV     Format = "wav"
+	FormatFLAC    Format = "flac"
+	FormatOGG     Format = "ogg"
+	FormatMP4     Format = "mp4"
+	FormatAAC     Format = "aac"
+)
+
+var mimeFormats = map[string]Format{
+	"audio/mpeg":   FormatMP3,
+	"audio/mp3":    FormatMP3,
+	"audio/wav":    FormatWAV,
+	"audio/wave":   FormatWAV,
+	"audio/x-wav":  FormatWAV,
+	"audio/flac":   FormatFLAC,
+	"audio/x-flac": FormatFLAC,
+	"audio/ogg":    FormatOGG,
+	"audio/mp4":    FormatMP4,
+	"audio/x-m4a":  FormatMP4,
+	"audio/aac":    FormatAAC,
+}
+
+var extFormats = map[string]Format{
+	".mp3":  FormatMP3,
+	".wav":  FormatWAV,
+	".flac": FormatFLAC,
+	".ogg":  FormatOGG,
+	".oga":  FormatOGG,
+	".m4a":  FormatMP4,
+	".mp4":  FormatMP4,
+	".aac":  FormatAAC,
+}
+
+var formatMimes = map[Format]string{
+	FormatMP3:  "audio/mpeg",
+	FormatWAV:  "audio/wav",
+	FormatFLAC: "audio/flac",
+	FormatOGG:  "audio/ogg",
+	FormatMP4:  "audio/mp4",
+	FormatAAC:  "audio/aac",
+}
+
+// MimeType returns the canonical MIME type of a format, empty for FormatUnknown
+func MimeType(f Format) string {
+	return formatMimes[f]
+}
+
+// FormatOf resolves the container of a file from its MIME type, falling back to the file extension
+func FormatOf(mimeType, filename string) Format {
+	if f, ok := mimeFormats[strings.ToLower(mimeType)]; ok {
+		return f
+	}
+
+	return extFormats[strings.ToLower(filepath.Ext(filename))]
+}
+
+// Sniff detects the container of r from its leading bytes and rewinds r afterwards
+func Sniff(r io.ReadSeeker) (Format, error) {
+	head := make([]byte, 12)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return FormatUnknown, err
+	}
+	head = head[:n]
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return FormatUnknown, err
+	}
+
+	switch {
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		return FormatWAV, nil
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return FormatFLAC, nil
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return FormatOGG, nil
+	case len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp")):
+		return FormatMP4, nil
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return FormatMP3, nil
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xF6 == 0xF0:
+		return FormatAAC, nil
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return FormatMP3, nil
+	}
+
+	return FormatUnknown, nil
+}
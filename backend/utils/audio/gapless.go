@@ -0,0 +1,180 @@
+package audio
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// mp3DecoderDelay is the fixed delay of the MPEG layer III synthesis filterbank in samples
+const mp3DecoderDelay = 529
+
+// Gapless is the priming and padding a lossy encoder added around the audio, in samples
+type Gapless struct {
+	SampleRate int
+	Delay      int
+	Padding    int
+	// DecoderDelay is added by decoders on top of Delay, so decoded output starts this
+	// many samples later than the audio itself
+	DecoderDelay int
+	// Tagged reports whether Delay and Padding were read from the file. Untagged results
+	// only carry the DecoderDelay of MP3 files.
+	Tagged bool
+}
+
+// ReadGapless returns the encoder delay and padding from the LAME/VBRI header of MP3 files or
+// from an iTunSMPB tag for MP3 and AAC. MP3 files carrying neither still get their decoder
+// delay, other files return nil.
+func ReadGapless(r io.ReadSeeker, f Format) (*Gapless, error) {
+	switch f {
+	case FormatMP3:
+		info, err := ParseMP3(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// the Xing/Info frame holds no audio but decodes to a frame of silence
+		decoderDelay := mp3DecoderDelay
+		if info.InfoFrameOffset >= 0 {
+			decoderDelay += info.SamplesPerFrame
+		}
+
+		if info.HasLAME || info.EncoderDelay > 0 {
+			return &Gapless{
+				SampleRate:   info.SampleRate,
+				Delay:        info.EncoderDelay,
+				Padding:      info.EncoderPadding,
+				DecoderDelay: decoderDelay,
+				Tagged:       true,
+			}, nil
+		}
+
+		g, err := readSMPB(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// iTunes counts the filterbank delay in its own delay value
+		if g != nil {
+			g.SampleRate, g.DecoderDelay = info.SampleRate, decoderDelay-mp3DecoderDelay
+			return g, nil
+		}
+
+		return &Gapless{SampleRate: info.SampleRate, DecoderDelay: decoderDelay}, nil
+
+	case FormatMP4:
+		g, err := readSMPB(r)
+		if g == nil || err != nil {
+			return nil, err
+		}
+
+		if g.SampleRate, err = mp4SampleRate(r); err != nil {
+			return nil, err
+		}
+		return g, nil
+
+	case FormatAAC:
+		var rate int
+		if err := WalkADTSFrames(r, func(_ int64, f ADTSFrame) bool {
+			rate = f.SampleRate
+			return false
+		}); err != nil {
+			return nil, err
+		}
+
+		g, err := readSMPB(r)
+		if g != nil {
+			g.SampleRate = rate
+		}
+		return g, err
+	}
+
+	return nil, nil
+}
+
+// readSMPB reads the iTunSMPB tag written by iTunes encoders, stored as an MP4 freeform
+// atom or an ID3 comment
+func readSMPB(r io.ReadSeeker) (*Gapless, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		if errors.Is(err, tag.ErrNoTagsFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	for name, v := range m.Raw() {
+		switch v := v.(type) {
+		case string:
+			if name == "iTunSMPB" {
+				return ParseSMPB(v), nil
+			}
+		case *tag.Comm:
+			if v.Description == "iTunSMPB" {
+				return ParseSMPB(v.Text), nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// ParseSMPB decodes an iTunSMPB value: hexadecimal fields holding a reserved word, the
+// delay, the padding and the original sample count. It returns nil for malformed values.
+func ParseSMPB(s string) *Gapless {
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return nil
+	}
+
+	delay, err := strconv.ParseUint(fields[1], 16, 32)
+	if err != nil {
+		return nil
+	}
+
+	padding, err := strconv.ParseUint(fields[2], 16, 32)
+	if err != nil {
+		return nil
+	}
+
+	return &Gapless{Delay: int(delay), Padding: int(padding), Tagged: true}
+}
+
+// mp4SampleRate returns the timescale of the sound track, which AAC encoders set to the sample rate
+func mp4SampleRate(r io.ReadSeeker) (int, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	moov, err := findAtom(r, 0, size, "moov")
+	if err != nil {
+		return 0, err
+	}
+
+	traks, err := listAtoms(r, moov.body, moov.end)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range traks {
+		if a.kind != "trak" {
+			continue
+		}
+
+		if mdhd, ok := soundMediaHeader(r, a); ok {
+			timescale, _, err := mediaHeader(r, mdhd)
+			return int(timescale), err
+		}
+	}
+
+	return 0, ErrNoDuration
+}
@@ -0,0 +1,298 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+)
+
+// id3Padding is reserved after a written tag so later edits fit without moving the audio
+const id3Padding = 1024
+
+// id3Renames maps ID3v2.3 frames to their ID3v2.4 replacement, frames mapped to "" are dropped
+var id3Renames = map[string]string{
+	"TYER": "TDRC",
+	"TORY": "TDOR",
+	"TDAT": "",
+	"TIME": "",
+	"TRDA": "",
+	"TSIZ": "",
+	"EQUA": "",
+	"RVAD": "",
+}
+
+type id3Frame struct {
+	id   string
+	body []byte
+}
+
+// writeID3 writes a new ID3v2.4 tag followed by the audio of r. Frames of an existing v2.3 or
+// v2.4 tag that are not replaced are carried over, an ID3v1 tag at the end is updated.
+func writeID3(w io.Writer, r io.ReadSeeker, t Tags) error {
+	frames, audioStart, err := readID3Frames(r)
+	if err != nil {
+		return err
+	}
+
+	text := []id3Frame{
+		id3TextFrame("TIT2", t.Title),
+		id3TextFrame("TPE1", t.Artist),
+		id3TextFrame("TALB", t.Album),
+		id3TextFrame("TDRC", t.date()),
+		id3TextFrame("TRCK", id3Number(t.TrackNumber, t.TrackTotal)),
+		id3TextFrame("TPOS", id3Number(t.DiscNumber, t.DiscTotal)),
+		id3TextFrame("TCON", t.Genre),
+		id3TextFrame("TCOM", t.Composer),
+		id3TextFrame("TSRC", t.ISRC),
+		id3TextFrame("TPUB", t.Label),
+	}
+
+	replaced := make(map[string]bool, len(text)+1)
+	for _, f := range text {
+		replaced[f.id] = true
+	}
+	if t.Picture != nil {
+		replaced["APIC"] = true
+	}
+
+	var body bytes.Buffer
+	for _, f := range text {
+		if len(f.body) > 1 {
+			writeID3Frame(&body, f)
+		}
+	}
+
+	if t.Comment != "" {
+		writeID3Frame(&body, id3CommentFrame(t.Comment))
+	}
+
+	if t.Picture != nil {
+		var apic bytes.Buffer
+		apic.WriteByte(0) // ISO-8859-1, the MIME type is always ASCII
+		apic.WriteString(t.Picture.MIMEType)
+		apic.Write([]byte{0, pictureFrontCover, 0}) // terminator, type, empty description
+		apic.Write(t.Picture.Data)
+		writeID3Frame(&body, id3Frame{id: "APIC", body: apic.Bytes()})
+	}
+
+	for _, f := range frames {
+		// comments with a description, such as iTunSMPB, are not the comment of the track
+		if !replaced[f.id] && !(f.id == "COMM" && id3PlainComment(f.body)) {
+			writeID3Frame(&body, f)
+		}
+	}
+
+	size := body.Len() + id3Padding
+	header := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}
+	putSyncsafe(header[6:], size)
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(body.Bytes()); err != nil {
+		return err
+	}
+	if _, err := w.Write(make([]byte, id3Padding)); err != nil {
+		return err
+	}
+
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	v1, err := readID3v1(r, end)
+	if err != nil {
+		return err
+	}
+	if v1 != nil {
+		end -= 128
+	}
+
+	if err := copyRange(w, r, audioStart, end); err != nil {
+		return err
+	}
+
+	if v1 == nil {
+		return nil
+	}
+
+	setID3v1Field(v1[3:33], t.Title)
+	setID3v1Field(v1[33:63], t.Artist)
+	setID3v1Field(v1[63:93], t.Album)
+	_, err = w.Write(v1)
+	return err
+}
+
+// readID3Frames returns the frames of a leading ID3v2.3 or v2.4 tag and where the audio starts.
+// Frames that are compressed, encrypted or unsynchronised, and every frame of v2.2 tags,
+// are dropped since they cannot be carried over as they are.
+func readID3Frames(r io.ReadSeeker) ([]id3Frame, int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	var h [10]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil || !bytes.Equal(h[:3], []byte("ID3")) {
+		return nil, 0, nil
+	}
+
+	version, flags := h[3], h[5]
+	size := syncsafe(h[6:])
+	end := 10 + int64(size)
+	if flags&0x10 != 0 {
+		end += 10 // footer
+	}
+
+	if version < 3 || version > 4 || flags&0x80 != 0 {
+		return nil, end, nil
+	}
+
+	tag := make([]byte, size)
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return nil, 0, ErrInvalidMP3
+	}
+
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		// extended header: v2.3 size excludes its own 4 bytes, v2.4 size is syncsafe and inclusive
+		skip := int(binary.BigEndian.Uint32(tag)) + 4
+		if version == 4 {
+			skip = syncsafe(tag)
+		}
+		tag = tag[min(skip, len(tag)):]
+	}
+
+	var frames []id3Frame
+	for len(tag) >= 10 && tag[0] != 0 {
+		id := string(tag[:4])
+		length := int(binary.BigEndian.Uint32(tag[4:]))
+		if version == 4 {
+			length = syncsafe(tag[4:])
+		}
+
+		format := tag[9]
+		if length > len(tag)-10 {
+			break
+		}
+		body := tag[10 : 10+length]
+		tag = tag[10+length:]
+
+		// v2.3: compression, encryption, grouping; v2.4: grouping, compression, encryption, unsync, length
+		if (version == 3 && format&0xE0 != 0) || (version == 4 && format&0x4F != 0) {
+			continue
+		}
+
+		if version == 3 {
+			if renamed, ok := id3Renames[id]; ok {
+				if renamed == "" {
+					continue
+				}
+				id = renamed
+			}
+		}
+
+		frames = append(frames, id3Frame{id: id, body: body})
+	}
+
+	return frames, end, nil
+}
+
+// id3TextFrame encodes a UTF-8 text frame
+func id3TextFrame(id, text string) id3Frame {
+	return id3Frame{id: id, body: append([]byte{3}, text...)}
+}
+
+// id3CommentFrame encodes a UTF-8 COMM frame without language and description
+func id3CommentFrame(text string) id3Frame {
+	body := append([]byte{3, 'x', 'x', 'x', 0}, text...)
+	return id3Frame{id: "COMM", body: body}
+}
+
+// id3PlainComment reports whether a COMM frame has an empty description
+func id3PlainComment(body []byte) bool {
+	if len(body) < 5 {
+		return true
+	}
+
+	desc := body[4:]
+	if body[0] == 1 || body[0] == 2 {
+		// UTF-16, with or without a byte order mark before the two byte terminator
+		if len(desc) >= 2 && (desc[0] == 0xFF && desc[1] == 0xFE || desc[0] == 0xFE && desc[1] == 0xFF) {
+			desc = desc[2:]
+		}
+		return len(desc) < 2 || desc[0] == 0 && desc[1] == 0
+	}
+
+	return desc[0] == 0
+}
+
+// id3Number formats a TRCK or TPOS value, n/total when the total is known
+func id3Number(n int, total int) string {
+	switch {
+	case n <= 0:
+		return ""
+	case total <= 0:
+		return strconv.Itoa(n)
+	}
+
+	return strconv.Itoa(n) + "/" + strconv.Itoa(total)
+}
+
+func writeID3Frame(w *bytes.Buffer, f id3Frame) {
+	h := make([]byte, 10)
+	copy(h, f.id)
+	putSyncsafe(h[4:], len(f.body))
+	w.Write(h)
+	w.Write(f.body)
+}
+
+// readID3v1 returns the 128 byte ID3v1 tag at the end of the stream, nil when there is none
+func readID3v1(r io.ReadSeeker, end int64) ([]byte, error) {
+	if end < 128 {
+		return nil, nil
+	}
+
+	if _, err := r.Seek(end-128, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	tag := make([]byte, 128)
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(tag, []byte("TAG")) {
+		return nil, nil
+	}
+
+	return tag, nil
+}
+
+// setID3v1Field stores text as ISO-8859-1, truncated and zero padded to the field size
+func setID3v1Field(field []byte, text string) {
+	clear(field)
+
+	i := 0
+	for _, c := range text {
+		if i == len(field) {
+			break
+		}
+		if c > 0xFF {
+			c = '?'
+		}
+		field[i] = byte(c)
+		i++
+	}
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+func putSyncsafe(b []byte, n int) {
+	b[0] = byte(n>>21) & 0x7F
+	b[1] = byte(n>>14) & 0x7F
+	b[2] = byte(n>>7) & 0x7F
+	b[3] = byte(n) & 0x7F
+}
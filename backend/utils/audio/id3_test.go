@@ -0,0 +1,114 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// testID3 builds an ID3v2 tag of version holding frames, sizes are syncsafe from v2.4 on
+func testID3(version byte, flags byte, frames ...id3Frame) []byte {
+	var body bytes.Buffer
+	for _, f := range frames {
+		h := make([]byte, 10)
+		copy(h, f.id)
+		if version == 4 {
+			putSyncsafe(h[4:], len(f.body))
+		} else {
+			binary.BigEndian.PutUint32(h[4:], uint32(len(f.body)))
+		}
+		body.Write(h)
+		body.Write(f.body)
+	}
+
+	h := []byte{'I', 'D', '3', version, 0, flags, 0, 0, 0, 0}
+	putSyncsafe(h[6:], body.Len())
+
+	return append(h, body.Bytes()...)
+}
+
+func TestReadID3Frames(t *testing.T) {
+	title := id3TextFrame("TIT2", "Title")
+	year := id3TextFrame("TYER", "1999")
+
+	tests := []struct {
+		name   string
+		data   []byte
+		frames []id3Frame
+		end    int64
+	}{
+		{
+			name:   "v2.4",
+			data:   testID3(4, 0, title, id3CommentFrame("note")),
+			frames: []id3Frame{title, id3CommentFrame("note")},
+			end:    10 + 10 + 6 + 10 + 9,
+		},
+		{
+			name:   "v2.3 frames are renamed or dropped",
+			data:   testID3(3, 0, title, year, id3TextFrame("TDAT", "0101")),
+			frames: []id3Frame{title, {id: "TDRC", body: year.body}},
+			end:    10 + 16 + 15 + 15,
+		},
+		{
+			name: "v2.2 frames are dropped",
+			data: testID3(2, 0, title),
+			end:  10 + 16,
+		},
+		{
+			name: "unsynchronised tags are dropped",
+			data: testID3(4, 0x80, title),
+			end:  10 + 16,
+		},
+		{
+			name:   "footer",
+			data:   testID3(4, 0x10, title),
+			frames: []id3Frame{title},
+			end:    10 + 16 + 10,
+		},
+		{
+			name: "no tag",
+			data: mp3Header,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, end, err := readID3Frames(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("readID3Frames() err = %v", err)
+			}
+			if !reflect.DeepEqual(frames, tt.frames) {
+				t.Errorf("readID3Frames() frames = %v, want %v", frames, tt.frames)
+			}
+			if end != tt.end {
+				t.Errorf("readID3Frames() end = %d, want %d", end, tt.end)
+			}
+		})
+	}
+}
+
+func TestSkipID3v2(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int64
+	}{
+		{name: "tag", data: append(id3Tag(300), mp3Header...), want: 310},
+		{name: "tag with footer", data: append(testID3(4, 0x10, id3TextFrame("TIT2", "x")), mp3Header...), want: 10 + 12 + 10},
+		{name: "no tag", data: mp3Header, want: 0},
+		{name: "short file", data: []byte("ID"), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SkipID3v2(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("SkipID3v2() err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SkipID3v2() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
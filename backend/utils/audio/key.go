@@ -0,0 +1,186 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	keyFrame   = 4096
+	keyHop     = 2048
+	keyMinFreq = 65.0 // C2
+	keyMaxFreq = 2100.0
+)
+
+var (
+	pitchClasses = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatClasses  = map[string]string{"DB": "C#", "EB": "D#", "GB": "F#", "AB": "G#", "BB": "A#", "CB": "B", "FB": "E", "E#": "F", "B#": "C"}
+
+	// Krumhansl-Kessler key profiles, starting at the tonic
+	majorProfile = [12]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [12]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// Key is a musical key. Tonic is the pitch class, 0 for C up to 11 for B.
+type Key struct {
+	Tonic int
+	Minor bool
+}
+
+// String formats the key with sharps and an "m" suffix for minor keys, e.g. "F#m"
+func (k Key) String() string {
+	if k.Minor {
+		return pitchClasses[k.Tonic] + "m"
+	}
+
+	return pitchClasses[k.Tonic]
+}
+
+// ParseKey reads notations such as "Am", "a minor", "Eb", "D#min" or "C major"
+func ParseKey(s string) (Key, error) {
+	v := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+
+	var k Key
+	for _, suffix := range []string{"MINOR", "MIN", "M"} {
+		if rest, ok := strings.CutSuffix(v, suffix); ok && rest != "" {
+			v, k.Minor = rest, true
+			break
+		}
+	}
+	if !k.Minor {
+		for _, suffix := range []string{"MAJOR", "MAJ"} {
+			if rest, ok := strings.CutSuffix(v, suffix); ok {
+				v = rest
+				break
+			}
+		}
+	}
+
+	if sharp, ok := flatClasses[v]; ok {
+		v = sharp
+	}
+
+	for i, name := range pitchClasses {
+		if v == name {
+			k.Tonic = i
+			return k, nil
+		}
+	}
+
+	return Key{}, fmt.Errorf("invalid key %q", s)
+}
+
+// KeySink estimates the key by matching the accumulated chroma of the stream against key profiles
+type KeySink struct {
+	dec    monoDecimator
+	spec   *spectrum
+	bins   []int // pitch class per FFT bin, -1 outside the analyzed range
+	chroma [12]float64
+}
+
+func NewKeySink(sampleRate, channels int) *KeySink {
+	k := &KeySink{
+		dec:  newMonoDecimator(sampleRate, channels),
+		spec: newSpectrum(keyFrame, keyHop),
+		bins: make([]int, keyFrame/2+1),
+	}
+
+	for i := range k.bins {
+		freq := float64(i) * k.dec.rate / keyFrame
+		k.bins[i] = -1
+		if freq >= keyMinFreq && freq <= keyMaxFreq {
+			// MIDI note 69 is A4 at 440 Hz
+			note := int(math.Round(69 + 12*math.Log2(freq/440)))
+			k.bins[i] = note % 12
+		}
+	}
+
+	return k
+}
+
+func (k *KeySink) Write(block []float32) {
+	k.dec.write(block, func(x float64) {
+		k.spec.push(x, k.frame)
+	})
+}
+
+// frame adds the chroma of one frame normalized to its strongest pitch class, so loud
+// passages do not outweigh the rest of the track
+func (k *KeySink) frame(mags []float64) {
+	var chroma [12]float64
+	for i, m := range mags {
+		if pc := k.bins[i]; pc >= 0 {
+			chroma[pc] += m
+		}
+	}
+
+	var peak float64
+	for _, v := range chroma {
+		peak = max(peak, v)
+	}
+	if peak < 1e-6 {
+		return
+	}
+
+	for i, v := range chroma {
+		k.chroma[i] += v / peak
+	}
+}
+
+// Key returns the best matching key and its Pearson correlation with the key profile,
+// clamped to [0, 1]. ok is false when no tonal content was found.
+func (k *KeySink) Key() (key Key, confidence float64, ok bool) {
+	var total float64
+	for _, v := range k.chroma {
+		total += v
+	}
+	if total == 0 {
+		return Key{}, 0, false
+	}
+
+	best := math.Inf(-1)
+	for tonic := 0; tonic < 12; tonic++ {
+		for _, minor := range []bool{false, true} {
+			profile := majorProfile
+			if minor {
+				profile = minorProfile
+			}
+
+			var rotated [12]float64
+			for i := range rotated {
+				rotated[(i+tonic)%12] = profile[i]
+			}
+
+			if r := pearson(k.chroma[:], rotated[:]); r > best {
+				best, key = r, Key{Tonic: tonic, Minor: minor}
+			}
+		}
+	}
+
+	return key, math.Min(math.Max(best, 0), 1), true
+}
+
+func pearson(a, b []float64) float64 {
+	var ma, mb float64
+	for i := range a {
+		ma += a[i]
+		mb += b[i]
+	}
+	ma /= float64(len(a))
+	mb /= float64(len(b))
+
+	var cov, va, vb float64
+	for i := range a {
+		da, db := a[i]-ma, b[i]-mb
+		cov += da * db
+		va += da * da
+		vb += db * db
+	}
+
+	if va == 0 || vb == 0 {
+		return 0
+	}
+
+	return cov / math.Sqrt(va*vb)
+}
@@ -0,0 +1,379 @@
+package audio
+
+import (
+	"math"
+	"sort"
+)
+
+// ReplayGainReference is the ReplayGain 2.0 target loudness in LUFS
+const ReplayGainReference = -18.0
+
+const (
+	absoluteGate       = -70.0
+	integratedRelGate  = -10.0
+	rangeRelGate       = -20.0
+	subBlocksPerBlock  = 4  // 400ms momentary blocks
+	subBlocksPerShort  = 30 // 3s short-term blocks
+	truePeakTapsPhase  = 12
+	subBlocksPerSecond = 10
+)
+
+// Loudness is the EBU R128 measurement of a stream
+type Loudness struct {
+	// Integrated is the gated programme loudness in LUFS
+	Integrated float64
+	// TruePeak is the maximum inter-sample peak in dBTP
+	TruePeak float64
+	// TruePeakLinear is the maximum inter-sample peak as a sample value
+	TruePeakLinear float64
+	// Range is the loudness range in LU
+	Range float64
+	// Duration is the measured length in seconds
+	Duration float64
+}
+
+// TrackGain returns the ReplayGain 2.0 gain in dB that brings the stream to the reference loudness
+func (l *Loudness) TrackGain() float64 {
+	return ReplayGainReference - l.Integrated
+}
+
+// AlbumLoudness combines per-track integrated loudness into the loudness of the whole album,
+// weighting the energy of every track by its duration
+func AlbumLoudness(tracks []Loudness) float64 {
+	var energy, duration float64
+	for _, t := range tracks {
+		energy += t.Duration * math.Pow(10, (t.Integrated+0.691)/10)
+		duration += t.Duration
+	}
+
+	if duration == 0 || energy == 0 {
+		return math.Inf(-1)
+	}
+
+	return energyToLUFS(energy / duration)
+}
+
+// biquad is a direct form I second order filter
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+
+	return y
+}
+
+// kWeighting returns the BS.1770 pre-filter and RLB high-pass for the sample rate
+func kWeighting(rate float64) (shelf, highpass biquad) {
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+
+	shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+
+	highpass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highpass
+}
+
+// LoudnessSink measures EBU R128 loudness while the stream is decoded
+type LoudnessSink struct {
+	rate     int
+	channels int
+	weights  []float64
+	shelf    []biquad
+	highpass []biquad
+
+	subBlockSize int
+	inSubBlock   int
+	sumSquares   []float64
+	subBlocks    []float64
+	frames       int64
+
+	oversample int
+	taps       [][]float64
+	history    [][]float64
+	peak       float64
+}
+
+func NewLoudnessSink(sampleRate, channels int) *LoudnessSink {
+	l := &LoudnessSink{
+		rate:         sampleRate,
+		channels:     channels,
+		weights:      make([]float64, channels),
+		shelf:        make([]biquad, channels),
+		highpass:     make([]biquad, channels),
+		subBlockSize: max(1, sampleRate/subBlocksPerSecond),
+		sumSquares:   make([]float64, channels),
+		history:      make([][]float64, channels),
+	}
+
+	for ch := 0; ch < channels; ch++ {
+		l.shelf[ch], l.highpass[ch] = kWeighting(float64(sampleRate))
+		l.weights[ch] = channelWeight(ch, channels)
+		l.history[ch] = make([]float64, truePeakTapsPhase)
+	}
+
+	switch {
+	case sampleRate < 96000:
+		l.oversample = 4
+	case sampleRate < 192000:
+		l.oversample = 2
+	default:
+		l.oversample = 1
+	}
+	l.taps = interpolationTaps(l.oversample)
+
+	return l
+}
+
+// channelWeight follows BS.1770: surround channels are boosted and LFE is ignored
+func channelWeight(ch, channels int) float64 {
+	switch {
+	case channels == 6 && ch == 3:
+		return 0
+	case channels >= 5 && ch >= 3:
+		return 1.41
+	default:
+		return 1
+	}
+}
+
+// interpolationTaps builds Hann windowed sinc polyphase filters for true peak oversampling
+func interpolationTaps(factor int) [][]float64 {
+	if factor == 1 {
+		return nil
+	}
+
+	n := truePeakTapsPhase * factor
+	center := float64(n-1) / 2
+	phases := make([][]float64, factor)
+
+	for p := 0; p < factor; p++ {
+		phases[p] = make([]float64, truePeakTapsPhase)
+		for k := 0; k < truePeakTapsPhase; k++ {
+			i := p + k*factor
+			x := (float64(i) - center) / float64(factor)
+			w := 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(n))
+			phases[p][k] = sinc(x) * w
+		}
+	}
+
+	return phases
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+func (l *LoudnessSink) Write(block []float32) {
+	for i := 0; i+l.channels <= len(block); i += l.channels {
+		for ch := 0; ch < l.channels; ch++ {
+			x := float64(block[i+ch])
+			l.truePeak(ch, x)
+
+			y := l.highpass[ch].process(l.shelf[ch].process(x))
+			l.sumSquares[ch] += y * y
+		}
+
+		l.frames++
+		l.inSubBlock++
+		if l.inSubBlock == l.subBlockSize {
+			l.flush()
+		}
+	}
+}
+
+func (l *LoudnessSink) truePeak(ch int, x float64) {
+	if a := math.Abs(x); a > l.peak {
+		l.peak = a
+	}
+
+	if l.taps == nil {
+		return
+	}
+
+	h := l.history[ch]
+	copy(h[1:], h[:len(h)-1])
+	h[0] = x
+
+	for _, taps := range l.taps {
+		var y float64
+		for k, c := range taps {
+			y += c * h[k]
+		}
+
+		if a := math.Abs(y); a > l.peak {
+			l.peak = a
+		}
+	}
+}
+
+func (l *LoudnessSink) flush() {
+	var energy float64
+	for ch, s := range l.sumSquares {
+		energy += l.weights[ch] * s / float64(l.subBlockSize)
+		l.sumSquares[ch] = 0
+	}
+
+	l.subBlocks = append(l.subBlocks, energy)
+	l.inSubBlock = 0
+}
+
+// Loudness returns the measurement of everything written so far.
+// Streams shorter than one 400ms block report -inf integrated loudness.
+func (l *LoudnessSink) Loudness() *Loudness {
+	res := &Loudness{
+		Integrated:     math.Inf(-1),
+		TruePeak:       amplitudeToDB(l.peak),
+		TruePeakLinear: l.peak,
+	}
+
+	if l.rate > 0 {
+		res.Duration = float64(l.frames) / float64(l.rate)
+	}
+
+	momentary := windowEnergies(l.subBlocks, subBlocksPerBlock)
+	res.Integrated = gatedLoudness(momentary, integratedRelGate)
+
+	res.Range = loudnessRange(windowEnergies(l.subBlocks, subBlocksPerShort))
+
+	return res
+}
+
+// windowEnergies averages every run of size consecutive sub-blocks, hopping one sub-block at a time
+func windowEnergies(subBlocks []float64, size int) []float64 {
+	if len(subBlocks) < size {
+		return nil
+	}
+
+	out := make([]float64, 0, len(subBlocks)-size+1)
+	var sum float64
+	for i, e := range subBlocks {
+		sum += e
+		if i >= size {
+			sum -= subBlocks[i-size]
+		}
+		if i >= size-1 {
+			out = append(out, sum/float64(size))
+		}
+	}
+
+	return out
+}
+
+// absoluteGated keeps the energies above the -70 LUFS gate
+func absoluteGated(energies []float64) []float64 {
+	gated := make([]float64, 0, len(energies))
+	for _, e := range energies {
+		if energyToLUFS(e) > absoluteGate {
+			gated = append(gated, e)
+		}
+	}
+
+	return gated
+}
+
+func gatedLoudness(energies []float64, relGate float64) float64 {
+	gated := absoluteGated(energies)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+
+	threshold := energyToLUFS(mean(gated)) + relGate
+
+	var sum float64
+	var n int
+	for _, e := range gated {
+		if energyToLUFS(e) > threshold {
+			sum += e
+			n++
+		}
+	}
+
+	if n == 0 {
+		return math.Inf(-1)
+	}
+
+	return energyToLUFS(sum / float64(n))
+}
+
+// loudnessRange implements EBU Tech 3342: the spread between the 10th and 95th percentile
+// of gated short-term loudness
+func loudnessRange(energies []float64) float64 {
+	gated := absoluteGated(energies)
+	if len(gated) == 0 {
+		return 0
+	}
+
+	threshold := energyToLUFS(mean(gated)) + rangeRelGate
+
+	values := make([]float64, 0, len(gated))
+	for _, e := range gated {
+		if l := energyToLUFS(e); l > threshold {
+			values = append(values, l)
+		}
+	}
+
+	if len(values) == 0 {
+		return 0
+	}
+
+	sort.Float64s(values)
+	return percentile(values, 0.95) - percentile(values, 0.10)
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	return sorted[int(math.Round(p*float64(len(sorted)-1)))]
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+func energyToLUFS(e float64) float64 {
+	if e <= 0 {
+		return math.Inf(-1)
+	}
+
+	return -0.691 + 10*math.Log10(e)
+}
+
+func amplitudeToDB(a float64) float64 {
+	if a <= 0 {
+		return math.Inf(-1)
+	}
+
+	return 20 * math.Log10(a)
+}
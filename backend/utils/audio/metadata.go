@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}[0-9]{7}$`)
+
+// ReleaseDate is a possibly partial date, Month and Day are 0 when unknown
+type ReleaseDate struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// String formats the date as YYYY, YYYY-MM or YYYY-MM-DD
+func (d ReleaseDate) String() string {
+	switch {
+	case d.Day > 0:
+		return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	case d.Month > 0:
+		return fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	}
+
+	return fmt.Sprintf("%04d", d.Year)
+}
+
+// ParseReleaseDate reads "2006", "2006-01" or "2006-01-02". A time after the date, as in
+// ID3v2.4 timestamps like "2006-01-02T15:04", is ignored.
+func ParseReleaseDate(s string) (ReleaseDate, error) {
+	v, _, _ := strings.Cut(strings.TrimSpace(s), "T")
+
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		t, err := time.Parse(layout, v)
+		if err != nil {
+			continue
+		}
+
+		d := ReleaseDate{Year: t.Year()}
+		if len(layout) >= len("2006-01") {
+			d.Month = int(t.Month())
+		}
+		if len(layout) == len("2006-01-02") {
+			d.Day = t.Day()
+		}
+
+		return d, nil
+	}
+
+	return ReleaseDate{}, fmt.Errorf("invalid release date %q", s)
+}
+
+// ParseISRC reads an International Standard Recording Code with or without hyphens, e.g.
+// "US-RC1-76-07839", and returns it in its 12 character form "USRC17607839"
+func ParseISRC(s string) (string, error) {
+	v := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+	if !isrcPattern.MatchString(v) {
+		return "", fmt.Errorf("invalid ISRC %q", s)
+	}
+
+	return v, nil
+}
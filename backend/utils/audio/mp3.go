@@ -0,0 +1,326 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrInvalidMP3 is returned when no MPEG audio frame can be found
+var ErrInvalidMP3 = errors.New("invalid MP3 file")
+
+var (
+	mp3Bitrates = [2][3][16]int{
+		// MPEG-1: layer I, II, III
+		{
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		},
+		// MPEG-2 and 2.5: layer I, II, III
+		{
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		},
+	}
+
+	mp3SampleRates = map[int][3]int{
+		1:  {44100, 48000, 32000},
+		2:  {22050, 24000, 16000},
+		25: {11025, 12000, 8000},
+	}
+)
+
+// MP3Frame is a parsed MPEG audio frame header
+type MP3Frame struct {
+	Version    int // 1, 2 or 25 for MPEG 2.5
+	Layer      int
+	Bitrate    int // kbit/s
+	SampleRate int
+	Channels   int
+	Size       int // bytes including the header
+	Samples    int // samples per channel
+}
+
+// ParseMP3Frame decodes the 4 byte header at the start of h
+func ParseMP3Frame(h []byte) (MP3Frame, bool) {
+	var f MP3Frame
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return f, false
+	}
+
+	switch (h[1] >> 3) & 0x03 {
+	case 0:
+		f.Version = 25
+	case 2:
+		f.Version = 2
+	case 3:
+		f.Version = 1
+	default:
+		return f, false
+	}
+
+	layerBits := (h[1] >> 1) & 0x03
+	if layerBits == 0 {
+		return f, false
+	}
+	f.Layer = 4 - int(layerBits)
+
+	bitrateIdx := int(h[2] >> 4)
+	rateIdx := int((h[2] >> 2) & 0x03)
+	if bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return f, false
+	}
+
+	table := 0
+	if f.Version != 1 {
+		table = 1
+	}
+	f.Bitrate = mp3Bitrates[table][f.Layer-1][bitrateIdx]
+	f.SampleRate = mp3SampleRates[f.Version][rateIdx]
+
+	padding := int((h[2] >> 1) & 0x01)
+	f.Channels = 2
+	if h[3]>>6 == 3 {
+		f.Channels = 1
+	}
+
+	switch {
+	case f.Layer == 1:
+		f.Samples = 384
+		f.Size = (12*f.Bitrate*1000/f.SampleRate + padding) * 4
+	case f.Layer == 3 && f.Version != 1:
+		f.Samples = 576
+		f.Size = 72*f.Bitrate*1000/f.SampleRate + padding
+	default:
+		f.Samples = 1152
+		f.Size = 144*f.Bitrate*1000/f.SampleRate + padding
+	}
+
+	return f, true
+}
+
+// sideInfoSize is the length of the layer III side information following the header
+func (f MP3Frame) sideInfoSize() int {
+	switch {
+	case f.Version == 1 && f.Channels == 1:
+		return 17
+	case f.Version == 1:
+		return 32
+	case f.Channels == 1:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// MP3Info summarizes an MPEG audio stream
+type MP3Info struct {
+	SampleRate      int
+	Channels        int
+	SamplesPerFrame int
+	// Frames counts audio frames, excluding a leading Xing/Info/VBRI frame
+	Frames int64
+	// DataOffset is the position of the first audio frame
+	DataOffset int64
+	// InfoFrameOffset is the position of the Xing/Info/VBRI frame, -1 when absent
+	InfoFrameOffset int64
+	InfoFrameSize   int
+	// EncoderDelay and EncoderPadding come from the LAME tag, in samples
+	EncoderDelay   int
+	EncoderPadding int
+	HasLAME        bool
+	// Scanned reports whether Frames was counted by walking the stream
+	Scanned bool
+}
+
+// Samples returns the playable sample count with encoder delay and padding removed
+func (m *MP3Info) Samples() int64 {
+	total := m.Frames*int64(m.SamplesPerFrame) - int64(m.EncoderDelay+m.EncoderPadding)
+	return max(total, 0)
+}
+
+// SkipID3v2 positions r after an ID3v2 tag at its start and returns the audio offset
+func SkipID3v2(r io.ReadSeeker) (int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var h [10]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil || !bytes.Equal(h[:3], []byte("ID3")) {
+		_, err := r.Seek(0, io.SeekStart)
+		return 0, err
+	}
+
+	size := int64(h[6]&0x7F)<<21 | int64(h[7]&0x7F)<<14 | int64(h[8]&0x7F)<<7 | int64(h[9]&0x7F)
+	offset := 10 + size
+	if h[5]&0x10 != 0 {
+		offset += 10 // footer
+	}
+
+	_, err := r.Seek(offset, io.SeekStart)
+	return offset, err
+}
+
+// ParseMP3 reads the stream layout from the Xing/Info or VBRI header and falls back to
+// counting every frame when neither exists
+func ParseMP3(r io.ReadSeeker) (*MP3Info, error) {
+	start, err := SkipID3v2(r)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	offset, first, err := syncMP3(br, start)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &MP3Info{
+		SampleRate:      first.SampleRate,
+		Channels:        first.Channels,
+		SamplesPerFrame: first.Samples,
+		DataOffset:      offset,
+		InfoFrameOffset: -1,
+	}
+
+	frame, err := br.Peek(first.Size)
+	if err != nil && len(frame) < 4 {
+		return nil, ErrInvalidMP3
+	}
+
+	if frames, ok := info.parseInfoFrame(first, frame); ok {
+		info.InfoFrameOffset = offset
+		info.InfoFrameSize = first.Size
+		info.DataOffset = offset + int64(first.Size)
+
+		if frames > 0 {
+			info.Frames = frames
+			return info, nil
+		}
+	}
+
+	// CBR or headerless VBR: count every frame
+	info.Scanned = true
+	err = WalkMP3Frames(r, info.DataOffset, first, func(int64, MP3Frame) bool {
+		info.Frames++
+		return true
+	})
+
+	return info, err
+}
+
+// parseInfoFrame reads a Xing/Info or VBRI header and the LAME extension of the first frame
+func (m *MP3Info) parseInfoFrame(first MP3Frame, frame []byte) (frames int64, ok bool) {
+	xing := 4 + first.sideInfoSize()
+	if len(frame) >= xing+8 {
+		tag := string(frame[xing : xing+4])
+		if tag == "Xing" || tag == "Info" {
+			flags := binary.BigEndian.Uint32(frame[xing+4:])
+			pos := xing + 8
+
+			if flags&0x1 != 0 && len(frame) >= pos+4 {
+				frames = int64(binary.BigEndian.Uint32(frame[pos:]))
+				pos += 4
+			}
+			if flags&0x2 != 0 {
+				pos += 4
+			}
+			if flags&0x4 != 0 {
+				pos += 100
+			}
+			if flags&0x8 != 0 {
+				pos += 4
+			}
+
+			// LAME extension: 9 byte version string, delay and padding at offset 21
+			if len(frame) >= pos+24 && (bytes.HasPrefix(frame[pos:], []byte("LAME")) || bytes.HasPrefix(frame[pos:], []byte("Lavf")) || bytes.HasPrefix(frame[pos:], []byte("Lavc"))) {
+				d := frame[pos+21:]
+				m.EncoderDelay = int(d[0])<<4 | int(d[1])>>4
+				m.EncoderPadding = int(d[1]&0x0F)<<8 | int(d[2])
+				m.HasLAME = true
+			}
+
+			return frames, true
+		}
+	}
+
+	// VBRI sits at a fixed 32 bytes after the header
+	if len(frame) >= 4+32+18 && string(frame[36:40]) == "VBRI" {
+		m.EncoderDelay = int(binary.BigEndian.Uint16(frame[42:]))
+		return int64(binary.BigEndian.Uint32(frame[50:])), true
+	}
+
+	return 0, false
+}
+
+// syncMP3 advances br to the first frame header that is followed by another valid header
+func syncMP3(br *bufio.Reader, offset int64) (int64, MP3Frame, error) {
+	for {
+		h, err := br.Peek(4)
+		if err != nil {
+			return 0, MP3Frame{}, ErrInvalidMP3
+		}
+
+		if f, ok := ParseMP3Frame(h); ok {
+			next, err := br.Peek(f.Size + 4)
+			if err == nil {
+				if n, ok := ParseMP3Frame(next[f.Size:]); ok && n.SampleRate == f.SampleRate {
+					return offset, f, nil
+				}
+			} else if len(next) >= f.Size {
+				// single frame file
+				return offset, f, nil
+			}
+		}
+
+		if _, err := br.Discard(1); err != nil {
+			return 0, MP3Frame{}, ErrInvalidMP3
+		}
+		offset++
+	}
+}
+
+// WalkMP3Frames calls fn with the offset and header of every frame from offset on that matches
+// the layer and sample rate of ref, skipping garbage in between. It stops at an ID3v1 tag,
+// at the end of the stream or when fn returns false.
+func WalkMP3Frames(r io.ReadSeeker, offset int64, ref MP3Frame, fn func(offset int64, f MP3Frame) bool) error {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	for {
+		h, err := br.Peek(4)
+		if err != nil {
+			return nil
+		}
+
+		if bytes.Equal(h[:3], []byte("TAG")) {
+			return nil
+		}
+
+		f, ok := ParseMP3Frame(h)
+		if !ok || f.SampleRate != ref.SampleRate || f.Layer != ref.Layer {
+			if _, err := br.Discard(1); err != nil {
+				return nil
+			}
+			offset++
+			continue
+		}
+
+		if !fn(offset, f) {
+			return nil
+		}
+
+		n, _ := br.Discard(f.Size)
+		if n < f.Size {
+			return nil
+		}
+		offset += int64(n)
+	}
+}
@@ -0,0 +1,247 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidOgg = errors.New("invalid Ogg file")
+
+// Ogg page header flags
+const (
+	oggContinued = 0x01
+	oggFirst     = 0x02
+)
+
+// oggPage is a single page of an Ogg bitstream
+type oggPage struct {
+	flags   byte
+	granule uint64
+	serial  uint32
+	seq     uint32
+	lacing  []byte
+	data    []byte
+}
+
+func readOggPage(r io.Reader) (*oggPage, error) {
+	var h [27]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrInvalidOgg
+		}
+		return nil, err
+	}
+
+	if !bytes.Equal(h[:4], []byte("OggS")) || h[4] != 0 {
+		return nil, ErrInvalidOgg
+	}
+
+	p := &oggPage{
+		flags:   h[5],
+		granule: binary.LittleEndian.Uint64(h[6:]),
+		serial:  binary.LittleEndian.Uint32(h[14:]),
+		seq:     binary.LittleEndian.Uint32(h[18:]),
+		lacing:  make([]byte, h[26]),
+	}
+	if _, err := io.ReadFull(r, p.lacing); err != nil {
+		return nil, ErrInvalidOgg
+	}
+
+	size := 0
+	for _, l := range p.lacing {
+		size += int(l)
+	}
+
+	p.data = make([]byte, size)
+	if _, err := io.ReadFull(r, p.data); err != nil {
+		return nil, ErrInvalidOgg
+	}
+
+	return p, nil
+}
+
+// bytes encodes the page with its checksum
+func (p *oggPage) bytes() []byte {
+	b := make([]byte, 27, 27+len(p.lacing)+len(p.data))
+	copy(b, "OggS")
+	b[5] = p.flags
+	binary.LittleEndian.PutUint64(b[6:], p.granule)
+	binary.LittleEndian.PutUint32(b[14:], p.serial)
+	binary.LittleEndian.PutUint32(b[18:], p.seq)
+	b[26] = byte(len(p.lacing))
+	b = append(b, p.lacing...)
+	b = append(b, p.data...)
+
+	binary.LittleEndian.PutUint32(b[22:], oggCRC(b))
+	return b
+}
+
+// paginateOgg lays packets out on pages of at most 255 segments, numbered from seq
+func paginateOgg(packets [][]byte, serial, seq uint32) []*oggPage {
+	var pages []*oggPage
+	page := &oggPage{serial: serial, seq: seq}
+
+	flush := func(continued bool) {
+		// pages on which no packet ends carry granule -1, header packets are at granule 0
+		if bytes.Count(page.lacing, []byte{255}) == len(page.lacing) {
+			page.granule = ^uint64(0)
+		}
+		pages = append(pages, page)
+
+		page = &oggPage{serial: serial, seq: page.seq + 1}
+		if continued {
+			page.flags = oggContinued
+		}
+	}
+
+	for _, packet := range packets {
+		for rest := packet; ; {
+			if len(page.lacing) == 255 {
+				flush(true)
+			}
+
+			n := min(len(rest), 255)
+			page.lacing = append(page.lacing, byte(n))
+			page.data = append(page.data, rest[:n]...)
+			rest = rest[n:]
+
+			if n < 255 {
+				break
+			}
+		}
+	}
+
+	if len(page.lacing) > 0 {
+		flush(false)
+	}
+
+	return pages
+}
+
+// writeOggTags replaces the comment header of an Ogg Vorbis or Opus stream. The header
+// packets are repaginated and the sequence numbers of later pages are shifted to match.
+func writeOggTags(w io.Writer, r io.ReadSeeker, t Tags) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+
+	first, err := readOggPage(br)
+	if err != nil {
+		return ErrInvalidOgg
+	}
+
+	var headers int
+	var prefix, suffix []byte
+	switch {
+	case bytes.HasPrefix(first.data, []byte("\x01vorbis")):
+		headers, prefix, suffix = 3, []byte("\x03vorbis"), []byte{1}
+	case bytes.HasPrefix(first.data, []byte("OpusHead")):
+		headers, prefix = 2, []byte("OpusTags")
+	default:
+		return fmt.Errorf("%w: cannot write tags to this Ogg codec", ErrUnsupportedFormat)
+	}
+
+	// the identification header is alone on the first page, collect the remaining header packets
+	var packets [][]byte
+	var packet []byte
+	oldPages := uint32(1)
+	for len(packets) < headers-1 {
+		page, err := readOggPage(br)
+		if err != nil {
+			return ErrInvalidOgg
+		}
+		if page.serial != first.serial {
+			return fmt.Errorf("%w: interleaved streams", ErrUnsupportedFormat)
+		}
+		oldPages++
+
+		data := page.data
+		for i, l := range page.lacing {
+			packet = append(packet, data[:l]...)
+			data = data[l:]
+
+			if l < 255 {
+				packets = append(packets, packet)
+				packet = nil
+
+				if len(packets) == headers-1 && i != len(page.lacing)-1 {
+					return fmt.Errorf("%w: audio shares a page with the headers", ErrInvalidOgg)
+				}
+			}
+		}
+	}
+
+	if !bytes.HasPrefix(packets[0], prefix) {
+		return ErrInvalidOgg
+	}
+
+	comment, err := parseVorbisComment(packets[0][len(prefix):])
+	if err != nil {
+		return err
+	}
+	if err := comment.apply(t); err != nil {
+		return err
+	}
+	packets[0] = append(append(append([]byte{}, prefix...), comment.bytes()...), suffix...)
+
+	pages := paginateOgg(packets, first.serial, 1)
+	shift := uint32(len(pages)+1) - oldPages
+
+	if _, err := w.Write(first.bytes()); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if _, err := w.Write(page.bytes()); err != nil {
+			return err
+		}
+	}
+
+	for {
+		page, err := readOggPage(br)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if page.serial == first.serial && page.flags&oggFirst == 0 {
+			page.seq += shift
+		}
+		if _, err := w.Write(page.bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		crc := uint32(i) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// oggCRC is the page checksum, computed with the checksum field zeroed
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for i, b := range page {
+		if i >= 22 && i < 26 {
+			b = 0
+		}
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
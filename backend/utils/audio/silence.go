@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// silenceWindow is the resolution of silence detection
+const silenceWindow = 10 * time.Millisecond
+
+// SilenceSink finds where audible content starts and ends by comparing the peak of short
+// windows against a threshold
+type SilenceSink struct {
+	channels  int
+	window    int
+	threshold float32
+
+	peak  float32
+	pos   int // frames in the current window
+	index int // current window
+	first int
+	last  int
+}
+
+// NewSilenceSink treats windows whose peak stays below thresholdDB (dBFS) as silence
+func NewSilenceSink(sampleRate, channels int, thresholdDB float64) *SilenceSink {
+	return &SilenceSink{
+		channels:  max(channels, 1),
+		window:    max(1, int(int64(sampleRate)*int64(silenceWindow)/int64(time.Second))),
+		threshold: float32(math.Pow(10, thresholdDB/20)),
+		first:     -1,
+		last:      -1,
+	}
+}
+
+func (s *SilenceSink) Write(block []float32) {
+	for i := 0; i+s.channels <= len(block); i += s.channels {
+		for _, v := range block[i : i+s.channels] {
+			s.peak = max(s.peak, v, -v)
+		}
+
+		s.pos++
+		if s.pos == s.window {
+			s.flush()
+		}
+	}
+}
+
+func (s *SilenceSink) flush() {
+	if s.peak >= s.threshold {
+		if s.first < 0 {
+			s.first = s.index
+		}
+		s.last = s.index
+	}
+
+	s.index++
+	s.peak, s.pos = 0, 0
+}
+
+// Bounds returns the positions where audible content starts and ends in the decoded stream.
+// ok is false when the whole stream is silent.
+func (s *SilenceSink) Bounds() (start, end time.Duration, ok bool) {
+	if s.pos > 0 {
+		s.flush()
+	}
+
+	if s.first < 0 {
+		return 0, 0, false
+	}
+
+	return time.Duration(s.first) * silenceWindow, time.Duration(s.last+1) * silenceWindow, true
+}
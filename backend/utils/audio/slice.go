@@ -0,0 +1,320 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Slice writes the part of r between start and end as a standalone file of the same format.
+// An end of zero means the end of the stream. WAV is cut on the exact sample, MP3, ADTS AAC
+// and FLAC on the frames containing the boundaries, so no re-encoding is needed.
+func Slice(w io.Writer, r io.ReadSeeker, f Format, start, end time.Duration) error {
+	if start < 0 || (end > 0 && end <= start) {
+		return fmt.Errorf("invalid slice %s-%s", start, end)
+	}
+
+	switch f {
+	case FormatWAV:
+		return sliceWAV(w, r, start, end)
+	case FormatMP3:
+		return sliceMP3(w, r, start, end)
+	case FormatFLAC:
+		return sliceFLAC(w, r, start, end)
+	case FormatAAC:
+		return sliceADTS(w, r, start, end)
+	}
+
+	return fmt.Errorf("%w: cannot slice %q", ErrUnsupportedFormat, f)
+}
+
+// CanSlice reports whether Slice supports the format
+func CanSlice(f Format) bool {
+	return f == FormatWAV || f == FormatMP3 || f == FormatFLAC || f == FormatAAC
+}
+
+// samplePosition converts a time offset into a sample index at rate
+func samplePosition(t time.Duration, rate int) int64 {
+	return int64(t) * int64(rate) / int64(time.Second)
+}
+
+func sliceWAV(w io.Writer, r io.ReadSeeker, start, end time.Duration) error {
+	info, err := ParseWAV(r)
+	if err != nil {
+		return err
+	}
+
+	frames := info.Frames()
+	first := min(samplePosition(start, info.SampleRate), frames)
+	last := frames
+	if end > 0 {
+		last = min(samplePosition(end, info.SampleRate), frames)
+	}
+
+	size := (last - first) * int64(info.BlockAlign)
+	if err := WriteWAVHeader(w, info, size); err != nil {
+		return err
+	}
+
+	if _, err := r.Seek(info.DataOffset+first*int64(info.BlockAlign), io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := io.CopyN(w, r, size); err != nil {
+		return err
+	}
+
+	// chunks are word aligned
+	if size%2 == 1 {
+		_, err = w.Write([]byte{0})
+	}
+
+	return err
+}
+
+// WriteWAVHeader writes a canonical 44 byte RIFF/WAVE header for dataSize bytes of samples
+// in the layout described by info
+func WriteWAVHeader(w io.Writer, info *WAVInfo, dataSize int64) error {
+	format := uint16(wavFormatPCM)
+	if info.IsFloat() {
+		format = wavFormatFloat
+	}
+
+	width := info.BlockAlign / info.Channels
+	h := make([]byte, 44)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(36+dataSize+dataSize%2))
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], format)
+	binary.LittleEndian.PutUint16(h[22:], uint16(info.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(info.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(info.SampleRate*info.BlockAlign))
+	binary.LittleEndian.PutUint16(h[32:], uint16(info.BlockAlign))
+	binary.LittleEndian.PutUint16(h[34:], uint16(width*8))
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], uint32(dataSize))
+
+	_, err := w.Write(h)
+	return err
+}
+
+// mp3Slice is the frame range of an MP3 slice
+type mp3Slice struct {
+	from, to int64
+	frames   int64
+	header   [4]byte
+}
+
+// mp3FrameRange finds the byte range of the frames covering start to end
+func mp3FrameRange(r io.ReadSeeker, info *MP3Info, start, end time.Duration) (*mp3Slice, error) {
+	first := samplePosition(start, info.SampleRate) / int64(info.SamplesPerFrame)
+	last := int64(-1)
+	if end > 0 {
+		last = (samplePosition(end, info.SampleRate) + int64(info.SamplesPerFrame) - 1) / int64(info.SamplesPerFrame)
+	}
+
+	if _, err := r.Seek(info.DataOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var h [4]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, ErrInvalidMP3
+	}
+
+	ref, ok := ParseMP3Frame(h[:])
+	if !ok {
+		ref = MP3Frame{SampleRate: info.SampleRate, Layer: 3}
+	}
+
+	res := &mp3Slice{from: -1}
+	var index int64
+	err := WalkMP3Frames(r, info.DataOffset, ref, func(offset int64, f MP3Frame) bool {
+		if index == last {
+			return false
+		}
+
+		if index == first {
+			res.from = offset
+		}
+
+		if index >= first {
+			res.to = offset + int64(f.Size)
+			res.frames++
+		}
+
+		index++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.from < 0 {
+		return nil, fmt.Errorf("slice starts after the end of the stream")
+	}
+
+	if _, err := r.Seek(res.from, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, res.header[:]); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func sliceMP3(w io.Writer, r io.ReadSeeker, start, end time.Duration) error {
+	info, err := ParseMP3(r)
+	if err != nil {
+		return err
+	}
+
+	slice, err := mp3FrameRange(r, info, start, end)
+	if err != nil {
+		return err
+	}
+
+	// players take the length of a VBR file from its Xing header, so the one of the
+	// source file is replaced by a header describing the slice
+	if xing := xingFrame(slice.header, slice.frames, slice.to-slice.from); xing != nil {
+		if _, err := w.Write(xing); err != nil {
+			return err
+		}
+	}
+
+	return copyRange(w, r, slice.from, slice.to)
+}
+
+// xingFrame builds an empty layer III frame carrying a Xing header with the frame and byte
+// counts of the stream that follows it. The header of the first audio frame is used as
+// template, raising the bitrate when the frame is too small. Returns nil for layer I and II.
+func xingFrame(template [4]byte, frames, size int64) []byte {
+	h := template
+	h[1] |= 0x01  // no CRC
+	h[2] &^= 0x02 // no padding
+
+	f, ok := ParseMP3Frame(h[:])
+	if !ok || f.Layer != 3 {
+		return nil
+	}
+
+	pos := 4 + f.sideInfoSize()
+	for f.Size < pos+16 {
+		idx := h[2] >> 4
+		if idx >= 14 {
+			return nil
+		}
+
+		h[2] = (idx+1)<<4 | h[2]&0x0F
+		f, _ = ParseMP3Frame(h[:])
+	}
+
+	frame := make([]byte, f.Size)
+	copy(frame, h[:])
+	copy(frame[pos:], "Xing")
+	binary.BigEndian.PutUint32(frame[pos+4:], 0x1|0x2) // frames and bytes fields
+	binary.BigEndian.PutUint32(frame[pos+8:], uint32(frames))
+	binary.BigEndian.PutUint32(frame[pos+12:], uint32(size+int64(f.Size)))
+
+	return frame
+}
+
+func sliceADTS(w io.Writer, r io.ReadSeeker, start, end time.Duration) error {
+	from, to := int64(-1), int64(-1)
+	var position int64 // samples before the current frame
+	err := WalkADTSFrames(r, func(offset int64, f ADTSFrame) bool {
+		next := position + int64(f.Samples)
+		if end > 0 && position >= samplePosition(end, f.SampleRate) {
+			return false
+		}
+
+		if from < 0 && next > samplePosition(start, f.SampleRate) {
+			from = offset
+		}
+
+		to = offset + int64(f.Size)
+		position = next
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if from < 0 {
+		return fmt.Errorf("slice starts after the end of the stream")
+	}
+
+	return copyRange(w, r, from, to)
+}
+
+func sliceFLAC(w io.Writer, r io.ReadSeeker, start, end time.Duration) error {
+	info, err := ParseFLAC(r)
+	if err != nil {
+		return err
+	}
+
+	first := uint64(samplePosition(start, info.SampleRate))
+	last := uint64(0)
+	if end > 0 {
+		last = uint64(samplePosition(end, info.SampleRate))
+	}
+
+	from, to := int64(-1), int64(-1)
+	var firstSample, lastSample uint64
+	err = info.WalkFLACFrames(r, func(f FLACFrame) bool {
+		if end > 0 && f.Sample >= last {
+			to = f.Offset
+			return false
+		}
+
+		if f.Sample+uint64(f.BlockSize) > first && from < 0 {
+			from = f.Offset
+			firstSample = f.Sample
+		}
+
+		lastSample = f.Sample + uint64(f.BlockSize)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if from < 0 {
+		return fmt.Errorf("slice starts after the end of the stream")
+	}
+
+	if to < 0 {
+		if to, err = r.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+
+	// STREAMINFO with the new sample count and an unknown MD5 signature
+	streamInfo := make([]byte, 34)
+	copy(streamInfo, info.StreamInfo)
+	packed := binary.BigEndian.Uint64(streamInfo[10:])
+	packed = packed&^0x0F_FFFF_FFFF | (lastSample-firstSample)&0x0F_FFFF_FFFF
+	binary.BigEndian.PutUint64(streamInfo[10:], packed)
+	clear(streamInfo[18:])
+
+	if _, err := w.Write([]byte{'f', 'L', 'a', 'C', 0x80, 0, 0, 34}); err != nil {
+		return err
+	}
+	if _, err := w.Write(streamInfo); err != nil {
+		return err
+	}
+
+	return copyRange(w, r, from, to)
+}
+
+func copyRange(w io.Writer, r io.ReadSeeker, from, to int64) error {
+	if _, err := r.Seek(from, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err := io.CopyN(w, r, to-from)
+	return err
+}
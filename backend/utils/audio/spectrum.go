@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"math"
+	"math/bits"
+)
+
+// analysisRate is the sample rate tempo and key analysis run at, high enough for onsets and
+// the pitch range that matters while keeping the FFTs cheap
+const analysisRate = 11025
+
+// monoDecimator downmixes interleaved blocks and lowers the sample rate by an integer factor
+// with a box filter, which is enough for spectral analysis below a few kHz
+type monoDecimator struct {
+	channels int
+	factor   int
+	rate     float64
+	acc      float64
+	n        int
+}
+
+func newMonoDecimator(sampleRate, channels int) monoDecimator {
+	factor := max(1, sampleRate/analysisRate)
+	return monoDecimator{
+		channels: max(channels, 1),
+		factor:   factor,
+		rate:     float64(sampleRate) / float64(factor),
+	}
+}
+
+// write feeds an interleaved block and calls fn for every output sample
+func (d *monoDecimator) write(block []float32, fn func(float64)) {
+	scale := 1 / float64(d.channels*d.factor)
+	for i := 0; i+d.channels <= len(block); i += d.channels {
+		for c := 0; c < d.channels; c++ {
+			d.acc += float64(block[i+c])
+		}
+
+		d.n++
+		if d.n == d.factor {
+			fn(d.acc * scale)
+			d.acc, d.n = 0, 0
+		}
+	}
+}
+
+// spectrum computes magnitude spectra of overlapping Hann windowed frames
+type spectrum struct {
+	size, hop int
+	buf       []float64
+	window    []float64
+	re, im    []float64
+	mags      []float64
+	plan      *fftPlan
+}
+
+func newSpectrum(size, hop int) *spectrum {
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size))
+	}
+
+	return &spectrum{
+		size:   size,
+		hop:    hop,
+		buf:    make([]float64, 0, size),
+		window: window,
+		re:     make([]float64, size),
+		im:     make([]float64, size),
+		mags:   make([]float64, size/2+1),
+		plan:   newFFTPlan(size),
+	}
+}
+
+// push adds a sample and calls fn with the magnitudes of bins 0..size/2 once a frame is complete.
+// The slice is reused between calls.
+func (s *spectrum) push(x float64, fn func(mags []float64)) {
+	s.buf = append(s.buf, x)
+	if len(s.buf) < s.size {
+		return
+	}
+
+	for i, v := range s.buf {
+		s.re[i] = v * s.window[i]
+		s.im[i] = 0
+	}
+	s.plan.transform(s.re, s.im)
+
+	for k := range s.mags {
+		s.mags[k] = math.Hypot(s.re[k], s.im[k])
+	}
+	fn(s.mags)
+
+	n := copy(s.buf, s.buf[s.hop:])
+	s.buf = s.buf[:n]
+}
+
+// fftPlan holds the twiddle factors of an in-place iterative radix-2 transform
+type fftPlan struct {
+	n, shift int
+	cos, sin []float64
+}
+
+// newFFTPlan prepares a transform of n points, n must be a power of two
+func newFFTPlan(n int) *fftPlan {
+	p := &fftPlan{
+		n:     n,
+		shift: bits.LeadingZeros(uint(n)) + 1,
+		cos:   make([]float64, n/2),
+		sin:   make([]float64, n/2),
+	}
+
+	for k := range p.cos {
+		p.cos[k] = math.Cos(-2 * math.Pi * float64(k) / float64(n))
+		p.sin[k] = math.Sin(-2 * math.Pi * float64(k) / float64(n))
+	}
+
+	return p
+}
+
+func (p *fftPlan) transform(re, im []float64) {
+	for i := 0; i < p.n; i++ {
+		j := int(bits.Reverse(uint(i)) >> p.shift)
+		if j > i {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
+	}
+
+	for size := 2; size <= p.n; size <<= 1 {
+		half, stride := size/2, p.n/size
+		for start := 0; start < p.n; start += size {
+			for k := 0; k < half; k++ {
+				wr, wi := p.cos[k*stride], p.sin[k*stride]
+				a, b := start+k, start+k+half
+				tr := wr*re[b] - wi*im[b]
+				ti := wr*im[b] + wi*re[b]
+				re[b], im[b] = re[a]-tr, im[a]-ti
+				re[a], im[a] = re[a]+tr, im[a]+ti
+			}
+		}
+	}
+}
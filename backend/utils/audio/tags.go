@@ -0,0 +1,386 @@
+package audio
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+
+	// decoders for picture dimensions
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// pictureFrontCover is the ID3 APIC and FLAC PICTURE type of a front cover
+const pictureFrontCover = 3
+
+// FLAC metadata block types
+const (
+	flacBlockPadding = 1
+	flacBlockComment = 4
+	flacBlockPicture = 6
+)
+
+// Tags are the metadata fields read from and written back into audio files. WriteTags writes
+// every field but AlbumArtist, empty fields and zero numbers remove the tag.
+type Tags struct {
+	Title  string
+	Artist string
+	Album  string
+	// Picture replaces the front cover when set, otherwise embedded pictures are kept
+	Picture *Picture
+
+	// AlbumArtist is only read, WriteTags leaves it unchanged
+	AlbumArtist string
+	// Year is written when there is no ReleaseDate
+	Year int
+	// ReleaseDate is YYYY, YYYY-MM or YYYY-MM-DD, empty when the file only has a year
+	ReleaseDate string
+	TrackNumber int
+	TrackTotal  int
+	DiscNumber  int
+	DiscTotal   int
+	Genre       string
+	Composer    string
+	ISRC        string
+	Label       string
+	Comment     string
+}
+
+// date returns the release date to write, the year alone when the date is unknown
+func (t Tags) date() string {
+	if t.ReleaseDate == "" && t.Year > 0 {
+		return strconv.Itoa(t.Year)
+	}
+
+	return t.ReleaseDate
+}
+
+// number formats a positive tag number, zero is written as no tag
+func number(n int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	return strconv.Itoa(n)
+}
+
+// raw tag names of the fields the tag package does not expose, for ID3v2.2, ID3v2.3/2.4 and
+// Vorbis comments (lower cased)
+var (
+	rawReleaseDate = []string{"TDRC", "TDRL", "date"}
+	rawISRC        = []string{"TRC", "TSRC", "isrc"}
+	rawLabel       = []string{"TPB", "TPUB", "label", "organization", "publisher"}
+)
+
+// ReadTags returns the metadata stored in the tags of r. Files without tags return empty fields.
+func ReadTags(r io.ReadSeeker) (Tags, error) {
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		if errors.Is(err, tag.ErrNoTagsFound) {
+			return Tags{}, nil
+		}
+
+		return Tags{}, err
+	}
+
+	t := Tags{
+		Title:  strings.TrimSpace(m.Title()),
+		Artist: strings.TrimSpace(m.Artist()),
+		Album:  strings.TrimSpace(m.Album()),
+
+		AlbumArtist: strings.TrimSpace(m.AlbumArtist()),
+		Year:        m.Year(),
+		Genre:       strings.TrimSpace(m.Genre()),
+		Composer:    strings.TrimSpace(m.Composer()),
+		Comment:     strings.TrimSpace(m.Comment()),
+		ISRC:        rawTag(m, rawISRC),
+		Label:       rawTag(m, rawLabel),
+	}
+	t.TrackNumber, t.TrackTotal = m.Track()
+	t.DiscNumber, t.DiscTotal = m.Disc()
+
+	if date, err := ParseReleaseDate(rawTag(m, rawReleaseDate)); err == nil {
+		t.ReleaseDate = date.String()
+		t.Year = cmp.Or(t.Year, date.Year)
+	}
+
+	return t, nil
+}
+
+// rawTag returns the first non-empty text value of the named raw tags
+func rawTag(m tag.Metadata, names []string) string {
+	raw := m.Raw()
+	for _, name := range names {
+		if v, ok := raw[name].(string); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+
+	return ""
+}
+
+// CanWriteTags reports whether WriteTags supports the format
+func CanWriteTags(f Format) bool {
+	return f == FormatMP3 || f == FormatFLAC || f == FormatOGG
+}
+
+// WriteTags copies the audio file in r to w with the fields of t and the cover replaced: an
+// ID3v2.4 tag for MP3, the VORBIS_COMMENT and PICTURE blocks for FLAC and the comment header
+// for Ogg Vorbis and Opus. Audio data is copied unchanged and other tags are kept.
+func WriteTags(w io.Writer, r io.ReadSeeker, f Format, t Tags) error {
+	switch f {
+	case FormatMP3:
+		return writeID3(w, r, t)
+	case FormatFLAC:
+		return writeFLACTags(w, r, t)
+	case FormatOGG:
+		return writeOggTags(w, r, t)
+	}
+
+	return fmt.Errorf("%w: cannot write tags to %q", ErrUnsupportedFormat, f)
+}
+
+// vorbisComment is a Vorbis comment block as used by FLAC, Ogg Vorbis and Opus
+type vorbisComment struct {
+	vendor string
+	fields []string // KEY=value
+}
+
+var errInvalidComment = errors.New("invalid Vorbis comment")
+
+func parseVorbisComment(b []byte) (*vorbisComment, error) {
+	read := func() (string, bool) {
+		if len(b) < 4 {
+			return "", false
+		}
+
+		n := binary.LittleEndian.Uint32(b)
+		if uint64(n) > uint64(len(b)-4) {
+			return "", false
+		}
+
+		s := string(b[4 : 4+n])
+		b = b[4+n:]
+		return s, true
+	}
+
+	vendor, ok := read()
+	if !ok || len(b) < 4 {
+		return nil, errInvalidComment
+	}
+
+	c := &vorbisComment{vendor: vendor}
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+
+	for i := uint32(0); i < count; i++ {
+		field, ok := read()
+		if !ok {
+			return nil, errInvalidComment
+		}
+		c.fields = append(c.fields, field)
+	}
+
+	return c, nil
+}
+
+// set replaces every field named key, case-insensitively, with a single value. Empty values remove the field.
+func (c *vorbisComment) set(key, value string) {
+	fields := c.fields[:0]
+	for _, f := range c.fields {
+		name, _, _ := strings.Cut(f, "=")
+		if !strings.EqualFold(name, key) {
+			fields = append(fields, f)
+		}
+	}
+	c.fields = fields
+
+	if value != "" {
+		c.fields = append(c.fields, key+"="+value)
+	}
+}
+
+func (c *vorbisComment) apply(t Tags) error {
+	c.set("TITLE", t.Title)
+	c.set("ARTIST", t.Artist)
+	c.set("ALBUM", t.Album)
+	c.set("DATE", t.date())
+	c.set("TRACKNUMBER", number(t.TrackNumber))
+	c.set("TOTALTRACKS", "")
+	c.set("TRACKTOTAL", number(t.TrackTotal))
+	c.set("DISCNUMBER", number(t.DiscNumber))
+	c.set("TOTALDISCS", "")
+	c.set("DISCTOTAL", number(t.DiscTotal))
+	c.set("GENRE", t.Genre)
+	c.set("COMPOSER", t.Composer)
+	c.set("ISRC", t.ISRC)
+	c.set("ORGANIZATION", "")
+	c.set("PUBLISHER", "")
+	c.set("LABEL", t.Label)
+	c.set("COMMENT", t.Comment)
+
+	if t.Picture != nil {
+		block, err := pictureBlock(t.Picture)
+		if err != nil {
+			return err
+		}
+
+		c.set("COVERART", "")
+		c.set("METADATA_BLOCK_PICTURE", base64.StdEncoding.EncodeToString(block))
+	}
+
+	return nil
+}
+
+func (c *vorbisComment) bytes() []byte {
+	var buf bytes.Buffer
+	write := func(s string) {
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+
+	write(c.vendor)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(c.fields)))
+	for _, f := range c.fields {
+		write(f)
+	}
+
+	return buf.Bytes()
+}
+
+// pictureBlock encodes a front cover as the body of a FLAC PICTURE block
+func pictureBlock(p *Picture) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(p.Data))
+	if err != nil {
+		return nil, fmt.Errorf("decode cover: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for _, v := range []any{
+		uint32(pictureFrontCover),
+		uint32(len(p.MIMEType)), []byte(p.MIMEType),
+		uint32(0), // description
+		uint32(cfg.Width), uint32(cfg.Height),
+		uint32(24), // color depth
+		uint32(0),  // palette size
+		uint32(len(p.Data)), p.Data,
+	} {
+		_ = binary.Write(&buf, binary.BigEndian, v)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeFLACTags rewrites the metadata blocks of a FLAC stream: the comment block is updated,
+// a new cover replaces front cover pictures and old padding is replaced by fresh padding
+func writeFLACTags(w io.Writer, r io.ReadSeeker, t Tags) error {
+	info, err := ParseFLAC(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.Seek(info.MetadataOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	type block struct {
+		kind byte
+		body []byte
+	}
+
+	var blocks []block
+	var comment *vorbisComment
+	for pos := info.MetadataOffset; pos < info.AudioOffset; {
+		var h [4]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return ErrInvalidFLAC
+		}
+
+		body := make([]byte, int(h[1])<<16|int(h[2])<<8|int(h[3]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return ErrInvalidFLAC
+		}
+		pos += 4 + int64(len(body))
+
+		kind := h[0] & 0x7F
+		switch {
+		case kind == flacBlockPadding:
+			continue
+		case kind == flacBlockComment:
+			if comment, err = parseVorbisComment(body); err != nil {
+				return err
+			}
+			blocks = append(blocks, block{kind: kind})
+			continue
+		case kind == flacBlockPicture && t.Picture != nil && len(body) >= 4 && binary.BigEndian.Uint32(body) == pictureFrontCover:
+			continue
+		}
+
+		blocks = append(blocks, block{kind: kind, body: body})
+	}
+
+	if comment == nil {
+		comment = &vorbisComment{vendor: "app-music"}
+		blocks = append(blocks, block{kind: flacBlockComment})
+	}
+	if err := comment.apply(t); err != nil {
+		return err
+	}
+
+	for i := range blocks {
+		if blocks[i].kind == flacBlockComment {
+			blocks[i].body = comment.bytes()
+		}
+	}
+
+	if t.Picture != nil {
+		picture, err := pictureBlock(t.Picture)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, block{kind: flacBlockPicture, body: picture})
+	}
+
+	// room for later edits without rewriting the file
+	blocks = append(blocks, block{kind: flacBlockPadding, body: make([]byte, 4096)})
+
+	if _, err := w.Write([]byte("fLaC")); err != nil {
+		return err
+	}
+
+	for i, b := range blocks {
+		if len(b.body) >= 1<<24 {
+			return fmt.Errorf("FLAC metadata block %d is too large", b.kind)
+		}
+
+		kind := b.kind
+		if i == len(blocks)-1 {
+			kind |= 0x80
+		}
+
+		n := len(b.body)
+		if _, err := w.Write([]byte{kind, byte(n >> 16), byte(n >> 8), byte(n)}); err != nil {
+			return err
+		}
+		if _, err := w.Write(b.body); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.Seek(info.AudioOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
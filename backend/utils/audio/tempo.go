@@ -0,0 +1,121 @@
+package audio
+
+import "math"
+
+const (
+	tempoFrame  = 1024
+	tempoHop    = 128
+	tempoMinBPM = 60.0
+	tempoMaxBPM = 200.0
+	// tempoPrior centers the octave weighting, listeners pick around 120 BPM when in doubt
+	tempoPrior = 120.0
+	// tempoMinSeconds is the shortest stream with a meaningful tempo
+	tempoMinSeconds = 5
+)
+
+// TempoSink estimates the tempo from a spectral flux onset envelope and its autocorrelation
+type TempoSink struct {
+	dec      monoDecimator
+	spec     *spectrum
+	prev     []float64
+	envelope []float64
+}
+
+func NewTempoSink(sampleRate, channels int) *TempoSink {
+	return &TempoSink{
+		dec:  newMonoDecimator(sampleRate, channels),
+		spec: newSpectrum(tempoFrame, tempoHop),
+		prev: make([]float64, tempoFrame/2+1),
+	}
+}
+
+func (t *TempoSink) Write(block []float32) {
+	t.dec.write(block, func(x float64) {
+		t.spec.push(x, t.onset)
+	})
+}
+
+// onset appends the half-wave rectified increase of log magnitude over the previous frame
+func (t *TempoSink) onset(mags []float64) {
+	var flux float64
+	for k, m := range mags {
+		v := math.Log1p(100 * m)
+		if d := v - t.prev[k]; d > 0 {
+			flux += d
+		}
+		t.prev[k] = v
+	}
+
+	t.envelope = append(t.envelope, flux)
+}
+
+// Tempo returns the estimated beats per minute and a confidence in [0, 1], which is the
+// normalized autocorrelation of the onset envelope at the beat period. ok is false for
+// streams that are too short or have no onsets.
+func (t *TempoSink) Tempo() (bpm float64, confidence float64, ok bool) {
+	fps := t.dec.rate / tempoHop
+	env := t.envelope
+	if len(env) < int(fps*tempoMinSeconds) {
+		return 0, 0, false
+	}
+
+	// remove the slowly varying part so only onsets correlate
+	radius := int(fps / 4)
+	detrended := make([]float64, len(env))
+	var sum float64
+	for i := 0; i < min(radius, len(env)); i++ {
+		sum += env[i]
+	}
+	for i := range env {
+		if j := i + radius; j < len(env) {
+			sum += env[j]
+		}
+		if j := i - radius - 1; j >= 0 {
+			sum -= env[j]
+		}
+		count := min(i+radius, len(env)-1) - max(i-radius, 0) + 1
+		detrended[i] = max(env[i]-sum/float64(count), 0)
+	}
+
+	minLag := int(math.Floor(fps * 60 / tempoMaxBPM))
+	maxLag := int(math.Ceil(fps * 60 / tempoMinBPM))
+	ac := make([]float64, maxLag+2)
+	for lag := range ac {
+		if lag != 0 && lag < minLag-1 {
+			continue
+		}
+
+		var s float64
+		for i := lag; i < len(detrended); i++ {
+			s += detrended[i] * detrended[i-lag]
+		}
+		ac[lag] = s / float64(len(detrended)-lag)
+	}
+
+	if ac[0] == 0 {
+		return 0, 0, false
+	}
+
+	best, bestScore := 0, 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		octaves := math.Log2(60 * fps / float64(lag) / tempoPrior)
+		score := ac[lag] * math.Exp(-0.5*octaves*octaves)
+		if score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+
+	if best == 0 {
+		return 0, 0, false
+	}
+
+	// parabolic interpolation between neighbouring lags
+	lag := float64(best)
+	if a, b, c := ac[best-1], ac[best], ac[best+1]; a-2*b+c < 0 {
+		lag += 0.5 * (a - c) / (a - 2*b + c)
+	}
+
+	bpm = 60 * fps / lag
+	confidence = math.Min(math.Max(ac[best]/ac[0], 0), 1)
+	return math.Round(bpm*10) / 10, confidence, true
+}
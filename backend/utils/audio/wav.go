@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// ErrInvalidWAV is returned for files that are not RIFF/WAVE
+var ErrInvalidWAV = errors.New("invalid WAV file")
+
+// WAVInfo describes the fmt and data chunks of a RIFF/WAVE file
+type WAVInfo struct {
+	AudioFormat   int
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	BlockAlign    int
+	DataOffset    int64
+	DataSize      int64
+}
+
+// Frames returns the number of sample frames in the data chunk
+func (w *WAVInfo) Frames() int64 {
+	if w.BlockAlign == 0 {
+		return 0
+	}
+
+	return w.DataSize / int64(w.BlockAlign)
+}
+
+// IsFloat reports whether samples are IEEE floats rather than integers
+func (w *WAVInfo) IsFloat() bool {
+	return w.AudioFormat == wavFormatFloat
+}
+
+// ParseWAV reads the chunk layout of a WAV file. Data sizes that overrun the file,
+// as written by streaming encoders, are clamped to the actual file size.
+func ParseWAV(r io.ReadSeeker) (*WAVInfo, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, ErrInvalidWAV
+	}
+
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return nil, ErrInvalidWAV
+	}
+
+	info := &WAVInfo{}
+	pos := int64(12)
+	haveFmt := false
+
+	for pos+8 <= size {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+
+		id := string(hdr[:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		pos += 8
+
+		switch id {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, ErrInvalidWAV
+			}
+
+			buf := make([]byte, chunkSize)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, err
+			}
+
+			info.AudioFormat = int(binary.LittleEndian.Uint16(buf[0:]))
+			info.Channels = int(binary.LittleEndian.Uint16(buf[2:]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(buf[4:]))
+			info.BlockAlign = int(binary.LittleEndian.Uint16(buf[12:]))
+			info.BitsPerSample = int(binary.LittleEndian.Uint16(buf[14:]))
+
+			// WAVE_FORMAT_EXTENSIBLE carries the real format in the sub-format GUID
+			if info.AudioFormat == wavFormatExtensible && chunkSize >= 26 {
+				info.AudioFormat = int(binary.LittleEndian.Uint16(buf[24:]))
+			}
+			haveFmt = true
+
+		case "data":
+			if !haveFmt {
+				return nil, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidWAV)
+			}
+
+			info.DataOffset = pos
+			info.DataSize = chunkSize
+			if pos+chunkSize > size || chunkSize == 0xFFFFFFFF {
+				info.DataSize = size - pos
+			}
+
+			return info, validateWAV(info)
+
+		default:
+			if _, err := r.Seek(chunkSize, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+
+		pos += chunkSize
+		// chunks are word aligned
+		if chunkSize%2 == 1 {
+			if _, err := r.Seek(1, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+			pos++
+		}
+	}
+
+	return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWAV)
+}
+
+func validateWAV(info *WAVInfo) error {
+	if info.Channels == 0 || info.SampleRate == 0 || info.BlockAlign == 0 {
+		return fmt.Errorf("%w: empty fmt chunk", ErrInvalidWAV)
+	}
+
+	switch {
+	case info.AudioFormat == wavFormatPCM && info.BitsPerSample >= 8 && info.BitsPerSample <= 32:
+	case info.AudioFormat == wavFormatFloat && (info.BitsPerSample == 32 || info.BitsPerSample == 64):
+	default:
+		return fmt.Errorf("%w: unsupported encoding %d/%d bit", ErrInvalidWAV, info.AudioFormat, info.BitsPerSample)
+	}
+
+	return nil
+}
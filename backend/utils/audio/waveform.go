@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"math"
+	"strconv"
+)
+
+// framesPerBucket is the resolution of the intermediate min/max buckets
+const framesPerBucket = 256
+
+// Waveform holds min/max peak pairs of a track at several resolutions
+type Waveform struct {
+	Version     int              `json:"version"`
+	SampleRate  int              `json:"sample_rate"`
+	Channels    int              `json:"channels"`
+	DurationMs  int64            `json:"duration_ms"`
+	Resolutions map[string]Peaks `json:"resolutions"`
+}
+
+// Peaks is a sequence of Length min/max pairs quantized to signed Bits
+type Peaks struct {
+	Length int    `json:"length"`
+	Bits   int    `json:"bits"`
+	Data   []int8 `json:"data"`
+}
+
+// PeakSink collects downmixed min/max buckets while the stream is decoded
+type PeakSink struct {
+	sampleRate int
+	channels   int
+	frames     int64
+	mins       []float32
+	maxs       []float32
+	curMin     float32
+	curMax     float32
+	inBucket   int
+}
+
+func NewPeakSink(sampleRate, channels int) *PeakSink {
+	return &PeakSink{sampleRate: sampleRate, channels: channels}
+}
+
+func (p *PeakSink) Write(block []float32) {
+	for i := 0; i+p.channels <= len(block); i += p.channels {
+		for _, v := range block[i : i+p.channels] {
+			if p.inBucket == 0 || v < p.curMin {
+				p.curMin = v
+			}
+			if p.inBucket == 0 || v > p.curMax {
+				p.curMax = v
+			}
+		}
+
+		p.inBucket++
+		p.frames++
+		if p.inBucket == framesPerBucket {
+			p.flush()
+		}
+	}
+}
+
+func (p *PeakSink) flush() {
+	p.mins = append(p.mins, p.curMin)
+	p.maxs = append(p.maxs, p.curMax)
+	p.inBucket = 0
+}
+
+// Waveform renders the collected buckets at every requested number of peaks
+func (p *PeakSink) Waveform(resolutions []int) *Waveform {
+	if p.inBucket > 0 {
+		p.flush()
+	}
+
+	w := &Waveform{
+		Version:     1,
+		SampleRate:  p.sampleRate,
+		Channels:    p.channels,
+		Resolutions: make(map[string]Peaks, len(resolutions)),
+	}
+
+	if p.sampleRate > 0 {
+		w.DurationMs = p.frames * 1000 / int64(p.sampleRate)
+	}
+
+	for _, res := range resolutions {
+		if res > 0 {
+			w.Resolutions[strconv.Itoa(res)] = p.downsample(res)
+		}
+	}
+
+	return w
+}
+
+func (p *PeakSink) downsample(n int) Peaks {
+	peaks := Peaks{Length: n, Bits: 8, Data: make([]int8, 0, n*2)}
+	buckets := len(p.mins)
+
+	for i := 0; i < n; i++ {
+		if buckets == 0 {
+			peaks.Data = append(peaks.Data, 0, 0)
+			continue
+		}
+
+		lo := i * buckets / n
+		hi := (i + 1) * buckets / n
+		if hi <= lo {
+			hi = lo + 1
+		}
+
+		mn, mx := p.mins[lo], p.maxs[lo]
+		for j := lo + 1; j < hi; j++ {
+			mn = min(mn, p.mins[j])
+			mx = max(mx, p.maxs[j])
+		}
+
+		peaks.Data = append(peaks.Data, quantize(mn), quantize(mx))
+	}
+
+	return peaks
+}
+
+func quantize(v float32) int8 {
+	q := math.Round(float64(v) * 127)
+	return int8(max(-128, min(127, q)))
+}
@@ -89,6 +89,14 @@ type middleware = struct {
 		AllowOrigins string `toml:"allow_origins"`
 		AllowHeaders string `toml:"allow_headers"`
 	}
+
+	Idempotency struct {
+		TTL time.Duration `toml:"ttl_seconds"`
+	}
+
+	Expvar struct {
+		Enable bool
+	}
 }
 
 type cookie struct {
@@ -122,6 +130,94 @@ type storage = struct {
 		Region    string `toml:"region"`
 		UseSsl    bool   `toml:"use_ssl"`
 	} `toml:"s3"`
+
+	Watch struct {
+		Enable          bool     `toml:"enable"`
+		Dirs            []string `toml:"dirs"`
+		UserID          uint64   `toml:"user_id"`
+		DebounceSeconds int      `toml:"debounce_seconds"`
+		After           string   `toml:"after"`
+		DoneDir         string   `toml:"done_dir"`
+	} `toml:"watch"`
+}
+
+// track struct config
+type track = struct {
+	Artwork struct {
+		Sizes   []int `toml:"sizes"`
+		Quality int   `toml:"quality"`
+	} `toml:"artwork"`
+
+	Waveform struct {
+		Resolutions []int `toml:"resolutions"`
+	} `toml:"waveform"`
+
+	Duration struct {
+		TrustClient      bool `toml:"trust_client"`
+		ToleranceSeconds int  `toml:"tolerance_seconds"`
+	} `toml:"duration"`
+
+	Preview struct {
+		DefaultSeconds int `toml:"default_seconds"`
+		MaxSeconds     int `toml:"max_seconds"`
+		SnapSeconds    int `toml:"snap_seconds"`
+		MaxCached      int `toml:"max_cached"`
+	} `toml:"preview"`
+
+	Silence struct {
+		ThresholdDB float64 `toml:"threshold_db"`
+		TrimStream  bool    `toml:"trim_stream"`
+	} `toml:"silence"`
+
+	Flac struct {
+		EncodeWAV bool `toml:"encode_wav"`
+	} `toml:"flac"`
+
+	Tags struct {
+		WriteBack bool `toml:"write_back"`
+	} `toml:"tags"`
+
+	UserTags struct {
+		Shared bool `toml:"shared"`
+	} `toml:"user_tags"`
+
+	SmartPlaylists struct {
+		Refresh time.Duration `toml:"refresh_seconds"`
+	} `toml:"smart_playlists"`
+
+	Ratings struct {
+		HalfStars bool `toml:"half_stars"`
+	} `toml:"ratings"`
+
+	Versions struct {
+		Keep int `toml:"keep"`
+	} `toml:"versions"`
+
+	Upload struct {
+		MaxConcurrent       int   `toml:"max_concurrent"`
+		MaxQueue            int   `toml:"max_queue"`
+		QueueTimeoutSeconds int   `toml:"queue_timeout_seconds"`
+		PerUser             int   `toml:"per_user"`
+		BandwidthKBps       int64 `toml:"bandwidth_kbps"`
+		RetryAfterSeconds   int   `toml:"retry_after_seconds"`
+	} `toml:"upload"`
+
+	Hooks struct {
+		Enabled []string `toml:"enabled"`
+
+		ClamAV struct {
+			Address        string `toml:"address"`
+			TimeoutSeconds int    `toml:"timeout_seconds"`
+		} `toml:"clamav"`
+
+		MaxDuration struct {
+			Seconds int `toml:"seconds"`
+		} `toml:"max_duration"`
+
+		WordFilter struct {
+			Words []string `toml:"words"`
+		} `toml:"word_filter"`
+	} `toml:"hooks"`
 }
 
 type Config struct {
@@ -131,6 +227,7 @@ type Config struct {
 	Middleware middleware
 	Cookie     cookie
 	Storage    storage
+	Track      track
 }
 
 // ParseConfig func to parse config
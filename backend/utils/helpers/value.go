@@ -0,0 +1,11 @@
+package helpers
+
+// Value returns the value p points to, the zero value for nil
+func Value[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+
+	return *p
+}
@@ -0,0 +1,152 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"math"
+
+	// register decoders for the formats found in audio tags and uploads
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Variant is a square JPEG rendition of a source image
+type Variant struct {
+	Size int
+	Data []byte
+}
+
+// SquareVariants decodes src, center crops it to a square and renders one JPEG per requested size.
+// Sources smaller than a requested size are not upscaled.
+func SquareVariants(src []byte, sizes []int, quality int) ([]Variant, error) {
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	square := cropSquare(img)
+	side := square.Bounds().Dx()
+	if side == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+
+	variants := make([]Variant, 0, len(sizes))
+	for _, size := range sizes {
+		target := size
+		if target > side {
+			target = side
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resample(square, target), &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, Variant{Size: size, Data: buf.Bytes()})
+	}
+
+	return variants, nil
+}
+
+// cropSquare copies the centered square of img into a zero-based RGBA image
+func cropSquare(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+
+	origin := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, origin, draw.Src)
+
+	return dst
+}
+
+type contrib struct {
+	idx    int
+	weight float64
+}
+
+// contributions computes area-coverage weights mapping n output pixels onto src input pixels
+func contributions(src, n int) [][]contrib {
+	scale := float64(src) / float64(n)
+	out := make([][]contrib, n)
+
+	for i := range out {
+		lo := float64(i) * scale
+		hi := lo + scale
+		for j := int(lo); float64(j) < hi && j < src; j++ {
+			w := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j))
+			if w > 0 {
+				out[i] = append(out[i], contrib{idx: j, weight: w / scale})
+			}
+		}
+	}
+
+	return out
+}
+
+// resample scales a square image to n x n pixels with a separable box filter
+func resample(src *image.RGBA, n int) *image.RGBA {
+	side := src.Bounds().Dx()
+	if side == n {
+		return src
+	}
+
+	weights := contributions(side, n)
+
+	// horizontal pass: side rows x n columns
+	tmp := make([]float64, side*n*4)
+	for y := 0; y < side; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, cs := range weights {
+			var acc [4]float64
+			for _, c := range cs {
+				p := row[c.idx*4:]
+				acc[0] += float64(p[0]) * c.weight
+				acc[1] += float64(p[1]) * c.weight
+				acc[2] += float64(p[2]) * c.weight
+				acc[3] += float64(p[3]) * c.weight
+			}
+			copy(tmp[(y*n+x)*4:], acc[:])
+		}
+	}
+
+	// vertical pass: n rows x n columns
+	dst := image.NewRGBA(image.Rect(0, 0, n, n))
+	for y, cs := range weights {
+		for x := 0; x < n; x++ {
+			var acc [4]float64
+			for _, c := range cs {
+				p := tmp[(c.idx*n+x)*4:]
+				acc[0] += p[0] * c.weight
+				acc[1] += p[1] * c.weight
+				acc[2] += p[2] * c.weight
+				acc[3] += p[3] * c.weight
+			}
+
+			o := dst.Pix[y*dst.Stride+x*4:]
+			for i := range acc {
+				o[i] = clamp8(acc[i])
+			}
+		}
+	}
+
+	return dst
+}
+
+func clamp8(v float64) uint8 {
+	v = math.Round(v)
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+
+	return uint8(v)
+}
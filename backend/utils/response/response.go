@@ -2,7 +2,9 @@ package response
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -15,6 +17,8 @@ type Messages = []any
 type Error struct {
 	Code    int `json:"code"`
 	Message any `json:"message"`
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter time.Duration `json:"-"`
 }
 
 // error makes it compatible with the error interface
@@ -50,6 +54,10 @@ var ErrorHandler = func(ctx *fiber.Ctx, err error) error {
 		resp.Code = c.Code
 		resp.Messages = Messages{c.Message}
 
+		if c.RetryAfter > 0 {
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int((c.RetryAfter+time.Second-1)/time.Second)))
+		}
+
 		if resp.Messages == nil {
 			resp.Messages = Messages{err}
 		}
@@ -27,6 +27,23 @@ func init() {
 	}
 }
 
+// RegisterValidation adds a custom validation tag, message is its translation with {0} as the field name
+func RegisterValidation(tag string, fn validator.Func, message string) {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		log.Panic().Err(err).Msg("")
+	}
+
+	err := validate.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+		return ut.Add(tag, message, true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T(tag, fe.Field())
+		return t
+	})
+	if err != nil {
+		log.Panic().Err(err).Msg("")
+	}
+}
+
 func ValidateStruct(input any) error {
 	return validate.Struct(input)
 }
@@ -118,6 +118,43 @@ func (s *SftpStorage) Upload(ctx context.Context, filename string, r io.Reader)
 	}
 }
 
+// sftpFile closes the ssh session together with the remote file
+type sftpFile struct {
+	*sftp.File
+	client  *sftp.Client
+	sshConn *ssh.Client
+}
+
+func (f *sftpFile) Close() error {
+	err := f.File.Close()
+	_ = f.client.Close()
+	_ = f.sshConn.Close()
+
+	return err
+}
+
+func (s *SftpStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	sshConn, client, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath := filename
+	if s.BaseDir != "" {
+		fullPath = path.Join(s.BaseDir, filename)
+	}
+
+	f, err := client.Open(fullPath)
+	if err != nil {
+		_ = client.Close()
+		_ = sshConn.Close()
+		log.Printf("[sftp] open %s err=%v", fullPath, err)
+		return nil, err
+	}
+
+	return &sftpFile{File: f, client: client, sshConn: sshConn}, nil
+}
+
 func (s *SftpStorage) Delete(filename string) error {
 	sshConn, client, err := s.connect()
 	if err != nil {
@@ -24,6 +24,10 @@ func NewLocalStorage(path string) (*LocalStorage, error) {
 
 func (s *LocalStorage) Upload(ctx context.Context, filename string, file io.Reader) (string, error) {
 	dstPath := filepath.Join(s.Path, filename)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return "", err
+	}
+
 	dst, err := os.Create(dstPath)
 	if err != nil {
 		return "", err
@@ -39,6 +43,10 @@ func (s *LocalStorage) Upload(ctx context.Context, filename string, file io.Read
 	return filename, nil
 }
 
+func (s *LocalStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.Path, filename))
+}
+
 func (s *LocalStorage) Delete(filename string) error {
 	return os.Remove(filepath.Join(s.Path, filename))
 }
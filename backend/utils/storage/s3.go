@@ -42,6 +42,21 @@ func (s *S3Storage) Upload(ctx context.Context, filename string, file io.Reader)
 	return filename, nil
 }
 
+func (s *S3Storage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	obj, err := s.Client.GetObject(ctx, s.Bucket, filename, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// GetObject is lazy, stat surfaces missing objects right away
+	if _, err := obj.Stat(); err != nil {
+		_ = obj.Close()
+		return nil, err
+	}
+
+	return obj, nil
+}
+
 func (s *S3Storage) Delete(filename string) error {
 	return s.Client.RemoveObject(context.Background(), s.Bucket, filename, minio.RemoveObjectOptions{})
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 
 	"git.dev.siap.id/kukuhkkh/app-music/utils/config"
 )
 
 type Storage interface {
 	Upload(ctx context.Context, filename string, file io.Reader) (string, error)
+	Open(ctx context.Context, filename string) (io.ReadCloser, error)
 	Delete(filename string) error
 	GetURL(filename string) string
 }
@@ -40,3 +42,52 @@ func NewStorage(cfg *config.Config) (Storage, error) {
 		return nil, fmt.Errorf("storage driver %s not supported", cfg.Storage.Driver)
 	}
 }
+
+// TempFile is a local copy of a stored object that is removed on Close
+type TempFile struct {
+	*os.File
+}
+
+func (f *TempFile) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.Name())
+
+	return err
+}
+
+// NewTempFile creates an empty temporary file, pattern follows os.CreateTemp
+func NewTempFile(pattern string) (*TempFile, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TempFile{File: f}, nil
+}
+
+// Download copies a stored object into a temporary file positioned at its start
+func Download(ctx context.Context, s Storage, filename string) (*TempFile, error) {
+	src, err := s.Open(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	tmp, err := NewTempFile("music-*")
+	if err != nil {
+		return nil, err
+	}
+
+	dst := tmp.File
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = tmp.Close()
+		return nil, err
+	}
+
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		_ = tmp.Close()
+		return nil, err
+	}
+
+	return tmp, nil
+}
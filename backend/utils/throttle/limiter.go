@@ -0,0 +1,164 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrUserBusy  = errors.New("too many concurrent uploads for this user")
+	ErrQueueFull = errors.New("too many uploads waiting")
+	ErrTimeout   = errors.New("timed out waiting for an upload slot")
+)
+
+// Limiter bounds concurrent work globally and per user. Work over the global limit waits in a
+// queue of at most maxQueue entries for up to timeout.
+type Limiter struct {
+	slots    chan struct{}
+	maxQueue int
+	perUser  int
+	timeout  time.Duration
+
+	mu    sync.Mutex
+	users map[uint64]int
+
+	active   atomic.Int64
+	queued   atomic.Int64
+	rejected atomic.Int64
+}
+
+// Stats is the state of a Limiter, published as an expvar
+type Stats struct {
+	Active        int64 `json:"active"`
+	Queued        int64 `json:"queued"`
+	Rejected      int64 `json:"rejected"`
+	Users         int   `json:"users"`
+	MaxConcurrent int   `json:"max_concurrent"`
+	MaxQueue      int   `json:"max_queue"`
+	PerUser       int   `json:"per_user"`
+}
+
+// NewLimiter creates a limiter, a zero maxConcurrent or perUser means unlimited. Its stats are
+// published under name in expvar, the first limiter created with a name keeps it.
+func NewLimiter(name string, maxConcurrent int, maxQueue int, perUser int, timeout time.Duration) *Limiter {
+	l := &Limiter{
+		maxQueue: maxQueue,
+		perUser:  perUser,
+		timeout:  timeout,
+		users:    make(map[uint64]int),
+	}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+
+	if name != "" && expvar.Get(name) == nil {
+		expvar.Publish(name, expvar.Func(func() any { return l.Stats() }))
+	}
+
+	return l
+}
+
+// Acquire takes a slot for userID, the returned release func must be called when the work is done
+func (l *Limiter) Acquire(ctx context.Context, userID uint64) (release func(), err error) {
+	if !l.reserveUser(userID) {
+		l.rejected.Add(1)
+		return nil, ErrUserBusy
+	}
+
+	if err := l.acquireSlot(ctx); err != nil {
+		l.releaseUser(userID)
+		l.rejected.Add(1)
+		return nil, err
+	}
+
+	l.active.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.active.Add(-1)
+			if l.slots != nil {
+				<-l.slots
+			}
+			l.releaseUser(userID)
+		})
+	}, nil
+}
+
+func (l *Limiter) acquireSlot(ctx context.Context) error {
+	if l.slots == nil {
+		return nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if l.queued.Add(1) > int64(l.maxQueue) {
+		l.queued.Add(-1)
+		return ErrQueueFull
+	}
+	defer l.queued.Add(-1)
+
+	var timeout <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) reserveUser(userID uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perUser > 0 && l.users[userID] >= l.perUser {
+		return false
+	}
+
+	l.users[userID]++
+	return true
+}
+
+func (l *Limiter) releaseUser(userID uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.users[userID] <= 1 {
+		delete(l.users, userID)
+		return
+	}
+
+	l.users[userID]--
+}
+
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	users := len(l.users)
+	l.mu.Unlock()
+
+	return Stats{
+		Active:        l.active.Load(),
+		Queued:        l.queued.Load(),
+		Rejected:      l.rejected.Load(),
+		Users:         users,
+		MaxConcurrent: cap(l.slots),
+		MaxQueue:      l.maxQueue,
+		PerUser:       l.perUser,
+	}
+}
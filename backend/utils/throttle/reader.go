@@ -0,0 +1,57 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// shapedReader reads at most rate bytes per second on average
+type shapedReader struct {
+	ctx   context.Context
+	r     io.Reader
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+// Reader limits reads from r to bytesPerSecond, a value <= 0 returns r unchanged
+func Reader(ctx context.Context, r io.Reader, bytesPerSecond int64) io.Reader {
+	if bytesPerSecond <= 0 {
+		return r
+	}
+
+	return &shapedReader{
+		ctx:  ctx,
+		r:    r,
+		rate: bytesPerSecond,
+	}
+}
+
+func (s *shapedReader) Read(p []byte) (int, error) {
+	if s.start.IsZero() {
+		s.start = time.Now()
+	}
+
+	// small reads keep the rate smooth, roughly ten per second
+	if chunk := max(s.rate/10, 1); int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := s.r.Read(p)
+	s.read += int64(n)
+
+	due := time.Duration(float64(s.read) / float64(s.rate) * float64(time.Second))
+	if wait := due - time.Since(s.start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			return n, s.ctx.Err()
+		}
+	}
+
+	return n, err
+}